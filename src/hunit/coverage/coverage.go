@@ -0,0 +1,225 @@
+package coverage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/instaunit/instaunit/hunit"
+	"github.com/instaunit/instaunit/hunit/keys"
+	"github.com/instaunit/instaunit/hunit/openapi"
+)
+
+// The coverage of an operation
+type Operation struct {
+	Method   string   `json:"method"`
+	Path     string   `json:"path"`
+	Id       string   `json:"operation_id,omitempty"`
+	Requests int      `json:"requests"`
+	Tested   []string `json:"tested_statuses,omitempty"`   // declared responses that were observed
+	Untested []string `json:"untested_statuses,omitempty"` // declared responses that were never observed
+	statuses map[int]int
+	segments []string
+}
+
+// Determine if the operation was requested
+func (o *Operation) Covered() bool {
+	return o.Requests > 0
+}
+
+// A request that does not correspond to any operation
+type Request struct {
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Requests int    `json:"requests"`
+}
+
+// A coverage report
+type Report struct {
+	Operations []*Operation `json:"operations"`
+	Undeclared []*Request   `json:"undeclared,omitempty"`
+}
+
+// The number of operations that were requested
+func (r *Report) Covered() int {
+	var n int
+	for _, e := range r.Operations {
+		if e.Covered() {
+			n++
+		}
+	}
+	return n
+}
+
+// Write the report as JSON
+func (r *Report) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// Measures the coverage of the operations an OpenAPI document declares by
+// the requests tests make.
+type Coverage struct {
+	doc        *openapi.Document
+	host       string // only requests to this host are considered, if it is set
+	base       string // the base path operations are relative to
+	ops        []*Operation
+	undeclared map[string]*Request
+}
+
+// Create a coverage tracker. If a host is provided, requests to other hosts
+// (e.g., to mock services) are ignored.
+func New(doc *openapi.Document, host string) *Coverage {
+	var base string
+	if len(doc.Servers) > 0 {
+		u, err := url.Parse(doc.Servers[0].URL)
+		if err == nil {
+			base = strings.TrimSuffix(u.Path, "/")
+		}
+	}
+	c := &Coverage{
+		doc:        doc,
+		host:       host,
+		base:       base,
+		undeclared: make(map[string]*Request),
+	}
+	for _, e := range doc.Routes() {
+		c.ops = append(c.ops, &Operation{
+			Method:   e.Method,
+			Path:     e.Path,
+			Id:       e.Operation.Id,
+			statuses: make(map[int]int),
+			segments: splitPath(base + e.Path),
+		})
+	}
+	return c
+}
+
+// Record the requests made by tests
+func (c *Coverage) Add(results []*hunit.Result) {
+	for _, e := range results {
+		if e.Method == "" || e.URL == "" {
+			continue // no request was made
+		}
+		u, err := url.Parse(e.URL)
+		if err != nil || (c.host != "" && u.Host != c.host) {
+			continue
+		}
+		op := c.match(e.Method, u.Path)
+		if op == nil {
+			k := e.Method + " " + u.Path
+			r, ok := c.undeclared[k]
+			if !ok {
+				r = &Request{Method: e.Method, Path: u.Path}
+				c.undeclared[k] = r
+			}
+			r.Requests++
+			continue
+		}
+		op.Requests++
+		if e.Status > 0 {
+			op.statuses[e.Status]++
+		}
+	}
+}
+
+// Produce a report
+func (c *Coverage) Report() *Report {
+	rep := &Report{Operations: c.ops}
+	for _, e := range c.ops {
+		e.Tested, e.Untested = nil, nil
+		op := c.doc.Paths[e.Path].Operation(e.Method)
+		for _, s := range keys.Sorted(op.Responses) {
+			if responseObserved(s, op.Responses, e.statuses) {
+				e.Tested = append(e.Tested, s)
+			} else {
+				e.Untested = append(e.Untested, s)
+			}
+		}
+	}
+	for _, e := range c.undeclared {
+		rep.Undeclared = append(rep.Undeclared, e)
+	}
+	sort.Slice(rep.Undeclared, func(i, j int) bool {
+		a, b := rep.Undeclared[i], rep.Undeclared[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Method < b.Method
+	})
+	return rep
+}
+
+// Find the operation a request is routed to. Paths with more literal
+// segments are preferred over those with templated segments, so that
+// '/users/me' is preferred over '/users/{id}'.
+func (c *Coverage) match(method, p string) *Operation {
+	segs := splitPath(p)
+	var best *Operation
+	var score int
+	for _, e := range c.ops {
+		if e.Method != strings.ToUpper(method) || len(e.segments) != len(segs) {
+			continue
+		}
+		n, ok := matchSegments(e.segments, segs)
+		if ok && (best == nil || n > score) {
+			best, score = e, n
+		}
+	}
+	return best
+}
+
+// Match path segments to a template, producing the number of literal segments
+func matchSegments(tmpl, segs []string) (int, bool) {
+	var n int
+	for i, t := range tmpl {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			if segs[i] == "" {
+				return 0, false
+			}
+		} else if t != segs[i] {
+			return 0, false
+		} else {
+			n++
+		}
+	}
+	return n, true
+}
+
+func splitPath(p string) []string {
+	return strings.Split(strings.Trim(p, "/"), "/")
+}
+
+// Determine if a declared response was observed. Responses may be declared
+// for a specific status, for a range (e.g., '4XX'), or as the 'default',
+// which applies to any status that is not otherwise declared.
+func responseObserved(key string, declared map[string]*openapi.Response, observed map[int]int) bool {
+	for s := range observed {
+		if responseKey(s, declared) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Determine which declared response describes a status
+func responseKey(s int, declared map[string]*openapi.Response) string {
+	if k := strconv.Itoa(s); declared[k] != nil {
+		return k
+	}
+	if k := fmt.Sprintf("%dXX", s/100); declared[k] != nil {
+		return k
+	}
+	if k := fmt.Sprintf("%dxx", s/100); declared[k] != nil {
+		return k
+	}
+	if declared["default"] != nil {
+		return "default"
+	}
+	return ""
+}
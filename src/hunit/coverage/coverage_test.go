@@ -0,0 +1,82 @@
+package coverage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/instaunit/instaunit/hunit"
+	"github.com/instaunit/instaunit/hunit/openapi"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const spec = `
+openapi: 3.0.0
+info:
+  title: Test
+  version: 1.0.0
+servers:
+  - url: http://localhost:8080/v1
+paths:
+  /users:
+    get:
+      responses:
+        '200':
+          description: Users
+    post:
+      responses:
+        '201':
+          description: Created
+  /users/me:
+    get:
+      responses:
+        '200':
+          description: Current user
+  /users/{id}:
+    get:
+      responses:
+        '200':
+          description: A user
+        '4XX':
+          description: A client error
+        default:
+          description: An error
+`
+
+func TestCoverage(t *testing.T) {
+	doc, err := openapi.Load(strings.NewReader(spec))
+	if !assert.Nil(t, err) {
+		return
+	}
+	res := func(method, u string, status int) *hunit.Result {
+		return &hunit.Result{Method: method, URL: u, Status: status}
+	}
+
+	cov := New(doc, "localhost:8080")
+	cov.Add([]*hunit.Result{
+		res("GET", "http://localhost:8080/v1/users", 200),
+		res("GET", "http://localhost:8080/v1/users/me", 200),
+		res("GET", "http://localhost:8080/v1/users/123", 404),
+		res("DELETE", "http://localhost:8080/v1/users/123", 204),
+		res("GET", "http://localhost:9090/v1/other", 200), // another host; ignored
+		{}, // no request was made
+	})
+
+	rep := cov.Report()
+	assert.Equal(t, 3, rep.Covered())
+	if assert.Len(t, rep.Operations, 4) {
+		ops := make(map[string]*Operation)
+		for _, e := range rep.Operations {
+			ops[e.Method+" "+e.Path] = e
+		}
+		assert.Equal(t, 0, ops["POST /users"].Requests)
+		assert.Equal(t, []string{"201"}, ops["POST /users"].Untested)
+		assert.Equal(t, 1, ops["GET /users/me"].Requests)
+		assert.Equal(t, []string{"4XX"}, ops["GET /users/{id}"].Tested)
+		assert.Equal(t, []string{"200", "default"}, ops["GET /users/{id}"].Untested)
+	}
+	if assert.Len(t, rep.Undeclared, 1) {
+		assert.Equal(t, "DELETE", rep.Undeclared[0].Method)
+		assert.Equal(t, "/v1/users/123", rep.Undeclared[0].Path)
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"net/http"
 
 	"github.com/instaunit/instaunit/hunit/doc/emit"
+	"github.com/instaunit/instaunit/hunit/doc/emit/html"
 	"github.com/instaunit/instaunit/hunit/doc/emit/instadoc"
 	"github.com/instaunit/instaunit/hunit/doc/emit/markdown"
 	"github.com/instaunit/instaunit/hunit/doc/emit/openapi"
+	"github.com/instaunit/instaunit/hunit/doc/emit/postman"
+	"github.com/instaunit/instaunit/hunit/doc/sample"
 	"github.com/instaunit/instaunit/hunit/testcase"
 )
 
@@ -19,15 +22,30 @@ type Generator interface {
 	Close() error
 }
 
+// Generator configuration
+type Config struct {
+	OpenAPI openapi.Config
+	Postman postman.Config
+	Samples sample.Config // code samples, for generators that produce them
+}
+
 // Create a documentation emitter
-func New(t emit.Doctype, base string) (Generator, error) {
+func New(t emit.Doctype, base string, conf Config) (Generator, error) {
 	switch t {
 	case emit.DoctypeMarkdown:
-		return Generator(markdown.New(base)), nil
+		return Generator(markdown.New(base, conf.Samples)), nil
 	case emit.DoctypeInstadoc:
-		return Generator(instadoc.New(base)), nil
+		return Generator(instadoc.New(base, conf.Samples)), nil
 	case emit.DoctypeOpenAPI:
-		return Generator(openapi.New(base)), nil
+		gen, err := openapi.New(base, conf.OpenAPI)
+		if err != nil {
+			return nil, err
+		}
+		return Generator(gen), nil
+	case emit.DoctypeHTML:
+		return Generator(html.New(base, conf.Samples)), nil
+	case emit.DoctypePostman:
+		return Generator(postman.New(base, conf.Postman, conf.Samples)), nil
 	default:
 		return nil, fmt.Errorf("Unsupported doctype: %v", t)
 	}
@@ -11,6 +11,8 @@ const (
 	DoctypeMarkdown Doctype = iota
 	DoctypeInstadoc
 	DoctypeOpenAPI
+	DoctypeHTML
+	DoctypePostman
 	DoctypeInvalid
 )
 
@@ -18,6 +20,8 @@ var doctypeNames = []string{
 	"markdown",
 	"instadoc",
 	"openapi",
+	"html",
+	"postman",
 	"<invalid>",
 }
 
@@ -25,6 +29,8 @@ var doctypeExts = []string{
 	".md",
 	".instadoc",
 	".json",
+	".html",
+	".json",
 	".???",
 }
 
@@ -37,6 +43,10 @@ func ParseDoctype(s string) (Doctype, error) {
 		return DoctypeInstadoc, nil
 	case "openapi":
 		return DoctypeOpenAPI, nil
+	case "html":
+		return DoctypeHTML, nil
+	case "postman":
+		return DoctypePostman, nil
 	default:
 		return DoctypeInvalid, fmt.Errorf("Unsupported type: %v", s)
 	}
@@ -0,0 +1,102 @@
+package html
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/instaunit/instaunit/hunit/text"
+)
+
+// Highlight an HTTP request or response listing. The start line and header
+// names are marked, and the entity is highlighted according to its content
+// type.
+func highlightHTTP(s string) template.HTML {
+	head, entity, body := strings.Cut(s, "\n\n")
+	b := &strings.Builder{}
+	var ctype string
+	for i, e := range strings.Split(head, "\n") {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if i == 0 {
+			b.WriteString(`<span class="hl-start">` + template.HTMLEscapeString(e) + `</span>`)
+			continue
+		}
+		k, v, ok := strings.Cut(e, ":")
+		if !ok {
+			b.WriteString(template.HTMLEscapeString(e))
+			continue
+		}
+		if strings.EqualFold(k, "Content-Type") {
+			ctype = strings.TrimSpace(v)
+		}
+		b.WriteString(`<span class="hl-header">` + template.HTMLEscapeString(k) + `</span>:` + template.HTMLEscapeString(v))
+	}
+	if body {
+		b.WriteString("\n\n")
+		b.WriteString(string(highlightEntity(entity, ctype)))
+	}
+	return template.HTML(b.String())
+}
+
+// Highlight an entity according to its content type
+func highlightEntity(s, ctype string) template.HTML {
+	switch text.EntityHighlight(ctype) {
+	case "json", "javascript":
+		return highlightJSON(s)
+	default:
+		return template.HTML(template.HTMLEscapeString(s))
+	}
+}
+
+// Highlight JSON. This is a tokenizer rather than a parser, so invalid JSON
+// is highlighted as well as is practical.
+func highlightJSON(s string) template.HTML {
+	b := &strings.Builder{}
+	span := func(class, v string) {
+		b.WriteString(`<span class="` + class + `">` + template.HTMLEscapeString(v) + `</span>`)
+	}
+	r := []rune(s)
+	for i := 0; i < len(r); {
+		switch c := r[i]; {
+		case c == '"':
+			j := i + 1
+			for ; j < len(r) && r[j] != '"'; j++ {
+				if r[j] == '\\' {
+					j++
+				}
+			}
+			if j < len(r) {
+				j++
+			}
+			class := "hl-string"
+			if k := skipSpace(r, j); k < len(r) && r[k] == ':' {
+				class = "hl-key"
+			}
+			span(class, string(r[i:min(j, len(r))]))
+			i = j
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for ; j < len(r) && strings.ContainsRune("0123456789.eE+-", r[j]); j++ {
+			}
+			span("hl-number", string(r[i:j]))
+			i = j
+		case c >= 'a' && c <= 'z':
+			j := i + 1
+			for ; j < len(r) && r[j] >= 'a' && r[j] <= 'z'; j++ {
+			}
+			span("hl-literal", string(r[i:j]))
+			i = j
+		default:
+			b.WriteString(template.HTMLEscapeString(string(c)))
+			i++
+		}
+	}
+	return template.HTML(b.String())
+}
+
+func skipSpace(r []rune, i int) int {
+	for ; i < len(r) && strings.ContainsRune(" \t\r\n", r[i]); i++ {
+	}
+	return i
+}
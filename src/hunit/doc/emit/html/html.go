@@ -0,0 +1,349 @@
+package html
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/instaunit/instaunit/hunit/doc/sample"
+	"github.com/instaunit/instaunit/hunit/testcase"
+	"github.com/instaunit/instaunit/hunit/text"
+	"github.com/instaunit/instaunit/hunit/text/slug"
+)
+
+//go:embed site.html
+var source string
+
+//go:embed site.css
+var stylesheet []byte
+
+//go:embed search.js
+var search []byte
+
+var site = template.Must(template.New("site").Funcs(template.FuncMap{
+	"markdown":  markdown,
+	"highlight": highlightHTTP,
+	"lower":     strings.ToLower,
+}).Parse(source))
+
+const (
+	indexFile       = "index.html"
+	stylesheetFile  = "site.css"
+	searchFile      = "search.js"
+	searchIndexFile = "search-index.js"
+)
+
+// A request parameter
+type param struct {
+	Name, Type, Detail string
+}
+
+// A request or response listing
+type listing struct {
+	Title, Comments string
+	Data            string
+}
+
+// A documented route, which is rendered on its own page
+type route struct {
+	Slug     string
+	Title    string
+	Comments string
+	Section  string
+	Method   string
+	Path     string
+	Params   []param
+	Request  *listing
+	Response *listing
+	Samples  []sample.Sample
+	File     string // relative to the site root
+}
+
+// A section of a suite's contents
+type section struct {
+	Title  string
+	Routes []*route
+}
+
+// A documented suite, which is rendered as a page listing its routes
+type page struct {
+	Title    string
+	Comments string
+	Link     string
+	TOC      string // comments on the contents
+	File     string // relative to the site root
+	Sections []section
+	routes   []*route
+	slugs    map[string]int
+	anchors  testcase.AnchorStyle
+}
+
+// An entry in the search index
+type entry struct {
+	Title  string `json:"title"`
+	Suite  string `json:"suite,omitempty"`
+	Method string `json:"method,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Text   string `json:"text,omitempty"`
+	URL    string `json:"url"`
+}
+
+// Data provided to page templates
+type view struct {
+	Pages []*page
+	Page  *page
+	Route *route
+	Root  string // the relative path to the site root
+}
+
+// A static HTML documentation site generator. Every suite is rendered as a
+// page listing its routes, organized by the sections of its table of
+// contents, and every route on its own page. Pages share a navigation sidebar
+// and a search index.
+type Generator struct {
+	docpath string
+	samples sample.Config
+	pages   []*page
+	cur     *page
+}
+
+// Produce a new emitter
+func New(docpath string, samples sample.Config) *Generator {
+	return &Generator{docpath: docpath, samples: samples}
+}
+
+// Init a suite; one page per suite
+func (g *Generator) Init(suite *testcase.Suite, docs string) error {
+	if docs == indexFile {
+		docs = "suite-" + docs // don't clobber the site index
+	}
+	g.cur = &page{
+		Title:    text.Coalesce(strings.TrimSpace(suite.Title), docs[:len(docs)-len(path.Ext(docs))]),
+		Comments: suite.Comments,
+		Link:     suite.Link,
+		TOC:      suite.TOC.Comments,
+		File:     docs,
+		slugs:    make(map[string]int),
+		anchors:  suite.Config.Doc.AnchorStyle,
+	}
+	return nil
+}
+
+// Finish a suite, organizing its routes into sections
+func (g *Generator) Finalize(suite *testcase.Suite) error {
+	p := g.cur
+	if p == nil {
+		return nil
+	}
+	g.cur = nil
+	if len(p.routes) == 0 {
+		return nil // nothing was documented
+	}
+
+	if len(suite.TOC.Sections) == 0 {
+		p.Sections = []section{{Routes: p.routes}}
+	} else {
+		var extra []*route
+		groups := make(map[string][]*route)
+		for _, e := range p.routes {
+			if e.Section == "" {
+				extra = append(extra, e)
+			} else {
+				groups[e.Section] = append(groups[e.Section], e)
+			}
+		}
+		for _, e := range suite.TOC.Sections {
+			p.Sections = append(p.Sections, section{
+				Title:  text.Coalesce(e.Title, e.Key),
+				Routes: groups[e.Key],
+			})
+		}
+		if !suite.TOC.SuppressUnassigned && len(extra) > 0 {
+			p.Sections = append(p.Sections, section{Title: "Additional", Routes: extra})
+		}
+	}
+
+	g.pages = append(g.pages, p)
+	return nil
+}
+
+// Write the site
+func (g *Generator) Close() error {
+	if len(g.pages) == 0 {
+		return nil // nothing to do
+	}
+
+	err := os.WriteFile(path.Join(g.docpath, stylesheetFile), stylesheet, 0644)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(path.Join(g.docpath, searchFile), search, 0644)
+	if err != nil {
+		return err
+	}
+	err = g.writeIndex()
+	if err != nil {
+		return err
+	}
+
+	err = g.render("index", indexFile, view{Pages: g.pages})
+	if err != nil {
+		return err
+	}
+	for _, p := range g.pages {
+		err = g.render("suite", p.File, view{Pages: g.pages, Page: p})
+		if err != nil {
+			return err
+		}
+		for _, r := range p.routes {
+			err = g.render("route", r.File, view{Pages: g.pages, Page: p, Route: r, Root: "../"})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Render a page
+func (g *Generator) render(name, file string, v view) error {
+	p := path.Join(g.docpath, file)
+	err := os.MkdirAll(path.Dir(p), 0755)
+	if err != nil {
+		return err
+	}
+	b := &bytes.Buffer{}
+	err = site.ExecuteTemplate(b, name, v)
+	if err != nil {
+		return fmt.Errorf("Could not render page: %s: %w", file, err)
+	}
+	return os.WriteFile(p, b.Bytes(), 0644)
+}
+
+// Write the search index as a script, so that it can be loaded from the
+// filesystem as well as when the site is served
+func (g *Generator) writeIndex() error {
+	var idx []entry
+	for _, p := range g.pages {
+		for _, r := range p.routes {
+			idx = append(idx, entry{
+				Title:  r.Title,
+				Suite:  p.Title,
+				Method: r.Method,
+				Path:   r.Path,
+				Text:   strings.Join(strings.Fields(r.Comments), " "),
+				URL:    r.File,
+			})
+		}
+	}
+	sort.SliceStable(idx, func(i, j int) bool { return idx[i].Suite < idx[j].Suite })
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path.Join(g.docpath, searchIndexFile), []byte("window.searchIndex = "+string(data)+";\n"), 0644)
+}
+
+// Generate documentation
+func (g *Generator) Case(suite *testcase.Suite, c testcase.Case, req *http.Request, reqdata string, rsp *http.Response, rspdata []byte) error {
+	p := g.cur
+	if p == nil {
+		return nil
+	}
+
+	var title string
+	if c.Title != "" {
+		title = strings.TrimSpace(c.Title)
+	} else {
+		title = fmt.Sprintf("%s %s", c.Request.Method, c.Request.URL)
+	}
+
+	var s string
+	switch p.anchors {
+	case testcase.AnchorRails:
+		s, p.slugs = slug.Rails(title, p.slugs)
+	default:
+		s, p.slugs = slug.Github(title, p.slugs)
+	}
+	if s == "" {
+		s = fmt.Sprintf("route-%d", len(p.routes)+1)
+	}
+
+	r := &route{
+		Slug:     s,
+		Title:    title,
+		Comments: c.Comments,
+		Section:  c.Section,
+		File:     path.Join(p.File[:len(p.File)-len(path.Ext(p.File))], s+".html"),
+	}
+
+	keys := make([]string, 0, len(c.Params))
+	for k := range c.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		d := c.Params[k]
+		r.Params = append(r.Params, param{
+			Name:   strings.TrimSpace(k),
+			Type:   d.Type,
+			Detail: strings.TrimSpace(d.Description),
+		})
+	}
+
+	if req != nil {
+		r.Method, r.Path = req.Method, req.URL.Path
+		if len(reqdata) > 0 && suite.Config.Doc.FormatEntities {
+			t := text.Coalesce(c.Request.Format, req.Header.Get("Content-Type"))
+			f, err := text.FormatEntity([]byte(reqdata), t)
+			if err == nil {
+				reqdata = string(f)
+			} else if err != text.ErrUnsupportedContentType {
+				fmt.Printf("* * * Invalid request entity could not be formatted: %v\n", t)
+			}
+		}
+		b := &bytes.Buffer{}
+		err := text.WriteRequest(b, req, reqdata)
+		if err != nil {
+			return err
+		}
+		r.Request = &listing{
+			Title:    text.Coalesce(strings.TrimSpace(c.Request.Title), "Example request"),
+			Comments: c.Request.Comments,
+			Data:     b.String(),
+		}
+		r.Samples = sample.Generate(req, reqdata, g.samples.ForSuite(suite))
+	}
+
+	if rsp != nil {
+		if len(rspdata) > 0 && suite.Config.Doc.FormatEntities {
+			t := text.Coalesce(c.Response.Format, rsp.Header.Get("Content-Type"))
+			f, err := text.FormatEntity(rspdata, t)
+			if err == nil {
+				rspdata = f
+			} else if err != text.ErrUnsupportedContentType {
+				fmt.Printf("* * * Invalid entity could not be formatted: %v\n", t)
+			}
+		}
+		b := &bytes.Buffer{}
+		err := text.WriteResponse(b, rsp, rspdata)
+		if err != nil {
+			return err
+		}
+		r.Response = &listing{
+			Title:    text.Coalesce(strings.TrimSpace(c.Response.Title), "Example response"),
+			Comments: c.Response.Comments,
+			Data:     b.String(),
+		}
+	}
+
+	p.routes = append(p.routes, r)
+	return nil
+}
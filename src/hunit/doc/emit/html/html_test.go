@@ -0,0 +1,185 @@
+package html
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/instaunit/instaunit/hunit/doc/sample"
+	"github.com/instaunit/instaunit/hunit/testcase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdown(t *testing.T) {
+	assert.Equal(t, template.HTML("<p>Manage <strong>users</strong> with <code>GET /users?a=&lt;b&gt;</code>, <em>see</em> <a href=\"https://example.com\">the guide</a>.</p>\n<ul>\n<li>one</li>\n<li>two, continued</li>\n</ul>\n<h2>Details</h2>\n<pre><code>a &lt; b</code></pre>\n"),
+		markdown("Manage **users** with `GET /users?a=<b>`,\n_see_ [the guide](https://example.com).\n\n* one\n* two,\n  continued\n\n# Details\n```\na < b\n```"))
+}
+
+func TestMarkdownLinks(t *testing.T) {
+	tests := []struct {
+		Source string
+		Expect template.HTML
+	}{
+		{"[a](https://example.com/?a=1&b=2)", `<p><a href="https://example.com/?a=1&amp;b=2">a</a></p>` + "\n"},
+		{"[a](http://example.com)", `<p><a href="http://example.com">a</a></p>` + "\n"},
+		{"[a](mailto:joe@example.com)", `<p><a href="mailto:joe@example.com">a</a></p>` + "\n"},
+		{"[a](../users.html#create)", `<p><a href="../users.html#create">a</a></p>` + "\n"},
+		{"[a](#create)", `<p><a href="#create">a</a></p>` + "\n"},
+		{"[a](javascript:alert(1))", `<p>a)</p>` + "\n"}, // the target ends at the first parenthesis
+		{"[a](JavaScript:alert&#40;1&#41;)", `<p>a</p>` + "\n"},
+		{"[a](data:text/html,<script>)", `<p>a</p>` + "\n"},
+		{"[a](vbscript:msgbox)", `<p>a</p>` + "\n"},
+	}
+	for _, e := range tests {
+		assert.Equal(t, e.Expect, markdown(e.Source), e.Source)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	assert.Equal(t, template.HTML(`<span class="hl-start">POST /users HTTP/1.1</span>
+<span class="hl-header">Content-Type</span>: application/json
+
+{<span class="hl-key">&#34;id&#34;</span>: <span class="hl-number">-1.5</span>, <span class="hl-key">&#34;tags&#34;</span>: [<span class="hl-string">&#34;a\&#34;b&#34;</span>, <span class="hl-literal">null</span>]}`),
+		highlightHTTP("POST /users HTTP/1.1\nContent-Type: application/json\n\n{\"id\": -1.5, \"tags\": [\"a\\\"b\", null]}"))
+	assert.Equal(t, template.HTML(`<span class="hl-start">HTTP/1.1 200 OK</span>
+
+&lt;b&gt;`), highlightHTTP("HTTP/1.1 200 OK\n\n<b>"))
+}
+
+func TestGenerator(t *testing.T) {
+	dir := t.TempDir()
+	g := New(dir, sample.Config{})
+
+	doc := func(suite *testcase.Suite, c testcase.Case, entity string) {
+		req, err := http.NewRequest(c.Request.Method, "http://localhost"+c.Request.URL, strings.NewReader(entity))
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		rsp := &http.Response{StatusCode: http.StatusOK, Proto: "HTTP/1.1", ProtoMajor: 1, ProtoMinor: 1, Header: http.Header{"Content-Type": {"application/json"}}}
+		assert.Nil(t, g.Case(suite, c, req, entity, rsp, []byte(`{"id": 1}`)))
+	}
+	route := func(method, url, title, section string) testcase.Case {
+		return testcase.Case{Title: title, Section: section, Comments: "Manage  the\n<users>", Request: testcase.Request{Method: method, URL: url}}
+	}
+
+	// a suite's routes are organized by the sections of its contents, followed
+	// by those that are not assigned to one
+	users := &testcase.Suite{Title: "Users", TOC: testcase.TOC{Sections: []testcase.Section{
+		{Key: "read", Title: "Reading"},
+		{Key: "write"},
+		{Key: "admin"},
+	}}}
+	assert.Nil(t, g.Init(users, "index.html")) // doesn't clobber the site index
+	doc(users, route("POST", "/users", "Create a user", "write"), `{"name": "Joe"}`)
+	doc(users, route("GET", "/users/1", "Fetch a user", "read"), "")
+	doc(users, route("GET", "/status", "", ""), "")
+	assert.Nil(t, g.Finalize(users))
+
+	// unassigned routes can be omitted from the contents
+	groups := &testcase.Suite{Title: "Groups", TOC: testcase.TOC{SuppressUnassigned: true, Sections: []testcase.Section{{Key: "read"}}}}
+	assert.Nil(t, g.Init(groups, "groups.html"))
+	doc(groups, route("GET", "/groups", "List groups", "read"), "")
+	doc(groups, route("GET", "/groups/1", "Fetch a group", ""), "")
+	assert.Nil(t, g.Finalize(groups))
+
+	// suites without documented routes are omitted
+	assert.Nil(t, g.Init(&testcase.Suite{Title: "Empty"}, "empty.html"))
+	assert.Nil(t, g.Finalize(&testcase.Suite{}))
+
+	if !assert.Len(t, g.pages, 2) {
+		return
+	}
+	titles := func(p *page) [][]string {
+		var t [][]string
+		for _, s := range p.Sections {
+			x := []string{s.Title}
+			for _, r := range s.Routes {
+				x = append(x, r.Title)
+			}
+			t = append(t, x)
+		}
+		return t
+	}
+	assert.Equal(t, [][]string{{"Reading", "Fetch a user"}, {"write", "Create a user"}, {"admin"}, {"Additional", "GET /status"}}, titles(g.pages[0]))
+	assert.Equal(t, [][]string{{"read", "List groups"}}, titles(g.pages[1]))
+	if !assert.Nil(t, g.Close()) {
+		return
+	}
+
+	for _, e := range []string{
+		"index.html",
+		"site.css",
+		"search.js",
+		"search-index.js",
+		"suite-index.html",
+		"suite-index/create-a-user.html",
+		"suite-index/fetch-a-user.html",
+		"suite-index/get-status.html",
+		"groups.html",
+		"groups/list-groups.html",
+		"groups/fetch-a-group.html",
+	} {
+		_, err := os.Stat(filepath.Join(dir, e))
+		assert.Nil(t, err, e)
+	}
+	_, err := os.Stat(filepath.Join(dir, "empty.html"))
+	assert.True(t, os.IsNotExist(err))
+
+	data, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	if assert.Nil(t, err) {
+		assert.Contains(t, string(data), `<a href="suite-index.html">Users</a>`)
+		assert.Contains(t, string(data), `<a href="groups.html">Groups</a>`)
+	}
+	data, err = os.ReadFile(filepath.Join(dir, "suite-index/create-a-user.html"))
+	if assert.Nil(t, err) {
+		assert.Contains(t, string(data), `<a href="../suite-index.html">Users</a>`)
+		assert.Contains(t, string(data), `POST /users HTTP/1.1`)
+	}
+
+	// the search index is a script which assigns the entries, ordered by suite
+	data, err = os.ReadFile(filepath.Join(dir, "search-index.js"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	src, ok := strings.CutPrefix(string(data), "window.searchIndex = ")
+	if !assert.True(t, ok) {
+		return
+	}
+	var idx []entry
+	if assert.Nil(t, json.Unmarshal([]byte(strings.TrimSuffix(src, ";\n")), &idx)) && assert.Len(t, idx, 5) {
+		assert.Equal(t, entry{Title: "List groups", Suite: "Groups", Method: "GET", Path: "/groups", Text: "Manage the <users>", URL: "groups/list-groups.html"}, idx[0])
+		assert.Equal(t, "Groups", idx[1].Suite)
+		assert.Equal(t, entry{Title: "Create a user", Suite: "Users", Method: "POST", Path: "/users", Text: "Manage the <users>", URL: "suite-index/create-a-user.html"}, idx[2])
+	}
+}
+
+func TestGeneratorSamples(t *testing.T) {
+	g := New(t.TempDir(), sample.Config{Langs: []sample.Lang{sample.LangCurl}})
+	suite := &testcase.Suite{Title: "Users"}
+	assert.Nil(t, g.Init(suite, "users.html"))
+
+	req, err := http.NewRequest("GET", "http://localhost/users", nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	rsp := &http.Response{StatusCode: http.StatusOK, Proto: "HTTP/1.1", ProtoMajor: 1, ProtoMinor: 1}
+	assert.Nil(t, g.Case(suite, testcase.Case{Title: "List users", Request: testcase.Request{Method: "GET", URL: "/users"}}, req, "", rsp, nil))
+	assert.Nil(t, g.Finalize(suite))
+
+	if !assert.Len(t, g.pages, 1) || !assert.Len(t, g.pages[0].Sections, 1) || !assert.Len(t, g.pages[0].Sections[0].Routes, 1) {
+		return
+	}
+	r := g.pages[0].Sections[0].Routes[0]
+	// credentials are redacted from samples, but not from the request listing
+	assert.Contains(t, r.Request.Data, "Authorization: Bearer secret")
+	if assert.Len(t, r.Samples, 1) {
+		assert.Contains(t, r.Samples[0].Source, "Bearer "+sample.Redacted)
+		assert.NotContains(t, r.Samples[0].Source, "secret")
+	}
+}
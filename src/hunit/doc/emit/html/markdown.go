@@ -0,0 +1,117 @@
+package html
+
+import (
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	inlineCode   = regexp.MustCompile("`([^`]+)`")
+	inlineLink   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	inlineStrong = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	inlineEm     = regexp.MustCompile(`(^|[\s(])[_*]([^_*]+)[_*]`)
+)
+
+// Render the subset of Markdown that is commonly used in documentation
+// comments: paragraphs, headings, lists, fenced code, and inline code, links
+// and emphasis. Anything else is rendered as text.
+func markdown(s string) template.HTML {
+	b := &strings.Builder{}
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	var para, list []string
+
+	flush := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>" + inline(strings.Join(para, " ")) + "</p>\n")
+			para = nil
+		}
+		if len(list) > 0 {
+			b.WriteString("<ul>\n")
+			for _, e := range list {
+				b.WriteString("<li>" + inline(e) + "</li>\n")
+			}
+			b.WriteString("</ul>\n")
+			list = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		l := strings.TrimRight(lines[i], " \t")
+		t := strings.TrimSpace(l)
+		switch {
+		case t == "":
+			flush()
+		case strings.HasPrefix(t, "```"):
+			flush()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			b.WriteString("<pre><code>" + template.HTMLEscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+		case strings.HasPrefix(t, "#"):
+			flush()
+			n := len(t) - len(strings.TrimLeft(t, "#"))
+			if n > 4 {
+				n = 4
+			}
+			h := string(rune('2' + n - 1)) // headings in comments are nested below the page heading
+			b.WriteString("<h" + h + ">" + inline(strings.TrimSpace(t[n:])) + "</h" + h + ">\n")
+		case strings.HasPrefix(t, "* ") || strings.HasPrefix(t, "- "):
+			if len(para) > 0 {
+				flush()
+			}
+			list = append(list, t[2:])
+		case len(list) > 0 && l != t: // continuation of a list item
+			list[len(list)-1] += " " + t
+		default:
+			if len(list) > 0 {
+				flush()
+			}
+			para = append(para, t)
+		}
+	}
+	flush()
+	return template.HTML(b.String())
+}
+
+// Render inline markup
+func inline(s string) string {
+	var code []string
+	s = inlineCode.ReplaceAllStringFunc(s, func(m string) string {
+		code = append(code, "<code>"+template.HTMLEscapeString(m[1:len(m)-1])+"</code>")
+		return "\x02"
+	})
+	s = template.HTMLEscapeString(s)
+	s = inlineLink.ReplaceAllStringFunc(s, func(m string) string {
+		v := inlineLink.FindStringSubmatch(m)
+		if !safeLink(html.UnescapeString(v[2])) {
+			return v[1]
+		}
+		return `<a href="` + v[2] + `">` + v[1] + `</a>`
+	})
+	s = inlineStrong.ReplaceAllString(s, `<strong>$1</strong>`)
+	s = inlineEm.ReplaceAllString(s, `$1<em>$2</em>`)
+	for _, e := range code {
+		s = strings.Replace(s, "\x02", e, 1)
+	}
+	return s
+}
+
+// Determine if a link target is safe to render: only web, mail, relative and
+// fragment links are permitted, so that links in comments can't run script in
+// the generated site
+func safeLink(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	default:
+		return false
+	}
+}
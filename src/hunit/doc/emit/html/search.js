@@ -0,0 +1,41 @@
+(function() {
+  var input = document.getElementById("search");
+  var results = document.getElementById("results");
+  var root = document.body.getAttribute("data-root") || "";
+  var index = window.searchIndex || [];
+
+  function text(e) {
+    return [e.title, e.suite, e.method, e.path, e.text].join(" ").toLowerCase();
+  }
+
+  input.addEventListener("input", function() {
+    results.innerHTML = "";
+    var terms = input.value.toLowerCase().split(/\s+/).filter(function(t) { return t.length > 0; });
+    if (terms.length === 0) {
+      return;
+    }
+    var n = 0;
+    for (var i = 0; i < index.length && n < 20; i++) {
+      var e = index[i], t = text(e);
+      if (!terms.every(function(v) { return t.indexOf(v) >= 0; })) {
+        continue;
+      }
+      var li = document.createElement("li");
+      var a = document.createElement("a");
+      a.href = root + e.url;
+      a.textContent = e.title;
+      var s = document.createElement("span");
+      s.className = "suite";
+      s.textContent = e.suite + (e.method ? " — " + e.method + " " + e.path : "");
+      li.appendChild(a);
+      li.appendChild(s);
+      results.appendChild(li);
+      n++;
+    }
+    if (n === 0) {
+      var li = document.createElement("li");
+      li.textContent = "No results";
+      results.appendChild(li);
+    }
+  });
+})();
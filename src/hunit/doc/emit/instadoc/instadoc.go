@@ -11,6 +11,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/instaunit/instaunit/hunit/doc/sample"
 	"github.com/instaunit/instaunit/hunit/testcase"
 	"github.com/instaunit/instaunit/hunit/text"
 )
@@ -26,11 +27,12 @@ type Generator struct {
 	w       io.WriteCloser
 	toc     *TOC
 	routes  []*Route
+	samples sample.Config
 }
 
 // Produce a new emitter
-func New(docpath string) *Generator {
-	return &Generator{docpath, nil, nil, nil}
+func New(docpath string, samples sample.Config) *Generator {
+	return &Generator{docpath, nil, nil, nil, samples}
 }
 
 // Init a suite; one doc output per suite
@@ -207,12 +209,22 @@ func (g *Generator) generate(suite *testcase.Suite, c testcase.Case, req *http.R
 		}
 	}
 
+	var samples []*Sample
+	for _, e := range sample.Generate(req, reqdata, g.samples.ForSuite(suite)) {
+		samples = append(samples, &Sample{
+			Lang:  e.Lang.String(),
+			Title: e.Lang.Title(),
+			Data:  e.Source,
+		})
+	}
+
 	var examples []*Example
 	if request != nil {
 		examples = []*Example{
 			{
 				Request:  request,
 				Response: response,
+				Samples:  samples,
 			},
 		}
 	}
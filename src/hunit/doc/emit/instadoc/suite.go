@@ -46,10 +46,17 @@ type Parameter struct {
 }
 
 type Example struct {
-	Title    string   `json:"title,omitempty"`
-	Detail   *Content `json:"detail,omitempty"`
-	Request  *Listing `json:"request,omitempty"`
-	Response *Listing `json:"response,omitempty"`
+	Title    string    `json:"title,omitempty"`
+	Detail   *Content  `json:"detail,omitempty"`
+	Request  *Listing  `json:"request,omitempty"`
+	Response *Listing  `json:"response,omitempty"`
+	Samples  []*Sample `json:"samples,omitempty"`
+}
+
+type Sample struct {
+	Lang  string `json:"lang"`
+	Title string `json:"title,omitempty"`
+	Data  string `json:"data"`
 }
 
 type Listing struct {
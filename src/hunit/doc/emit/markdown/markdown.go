@@ -10,6 +10,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/instaunit/instaunit/hunit/doc/sample"
 	"github.com/instaunit/instaunit/hunit/testcase"
 	"github.com/instaunit/instaunit/hunit/text"
 	"github.com/instaunit/instaunit/hunit/text/slug"
@@ -33,11 +34,12 @@ type Generator struct {
 	b       *bytes.Buffer
 	entries []entry
 	slugs   map[string]int
+	samples sample.Config
 }
 
 // Produce a new emitter
-func New(docpath string) *Generator {
-	return &Generator{docpath, nil, nil, make([]entry, 0), nil}
+func New(docpath string, samples sample.Config) *Generator {
+	return &Generator{docpath, nil, nil, make([]entry, 0), nil, samples}
 }
 
 // Init a suite; one doc output per suite
@@ -281,6 +283,12 @@ func (g *Generator) generate(w io.Writer, suite *testcase.Suite, c testcase.Case
 			doc += string(b.Bytes()) + "\n"
 			doc += "```\n\n"
 		}
+		for _, e := range sample.Generate(req, reqdata, g.samples.ForSuite(suite)) {
+			doc += fmt.Sprintf("**%s**\n\n", e.Lang.Title())
+			doc += "```" + e.Lang.Highlight() + "\n"
+			doc += e.Source + "\n"
+			doc += "```\n\n"
+		}
 	}
 
 	if rsp != nil {
@@ -1,18 +1,22 @@
 package openapi
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"os"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/instaunit/instaunit/hunit/httputil/mimetype"
+	"github.com/instaunit/instaunit/hunit/keys"
 	"github.com/instaunit/instaunit/hunit/testcase"
 	"github.com/instaunit/instaunit/hunit/text"
+
+	yaml "gopkg.in/yaml.v3"
 )
 
 const (
@@ -20,9 +24,17 @@ const (
 	typeMarkdown = "text/markdown"
 )
 
+// Generator configuration
+type Config struct {
+	Format Format // the format to write the document in
+	Base   string // a hand-written document to merge generated operations into
+}
+
 // An OpenAPI documentation generator
 type Generator struct {
 	docpath string
+	conf    Config
+	base    *yaml.Node
 	w       io.WriteCloser
 	routes  map[string]*Route
 	authns  map[string]testcase.Authentication
@@ -30,25 +42,35 @@ type Generator struct {
 }
 
 // Produce a new emitter
-func New(docpath string) *Generator {
+func New(docpath string, conf Config) (*Generator, error) {
+	var base *yaml.Node
+	if conf.Base != "" {
+		var err error
+		base, err = loadBase(conf.Base)
+		if err != nil {
+			return nil, fmt.Errorf("Could not load base document: %w", err)
+		}
+	}
 	return &Generator{
 		docpath: docpath,
+		conf:    conf,
+		base:    base,
 		w:       nil,
 		routes:  make(map[string]*Route),
 		tags:    make(map[string]Tag),
-	}
+	}, nil
 }
 
 // Init a suite
 func (g *Generator) Init(suite *testcase.Suite, docs string) error {
 	if g.w == nil {
-		out, err := os.OpenFile(path.Join(g.docpath, "service.json"), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		out, err := os.OpenFile(path.Join(g.docpath, "service"+g.conf.Format.Ext()), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
 		if err != nil {
 			return err
 		}
 		g.w = out
 	}
-	if len(suite.Authns) > 0 {
+	if len(suite.Authns) > 0 && g.authns == nil {
 		g.authns = make(map[string]testcase.Authentication)
 	}
 	for k, v := range suite.Authns {
@@ -79,113 +101,22 @@ func (g *Generator) Close() error {
 		g.w = nil
 	}()
 
-	enc := json.NewEncoder(g.w)
-	enc.SetIndent("", "  ")
-
 	var host string
 	paths := make(map[string]Path)
 	for k, v := range g.routes {
 		if len(v.Tests) < 1 {
 			continue // no specimens
 		}
-
-		p, ok := paths[k]
-		if !ok {
-			p = Path{
-				Path:       k,
-				Operations: make(map[string]Operation),
-			}
+		if host == "" {
+			host = v.Tests[0].Req.Req.Host
 		}
-
-		// The representative speciment for this collection. We try to use the
-		// first successful response encountered for this purpose. If one is not
-		// found, the first response is used instead.
-		var first, rep *Specimen
-
-		// Process responses
-		rsps := make(map[string]Status)
-		for i, e := range v.Tests {
-			if i == 0 {
-				first = &e
-			}
-			if rep == nil && e.Rsp.Rsp.StatusCode == http.StatusOK {
-				rep = &e
-			}
-			if host == "" {
-				host = e.Req.Req.Host
-			}
-			var rspcnt map[string]Reference
-			if rsp := e.Rsp; len(rsp.Data) > 0 {
-				ctype := text.Coalesce(firstValue(rsp.Rsp.Header["Content-Type"]), "text/plain")
-				rspcnt = map[string]Reference{
-					ctype: {
-						Schema: Schema{
-							Type:    "object",
-							Example: newValue(ctype, []byte(rsp.Data)),
-						},
-					},
-				}
-			}
-			rsps[strconv.Itoa(e.Rsp.Rsp.StatusCode)] = Status{
-				Summary:     e.Case.Response.Title,
-				Description: e.Case.Response.Comments,
-				Status:      e.Rsp.Rsp.Status,
-				Content:     rspcnt,
-			}
-		}
-		if rep == nil {
-			rep = first
-		}
-
-		// Process the request
-		m := strings.ToLower(rep.Req.Req.Method)
-		var id string
-		if v := rep.Case.Route.Id; v != "" {
-			id = v
-		} else if v := rep.Case.Route.Path; v != "" {
-			id = v
-		} else {
-			id = fmt.Sprintf("%s:%s", k, m)
-		}
-		var reqcnt Payload
-		if req := rep.Req; len(req.Data) > 0 {
-			ctype := text.Coalesce(firstValue(req.Req.Header["Content-Type"]), "text/plain")
-			reqcnt = Payload{
-				Content: map[string]Schema{
-					ctype: {
-						Example: newValue(ctype, []byte(req.Data)),
-					},
-				},
-			}
-		}
-
-		var params []Parameter
-		for k, v := range rep.Case.Params {
-			params = append(params, Parameter{
-				In:          QueryParameter,
-				Name:        k,
-				Schema:      Schema{Type: v.Type},
-				Description: v.Description,
-				Required:    v.Required,
-			})
+		p := Path{
+			Path:       k,
+			Operations: make(map[string]Operation),
 		}
-
-		var acls []SecurityRequirement
-		for k, v := range rep.Case.Security {
-			acls = append(acls, SecurityRequirement{}.Add(k, append(v.Scopes, v.Roles...)...))
-		}
-
-		p.Operations[m] = Operation{
-			Id:          id,
-			Summary:     text.Coalesce(rep.Case.Request.Title, rep.Case.Title),
-			Description: text.Coalesce(rep.Case.Request.Comments, rep.Case.Comments),
-			Tags:        []string{rep.Suite.Title},
-			Params:      params,
-			Request:     reqcnt,
-			Responses:   rsps,
-			Security:    acls,
+		for _, m := range v.Methods() {
+			p.Operations[strings.ToLower(m)] = operation(k, v.Specimens(m))
 		}
-
 		paths[k] = p
 	}
 
@@ -209,9 +140,10 @@ func (g *Generator) Close() error {
 		for _, v := range g.tags {
 			tags = append(tags, v)
 		}
+		sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
 	}
 
-	return enc.Encode(Service{
+	return g.write(g.w, Service{
 		Standard: version,
 		Consumes: []string{mimetype.JSON},
 		Produces: []string{mimetype.JSON},
@@ -229,6 +161,91 @@ func (g *Generator) Close() error {
 	})
 }
 
+// Produce an operation from the specimens of a route that share a method
+func operation(path string, tests []Specimen) Operation {
+	// The representative speciment for this collection. We try to use the
+	// first successful response encountered for this purpose. If one is not
+	// found, the first response is used instead.
+	var rep *Specimen
+
+	// Process responses; schemas are inferred from every specimen
+	rsps := make(map[string]Status)
+	for i, e := range tests {
+		if rep == nil && e.Rsp.Rsp.StatusCode == http.StatusOK {
+			rep = &tests[i]
+		}
+		code := strconv.Itoa(e.Rsp.Rsp.StatusCode)
+		status, ok := rsps[code]
+		if !ok {
+			status = Status{Status: e.Rsp.Rsp.Status}
+		}
+		status.Summary = text.Coalesce(e.Case.Response.Title, status.Summary)
+		status.Description = text.Coalesce(e.Case.Response.Comments, status.Description)
+		if rsp := e.Rsp; len(rsp.Data) > 0 {
+			ctype := contentType(rsp.Rsp.Header)
+			if status.Content == nil {
+				status.Content = make(map[string]MediaType)
+			}
+			status.Content[ctype] = mergeMediaType(status.Content[ctype], ctype, rsp.Data)
+		}
+		rsps[code] = status
+	}
+	if rep == nil {
+		rep = &tests[0]
+	}
+
+	// Process requests; the body is required if every specimen provides one
+	var reqcnt *Payload
+	var bodies int
+	for _, e := range tests {
+		if req := e.Req; len(req.Data) > 0 {
+			ctype := contentType(req.Req.Header)
+			if reqcnt == nil {
+				reqcnt = &Payload{Content: make(map[string]MediaType)}
+			}
+			reqcnt.Content[ctype] = mergeMediaType(reqcnt.Content[ctype], ctype, []byte(req.Data))
+			bodies++
+		}
+	}
+	if reqcnt != nil {
+		reqcnt.Required = bodies == len(tests)
+	}
+
+	var acls []SecurityRequirement
+	for _, k := range keys.Sorted(rep.Case.Security) {
+		v := rep.Case.Security[k]
+		acls = append(acls, SecurityRequirement{}.Add(k, append(v.Scopes, v.Roles...)...))
+	}
+
+	return Operation{
+		Id:          rep.Id(path),
+		Summary:     text.Coalesce(rep.Case.Request.Title, rep.Case.Title),
+		Description: text.Coalesce(rep.Case.Request.Comments, rep.Case.Comments),
+		Tags:        []string{rep.Suite.Title},
+		Params:      parameters(path, tests),
+		Request:     reqcnt,
+		Responses:   rsps,
+		Security:    acls,
+	}
+}
+
+// Merge a specimen entity into a media type. The first specimen is retained
+// as the example.
+func mergeMediaType(m MediaType, ctype string, data []byte) MediaType {
+	var s *Schema
+	if isJSON(ctype) {
+		s = inferJSON(data)
+	}
+	if s == nil {
+		s = &Schema{Type: typeString}
+	}
+	if m.Example == nil {
+		m.Example = newValue(ctype, data)
+	}
+	m.Schema = mergeSchema(m.Schema, s)
+	return m
+}
+
 // Generate documentation
 func (g *Generator) Case(suite *testcase.Suite, c testcase.Case, req *http.Request, reqdata string, rsp *http.Response, rspdata []byte) error {
 	var path string
@@ -253,11 +270,16 @@ func (g *Generator) Case(suite *testcase.Suite, c testcase.Case, req *http.Reque
 	return nil
 }
 
-func firstValue[E any](v []E) E {
-	var z E
-	if len(v) > 0 {
-		return v[0]
-	} else {
-		return z
+// The media type of an entity, without parameters
+func contentType(hdr http.Header) string {
+	ctype := text.Coalesce(hdr.Get("Content-Type"), typePlain)
+	if t, _, err := mime.ParseMediaType(ctype); err == nil {
+		return t
 	}
+	return ctype
+}
+
+// Determine if a media type is JSON
+func isJSON(ctype string) bool {
+	return ctype == mimetype.JSON || strings.HasSuffix(ctype, "+json")
 }
@@ -0,0 +1,324 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Document format
+type Format uint32
+
+const (
+	FormatJSON Format = iota
+	FormatYAML
+	FormatInvalid
+)
+
+var formatNames = []string{
+	"json",
+	"yaml",
+	"<invalid>",
+}
+
+var formatExts = []string{
+	".json",
+	".yaml",
+	".???",
+}
+
+// Parse a format
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "json":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	default:
+		return FormatInvalid, fmt.Errorf("Unsupported format: %v", s)
+	}
+}
+
+// Extension
+func (f Format) Ext() string {
+	if f >= FormatInvalid {
+		return ""
+	} else {
+		return formatExts[int(f)]
+	}
+}
+
+// Stringer
+func (f Format) String() string {
+	if f >= FormatInvalid {
+		return "<invalid>"
+	} else {
+		return formatNames[int(f)]
+	}
+}
+
+// Load a base document, in either YAML or JSON
+func loadBase(p string) (*yaml.Node, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	err = yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Content) < 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("Document is not an object: %s", p)
+	}
+	return doc.Content[0], nil
+}
+
+// Write a service document in the configured format, merging it into the base
+// document, if there is one. Output is ordered consistently so that documents
+// can be compared between runs.
+func (g *Generator) write(w io.Writer, svc Service) error {
+	data, err := json.Marshal(svc)
+	if err != nil {
+		return err
+	}
+	if g.base == nil && g.conf.Format == FormatJSON {
+		b := &bytes.Buffer{}
+		err = json.Indent(b, data, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(b.Bytes(), '\n'))
+		return err
+	}
+
+	var doc yaml.Node
+	err = yaml.Unmarshal(data, &doc) // JSON is YAML; this preserves ordering
+	if err != nil {
+		return err
+	}
+	root := doc.Content[0]
+	if g.base != nil {
+		root = mergeBase(g.base, root)
+	}
+
+	switch g.conf.Format {
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		return enc.Encode(blockStyle(root))
+	default:
+		b := &bytes.Buffer{}
+		err = writeJSON(b, root)
+		if err != nil {
+			return err
+		}
+		o := &bytes.Buffer{}
+		err = json.Indent(o, b.Bytes(), "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(o.Bytes(), '\n'))
+		return err
+	}
+}
+
+// Merge a generated document into a base document. The base provides the
+// document's metadata (info, servers, security, etc.); generated paths,
+// components and tags are merged into it. Where both documents define the same
+// value, the base takes precedence, so hand-written descriptions, schemas, and
+// so on override generated ones.
+func mergeBase(base, gen *yaml.Node) *yaml.Node {
+	res := copyNode(base)
+	for _, k := range []string{"paths", "components"} {
+		if v := mappingValue(gen, k); v != nil && len(v.Content) > 0 {
+			setMappingValue(res, k, mergeNodes(mappingValue(res, k), v, k))
+		}
+	}
+	if v := mappingValue(gen, "tags"); v != nil {
+		setMappingValue(res, "tags", mergeTags(mappingValue(res, "tags"), v))
+	}
+	return res
+}
+
+// Merge mappings recursively, preferring the values in the base. Keys that
+// only appear in the generated mapping are appended in order. The key is that
+// of the base in its parent mapping.
+func mergeNodes(base, gen *yaml.Node, key string) *yaml.Node {
+	if base == nil {
+		return gen
+	}
+	if base.Kind != yaml.MappingNode || gen.Kind != yaml.MappingNode || isAtomic(base, key) {
+		return base
+	}
+	res := copyNode(base)
+	for i := 0; i+1 < len(gen.Content); i += 2 {
+		k, v := gen.Content[i].Value, gen.Content[i+1]
+		setMappingValue(res, k, mergeNodes(mappingValue(res, k), v, k))
+	}
+	return res
+}
+
+// Determine if a mapping in the base replaces the generated one as a whole
+// rather than being merged with it. Combining the properties of a hand-written
+// schema or example with those of a generated one would describe neither, and
+// a reference must not have siblings.
+func isAtomic(n *yaml.Node, key string) bool {
+	switch key {
+	case "schema", "example":
+		return true
+	default:
+		return mappingValue(n, "$ref") != nil
+	}
+}
+
+// Merge tags by name, preferring those in the base
+func mergeTags(base, gen *yaml.Node) *yaml.Node {
+	if base == nil || base.Kind != yaml.SequenceNode {
+		return gen
+	}
+	res := copyNode(base)
+	names := make(map[string]struct{})
+	for _, e := range res.Content {
+		if v := mappingValue(e, "name"); v != nil {
+			names[v.Value] = struct{}{}
+		}
+	}
+	for _, e := range gen.Content {
+		if v := mappingValue(e, "name"); v != nil {
+			if _, ok := names[v.Value]; ok {
+				continue
+			}
+		}
+		res.Content = append(res.Content, e)
+	}
+	return res
+}
+
+func mappingValue(n *yaml.Node, k string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == k {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(n *yaml.Node, k string, v *yaml.Node) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == k {
+			n.Content[i+1] = v
+			return
+		}
+	}
+	n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, v)
+}
+
+// Copy a node, but not its descendants
+func copyNode(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.Content = append([]*yaml.Node(nil), n.Content...)
+	return &c
+}
+
+// Reset the flow style produced by parsing JSON so the document is written as
+// block YAML
+func blockStyle(n *yaml.Node) *yaml.Node {
+	n.Style &^= yaml.FlowStyle | yaml.DoubleQuotedStyle
+	for _, e := range n.Content {
+		blockStyle(e)
+	}
+	return n
+}
+
+// Write a node as JSON, preserving the order of mappings
+func writeJSON(w *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) > 0 {
+			return writeJSON(w, n.Content[0])
+		}
+		w.WriteString("null")
+	case yaml.AliasNode:
+		return writeJSON(w, n.Alias)
+	case yaml.MappingNode:
+		w.WriteString("{")
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				w.WriteString(",")
+			}
+			k, err := json.Marshal(n.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			w.Write(k)
+			w.WriteString(":")
+			err = writeJSON(w, n.Content[i+1])
+			if err != nil {
+				return err
+			}
+		}
+		w.WriteString("}")
+	case yaml.SequenceNode:
+		w.WriteString("[")
+		for i, e := range n.Content {
+			if i > 0 {
+				w.WriteString(",")
+			}
+			err := writeJSON(w, e)
+			if err != nil {
+				return err
+			}
+		}
+		w.WriteString("]")
+	default:
+		return writeScalar(w, n)
+	}
+	return nil
+}
+
+// Write a scalar as JSON. Numbers and literals are written as they appear in
+// the document, and anything else is written as a string, so values that
+// YAML would otherwise interpret, like dates, are reproduced exactly.
+func writeScalar(w *bytes.Buffer, n *yaml.Node) error {
+	var v interface{}
+	switch n.ShortTag() {
+	case "!!null":
+		w.WriteString("null")
+		return nil
+	case "!!int", "!!float":
+		if json.Valid([]byte(n.Value)) {
+			w.WriteString(n.Value)
+			return nil
+		}
+		var f float64 // e.g., '0x1f' or '1_000'
+		err := n.Decode(&f)
+		if err != nil {
+			return err
+		}
+		v = f
+	case "!!bool":
+		var b bool
+		err := n.Decode(&b)
+		if err != nil {
+			return err
+		}
+		v = b
+	default:
+		v = n.Value
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Write(data)
+	return nil
+}
@@ -0,0 +1,175 @@
+package openapi
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const base = `
+openapi: 3.0.3
+info:
+  title: Hand-written
+paths:
+  /users:
+    post:
+      summary: Create a user
+tags:
+  - name: Users
+    description: Hand-written
+`
+
+func TestWrite(t *testing.T) {
+	p := path.Join(t.TempDir(), "base.yml")
+	if !assert.Nil(t, os.WriteFile(p, []byte(base), 0644)) {
+		return
+	}
+	svc := Service{
+		Standard: version,
+		Info:     Info{Title: "API"},
+		Paths: map[string]Path{
+			"/users": {Operations: map[string]Operation{
+				"post": {Id: "createUser", Summary: "Generated", Responses: map[string]Status{"201": {Status: "201 Created"}}},
+				"get":  {Id: "listUsers", Responses: map[string]Status{"200": {Status: "200 OK"}}},
+			}},
+		},
+		Tags: []Tag{{Name: "Accounts"}, {Name: "Users", Description: "Generated"}},
+	}
+
+	g, err := New(t.TempDir(), Config{Format: FormatYAML, Base: p})
+	if !assert.Nil(t, err) {
+		return
+	}
+	b := &bytes.Buffer{}
+	if assert.Nil(t, g.write(b, svc)) {
+		assert.Equal(t, `openapi: 3.0.3
+info:
+  title: Hand-written
+paths:
+  /users:
+    post:
+      summary: Create a user
+      operationId: createUser
+      responses:
+        "201":
+          status: 201 Created
+    get:
+      operationId: listUsers
+      responses:
+        "200":
+          status: 200 OK
+tags:
+  - name: Users
+    description: Hand-written
+  - name: Accounts
+`, b.String())
+	}
+
+	g, err = New(t.TempDir(), Config{Format: FormatJSON, Base: p})
+	if !assert.Nil(t, err) {
+		return
+	}
+	b.Reset()
+	if assert.Nil(t, g.write(b, svc)) {
+		assert.Contains(t, b.String(), "{\n  \"openapi\": \"3.0.3\",\n  \"info\": {\n    \"title\": \"Hand-written\"\n  },")
+	}
+}
+
+func TestMergeSchemas(t *testing.T) {
+	p := path.Join(t.TempDir(), "base.yml")
+	err := os.WriteFile(p, []byte(`
+openapi: 3.0.3
+info: {title: Hand-written}
+paths:
+  /users:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/User'}
+      responses:
+        "201": {$ref: '#/components/responses/Created'}
+    get:
+      responses:
+        "200":
+          description: Hand-written
+          content:
+            application/json:
+              schema: {type: array}
+`), 0644)
+	if !assert.Nil(t, err) {
+		return
+	}
+	user := &Schema{Type: "object", Properties: map[string]*Schema{"name": {Type: "string"}}, Required: []string{"name"}}
+	svc := Service{
+		Standard: version,
+		Paths: map[string]Path{
+			"/users": {Operations: map[string]Operation{
+				"post": {
+					Id:        "createUser",
+					Request:   &Payload{Content: map[string]MediaType{"application/json": {Schema: user}}},
+					Responses: map[string]Status{"201": {Status: "201 Created"}},
+				},
+				"get": {
+					Id:        "listUsers",
+					Responses: map[string]Status{"200": {Status: "200 OK", Content: map[string]MediaType{"application/json": {Schema: &Schema{Type: "array", Items: user}}}}},
+				},
+			}},
+		},
+	}
+
+	// references and hand-written schemas replace generated ones as a whole
+	g, err := New(t.TempDir(), Config{Format: FormatYAML, Base: p})
+	if !assert.Nil(t, err) {
+		return
+	}
+	b := &bytes.Buffer{}
+	if assert.Nil(t, g.write(b, svc)) {
+		assert.Equal(t, `openapi: 3.0.3
+info:
+  title: Hand-written
+paths:
+  /users:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/User'
+      responses:
+        "201":
+          $ref: '#/components/responses/Created'
+      operationId: createUser
+    get:
+      responses:
+        "200":
+          description: Hand-written
+          content:
+            application/json:
+              schema:
+                type: array
+          status: 200 OK
+      operationId: listUsers
+`, b.String())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var doc yaml.Node
+	err := yaml.Unmarshal([]byte(`
+info: {version: 2024-01-02, title: "123"}
+example: {count: 0x1f, ratio: 1.50, exp: 1e3, on: true, legacy: yes, none: ~, empty: null, time: 10:30, list: [1, "2", three]}
+`), &doc)
+	if !assert.Nil(t, err) {
+		return
+	}
+	b := &bytes.Buffer{}
+	if assert.Nil(t, writeJSON(b, &doc)) {
+		assert.Equal(t, `{"info":{"version":"2024-01-02","title":"123"},"example":{"count":31,"ratio":1.50,"exp":1e3,"on":true,"legacy":"yes","none":null,"empty":null,"time":"10:30","list":[1,"2","three"]}}`, b.String())
+	}
+}
@@ -0,0 +1,117 @@
+package openapi
+
+import (
+	"sort"
+	"strings"
+)
+
+// Produce the parameters of an operation. Path parameters are those in the
+// path template; other parameters are those documented by test cases. When a
+// case does not declare the type of a parameter, its schema is inferred from
+// the values observed in requests.
+func parameters(path string, tests []Specimen) []Parameter {
+	tmpl := pathParams(path)
+	params := make(map[string]*Parameter)
+	declared := make(map[string]bool)
+
+	param := func(loc ParameterLocation, name string) (*Parameter, string) {
+		k := loc.String() + ":" + name
+		p, ok := params[k]
+		if !ok {
+			p = &Parameter{In: loc, Name: name, Required: loc == PathParameter}
+			params[k] = p
+		}
+		return p, k
+	}
+
+	for _, e := range tests {
+		req := e.Req.Req
+		vals := pathValues(path, req.URL.Path)
+		for _, n := range tmpl {
+			p, k := param(PathParameter, n)
+			if v, ok := vals[n]; ok && !declared[k] {
+				p.Schema = mergeSchema(p.Schema, inferText(v))
+			}
+		}
+		for n, d := range e.Case.Params {
+			loc := parseParameterLocation(d.Location)
+			if contains(tmpl, n) {
+				loc = PathParameter
+			}
+			p, k := param(loc, n)
+			if d.Description != "" {
+				p.Description = d.Description
+			}
+			if d.Required {
+				p.Required = true
+			}
+			if d.Type != "" {
+				p.Schema, declared[k] = &Schema{Type: d.Type}, true
+			}
+			if declared[k] {
+				continue
+			}
+			var obs []string
+			switch loc {
+			case QueryParameter:
+				obs = req.URL.Query()[n]
+			case HeaderParameter:
+				obs = req.Header.Values(n)
+			case CookieParameter:
+				if c, err := req.Cookie(n); err == nil {
+					obs = []string{c.Value}
+				}
+			}
+			for _, v := range obs {
+				p.Schema = mergeSchema(p.Schema, inferText(v))
+			}
+		}
+	}
+
+	res := make([]Parameter, 0, len(params))
+	for _, p := range params {
+		if p.Schema == nil {
+			p.Schema = &Schema{Type: typeString}
+		}
+		res = append(res, *p)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].In != res[j].In {
+			return res[i].In < res[j].In
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// The names of the parameters in a path template; e.g., '/users/{user_id}'
+func pathParams(path string) []string {
+	var names []string
+	for _, e := range strings.Split(path, "/") {
+		if strings.HasPrefix(e, "{") && strings.HasSuffix(e, "}") {
+			names = append(names, e[1:len(e)-1])
+		}
+	}
+	return names
+}
+
+// Extract the values of path parameters from a request path. The template is
+// aligned with the end of the path, since the path may include a base path
+// that the template does not.
+func pathValues(tmpl, path string) map[string]string {
+	t := strings.Split(strings.Trim(tmpl, "/"), "/")
+	p := strings.Split(strings.Trim(path, "/"), "/")
+	if len(p) < len(t) {
+		return nil
+	}
+	p = p[len(p)-len(t):]
+	vals := make(map[string]string)
+	for i, e := range t {
+		if strings.HasPrefix(e, "{") && strings.HasSuffix(e, "}") {
+			vals[e[1:len(e)-1]] = p[i]
+		} else if e != p[i] {
+			return nil
+		}
+	}
+	return vals
+}
@@ -0,0 +1,228 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	typeString  = "string"
+	typeInteger = "integer"
+	typeNumber  = "number"
+	typeBoolean = "boolean"
+	typeArray   = "array"
+	typeObject  = "object"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// A schema inferred from specimens
+type Schema struct {
+	Type       string             `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Nullable   bool               `json:"nullable,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	OneOf      []*Schema          `json:"oneOf,omitempty"`
+}
+
+// Arrays must describe their items, even if none were observed
+func (s Schema) MarshalJSON() ([]byte, error) {
+	type schema Schema
+	if s.Type == typeArray && s.Items == nil {
+		s.Items = &Schema{}
+	}
+	return json.Marshal(schema(s))
+}
+
+// Infer a schema from a JSON entity. If the entity is not valid JSON, nil is
+// returned.
+func inferJSON(data []byte) *Schema {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil
+	}
+	return inferValue(v)
+}
+
+// Infer a schema from a decoded JSON value
+func inferValue(v interface{}) *Schema {
+	switch c := v.(type) {
+	case nil:
+		return &Schema{Nullable: true}
+	case bool:
+		return &Schema{Type: typeBoolean}
+	case json.Number:
+		if _, err := c.Int64(); err == nil {
+			return &Schema{Type: typeInteger}
+		}
+		return &Schema{Type: typeNumber}
+	case string:
+		return &Schema{Type: typeString, Format: inferFormat(c)}
+	case []interface{}:
+		var items *Schema
+		for _, e := range c {
+			items = mergeSchema(items, inferValue(e))
+		}
+		return &Schema{Type: typeArray, Items: items}
+	case map[string]interface{}:
+		s := &Schema{Type: typeObject, Properties: make(map[string]*Schema)}
+		for k, e := range c {
+			s.Properties[k] = inferValue(e)
+			s.Required = append(s.Required, k)
+		}
+		sort.Strings(s.Required)
+		return s
+	default:
+		return &Schema{}
+	}
+}
+
+// Infer a schema from a textual value, such as a parameter
+func inferText(v string) *Schema {
+	if v == "true" || v == "false" {
+		return &Schema{Type: typeBoolean}
+	}
+	if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return &Schema{Type: typeInteger}
+	}
+	if _, err := strconv.ParseFloat(v, 64); err == nil {
+		return &Schema{Type: typeNumber}
+	}
+	return &Schema{Type: typeString, Format: inferFormat(v)}
+}
+
+// Infer the format of a string
+func inferFormat(v string) string {
+	if uuidPattern.MatchString(v) {
+		return "uuid"
+	}
+	if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return "date-time"
+	}
+	if _, err := time.Parse(time.DateOnly, v); err == nil {
+		return "date"
+	}
+	if strings.Contains(v, "@") && !strings.ContainsAny(v, " <>") {
+		if a, err := mail.ParseAddress(v); err == nil && a.Address == v {
+			return "email"
+		}
+	}
+	if u, err := url.Parse(v); err == nil && u.Scheme != "" && u.Host != "" {
+		return "uri"
+	}
+	return ""
+}
+
+// Merge two schemas into a schema that describes values of either. Properties
+// are only required if they are required by both schemas.
+func mergeSchema(a, b *Schema) *Schema {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if len(a.OneOf) > 0 || len(b.OneOf) > 0 {
+		return mergeAlternatives(a, b)
+	}
+	if a.Type == "" || b.Type == "" { // untyped null
+		var s Schema
+		if a.Type == "" {
+			s = *b
+		} else {
+			s = *a
+		}
+		s.Nullable = s.Nullable || a.Nullable || b.Nullable
+		return &s
+	}
+
+	s := &Schema{Type: a.Type, Nullable: a.Nullable || b.Nullable}
+	switch {
+	case a.Type == b.Type:
+	case isNumeric(a.Type) && isNumeric(b.Type):
+		s.Type = typeNumber
+	default:
+		return mergeAlternatives(a, b)
+	}
+
+	switch s.Type {
+	case typeString:
+		if a.Format == b.Format {
+			s.Format = a.Format
+		}
+	case typeArray:
+		s.Items = mergeSchema(a.Items, b.Items)
+	case typeObject:
+		s.Properties = make(map[string]*Schema)
+		for k, v := range a.Properties {
+			s.Properties[k] = mergeSchema(v, b.Properties[k])
+		}
+		for k, v := range b.Properties {
+			if _, ok := a.Properties[k]; !ok {
+				s.Properties[k] = v
+			}
+		}
+		for _, e := range a.Required {
+			if contains(b.Required, e) {
+				s.Required = append(s.Required, e)
+			}
+		}
+	}
+	return s
+}
+
+// Merge schemas of different types into alternatives, merging each schema
+// into an alternative of a compatible type, if there is one.
+func mergeAlternatives(a, b *Schema) *Schema {
+	var alts []*Schema
+	var nullable bool
+	for _, s := range []*Schema{a, b} {
+		nullable = nullable || s.Nullable
+		if len(s.OneOf) > 0 {
+			alts = append(alts, s.OneOf...)
+		} else {
+			alts = append(alts, s)
+		}
+	}
+	var res []*Schema
+outer:
+	for _, e := range alts {
+		for i, x := range res {
+			if x.Type == e.Type || x.Type == "" || e.Type == "" || (isNumeric(x.Type) && isNumeric(e.Type)) {
+				res[i] = mergeSchema(x, e)
+				continue outer
+			}
+		}
+		res = append(res, e)
+	}
+	if len(res) == 1 {
+		s := *res[0]
+		s.Nullable = s.Nullable || nullable
+		return &s
+	}
+	return &Schema{OneOf: res, Nullable: nullable}
+}
+
+func isNumeric(t string) bool {
+	return t == typeInteger || t == typeNumber
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package openapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInferSchema(t *testing.T) {
+	var s *Schema
+	for _, e := range []string{
+		`{"id": "3fa85f64-5717-4562-b3fc-2c963f66afa6", "count": 1, "nick": null, "tags": [], "created": "2024-01-02T03:04:05Z"}`,
+		`{"id": "6fa85f64-5717-4562-b3fc-2c963f66afa6", "count": 1.5, "nick": "jj", "tags": ["a"], "extra": true}`,
+	} {
+		s = mergeSchema(s, inferJSON([]byte(e)))
+	}
+	if assert.NotNil(t, s) {
+		assert.Equal(t, typeObject, s.Type)
+		assert.Equal(t, []string{"count", "id", "nick", "tags"}, s.Required) // 'created' and 'extra' are optional
+		assert.Equal(t, &Schema{Type: typeString, Format: "uuid"}, s.Properties["id"])
+		assert.Equal(t, &Schema{Type: typeNumber}, s.Properties["count"])
+		assert.Equal(t, &Schema{Type: typeString, Nullable: true}, s.Properties["nick"])
+		assert.Equal(t, &Schema{Type: typeArray, Items: &Schema{Type: typeString}}, s.Properties["tags"])
+		assert.Equal(t, &Schema{Type: typeString, Format: "date-time"}, s.Properties["created"])
+		assert.Equal(t, &Schema{Type: typeBoolean}, s.Properties["extra"])
+	}
+
+	s = mergeSchema(inferJSON([]byte(`1`)), inferJSON([]byte(`"one"`)))
+	assert.Equal(t, &Schema{OneOf: []*Schema{{Type: typeInteger}, {Type: typeString}}}, s)
+	s = mergeSchema(s, inferJSON([]byte(`2.5`)))
+	assert.Equal(t, &Schema{OneOf: []*Schema{{Type: typeNumber}, {Type: typeString}}}, s)
+
+	assert.Nil(t, inferJSON([]byte(`not json`)))
+	assert.Equal(t, &Schema{Type: typeBoolean}, inferText("true"))
+	assert.Equal(t, &Schema{Type: typeInteger}, inferText("123"))
+	assert.Equal(t, &Schema{Type: typeString, Format: "date"}, inferText("2024-01-02"))
+	assert.Equal(t, map[string]string{"user_id": "123"}, pathValues("/users/{user_id}", "/v1/users/123"))
+}
@@ -6,7 +6,6 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/instaunit/instaunit/hunit/httputil/mimetype"
 	"github.com/instaunit/instaunit/hunit/testcase"
 )
 
@@ -44,6 +43,28 @@ type Route struct {
 	Tests []Specimen
 }
 
+// The methods specimens were produced with, in the order they were observed
+func (r *Route) Methods() []string {
+	var m []string
+	for _, e := range r.Tests {
+		if !contains(m, e.Req.Req.Method) {
+			m = append(m, e.Req.Req.Method)
+		}
+	}
+	return m
+}
+
+// The specimens produced with a method
+func (r *Route) Specimens(method string) []Specimen {
+	var s []Specimen
+	for _, e := range r.Tests {
+		if e.Req.Req.Method == method {
+			s = append(s, e)
+		}
+	}
+	return s
+}
+
 type Value struct {
 	Summary     string `json:"summary,omitempty"`
 	Description string `json:"description,omitempty"`
@@ -51,32 +72,27 @@ type Value struct {
 }
 
 func newValue(ctype string, data []byte) interface{} {
-	switch ctype {
-	case mimetype.JSON:
+	if isJSON(ctype) && json.Valid(data) {
 		return json.RawMessage(data)
-	default:
-		return Value{Value: string(data)}
 	}
+	return Value{Value: string(data)}
 }
 
-type Schema struct {
-	Type    string      `json:"type,omitempty"`
+type MediaType struct {
+	Schema  *Schema     `json:"schema,omitempty"`
 	Example interface{} `json:"example,omitempty"` // representative object or Value
 }
 
 type Payload struct {
-	Content map[string]Schema `json:"content,omitempty"`
-}
-
-type Reference struct {
-	Schema Schema `json:"schema"`
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content,omitempty"`
 }
 
 type Status struct {
 	Summary     string               `json:"summary,omitempty"`
 	Description string               `json:"description,omitempty"`
 	Status      string               `json:"status"`
-	Content     map[string]Reference `json:"content,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
 }
 
 type Operation struct {
@@ -85,9 +101,9 @@ type Operation struct {
 	Description string                `json:"description,omitempty"`
 	Tags        []string              `json:"tags,omitempty"`
 	Params      []Parameter           `json:"parameters,omitempty"`
-	Request     Payload               `json:"requestBody"`
+	Request     *Payload              `json:"requestBody,omitempty"`
 	Responses   map[string]Status     `json:"responses"`
-	Security    []SecurityRequirement `json:"security"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
 }
 
 type Path struct {
@@ -104,12 +120,25 @@ type ParameterLocation int
 const (
 	QueryParameter ParameterLocation = iota
 	PathParameter
+	HeaderParameter
+	CookieParameter
 	parameterLocationCount
 )
 
 var parameterLocationNames = []string{
 	"query",
 	"path",
+	"header",
+	"cookie",
+}
+
+func parseParameterLocation(s string) ParameterLocation {
+	for i, e := range parameterLocationNames {
+		if strings.EqualFold(s, e) {
+			return ParameterLocation(i)
+		}
+	}
+	return QueryParameter
 }
 
 func (p ParameterLocation) MarshalJSON() ([]byte, error) {
@@ -129,13 +158,16 @@ type Parameter struct {
 	Name        string            `json:"name,omitempty"`
 	Description string            `json:"description,omitempty"`
 	Required    bool              `json:"required"`
-	Schema      Schema            `json:"schema,omitempty"`
+	Schema      *Schema           `json:"schema,omitempty"`
 }
 
 type SecurityRequirement map[string][]string
 
 func (a SecurityRequirement) Add(realm string, scopes ...string) SecurityRequirement {
 	a[realm] = append(a[realm], scopes...)
+	if a[realm] == nil {
+		a[realm] = []string{} // a requirement without scopes is an empty list, not null
+	}
 	return a
 }
 
@@ -146,7 +178,7 @@ type Info struct {
 }
 
 type Components struct {
-	Security map[string]SecurityScheme `json:"securitySchemes"`
+	Security map[string]SecurityScheme `json:"securitySchemes,omitempty"`
 }
 
 type SecurityScheme struct {
@@ -173,5 +205,5 @@ type Service struct {
 	Info       Info            `json:"info"`
 	Host       string          `json:"host"`
 	Paths      map[string]Path `json:"paths"`
-	Tags       []Tag           `json:"tags"`
+	Tags       []Tag           `json:"tags,omitempty"`
 }
@@ -0,0 +1,74 @@
+package postman
+
+// The Postman collection format version 2.1 schema
+const schemaURL = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+type Info struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      string `json:"schema"`
+}
+
+type Collection struct {
+	Info      Info        `json:"info"`
+	Items     []*Item     `json:"item"`
+	Variables []*Variable `json:"variable,omitempty"`
+}
+
+// An item is either a folder, which contains items, or a request
+type Item struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Items       []*Item     `json:"item,omitempty"`
+	Request     *Request    `json:"request,omitempty"`
+	Responses   []*Response `json:"response,omitempty"`
+}
+
+type Variable struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Type  string `json:"type,omitempty"`
+}
+
+type Pair struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+type URL struct {
+	Raw   string   `json:"raw"`
+	Host  []string `json:"host,omitempty"`
+	Path  []string `json:"path,omitempty"`
+	Query []*Pair  `json:"query,omitempty"`
+}
+
+type Options struct {
+	Raw struct {
+		Language string `json:"language"`
+	} `json:"raw"`
+}
+
+type Body struct {
+	Mode    string   `json:"mode"`
+	Raw     string   `json:"raw"`
+	Options *Options `json:"options,omitempty"`
+}
+
+type Request struct {
+	Method      string  `json:"method"`
+	Headers     []*Pair `json:"header"`
+	Body        *Body   `json:"body,omitempty"`
+	URL         *URL    `json:"url"`
+	Description string  `json:"description,omitempty"`
+}
+
+type Response struct {
+	Name            string   `json:"name"`
+	OriginalRequest *Request `json:"originalRequest,omitempty"`
+	Status          string   `json:"status"`
+	Code            int      `json:"code"`
+	Language        string   `json:"_postman_previewlanguage,omitempty"`
+	Headers         []*Pair  `json:"header"`
+	Body            string   `json:"body"`
+}
@@ -0,0 +1,480 @@
+package postman
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/instaunit/instaunit/hunit/doc/sample"
+	"github.com/instaunit/instaunit/hunit/keys"
+	"github.com/instaunit/instaunit/hunit/testcase"
+	"github.com/instaunit/instaunit/hunit/text"
+)
+
+const (
+	collectionFile = "collection.postman_collection.json"
+	baseURLVar     = "baseUrl"
+)
+
+// Generator configuration
+type Config struct {
+	BaseURL string // the base URL for requests, which is replaced by a variable
+}
+
+// A suite being documented
+type folder struct {
+	item     *Item
+	sections []*Item
+	bykey    map[string]*Item
+	items    []*Item           // requests that are not assigned to a section
+	vars     map[string]string // the suite's globals which are represented by variables
+}
+
+// A Postman collection generator. Every suite is a folder in the collection
+// which contains a folder for every section of its table of contents. Requests
+// are relative to a base URL variable and the values of secret headers are
+// replaced by variables, which are declared by the collection without values.
+//
+// Suite globals with literal values are declared as variables as well, and
+// where a request refers to a global its value is replaced by a reference to
+// the variable. Variables are shared by the whole collection, so a global is
+// only represented by a variable in the first suite that declares it, unless
+// other suites declare the same value for it.
+type Generator struct {
+	docpath string
+	conf    Config
+	samples sample.Config
+	folders []*Item
+	vars    map[string]struct{}
+	globals map[string]string
+	cur     *folder
+}
+
+// Produce a new emitter
+func New(docpath string, conf Config, samples sample.Config) *Generator {
+	return &Generator{
+		docpath: docpath,
+		conf:    conf,
+		samples: samples,
+		vars:    make(map[string]struct{}),
+		globals: make(map[string]string),
+	}
+}
+
+// Init a suite; one folder per suite
+func (g *Generator) Init(suite *testcase.Suite, docs string) error {
+	f := &folder{
+		item: &Item{
+			Name:        text.Coalesce(strings.TrimSpace(suite.Title), docs[:len(docs)-len(path.Ext(docs))]),
+			Description: strings.TrimSpace(suite.Comments),
+		},
+		bykey: make(map[string]*Item),
+		vars:  make(map[string]string),
+	}
+	for k, v := range literalGlobals(suite.Globals) {
+		if x, ok := g.globals[k]; !ok || x == v {
+			g.globals[k] = v
+			f.vars[k] = v
+		}
+	}
+	for _, e := range suite.TOC.Sections {
+		s := &Item{Name: text.Coalesce(e.Title, e.Key)}
+		f.sections = append(f.sections, s)
+		f.bykey[e.Key] = s
+	}
+	g.cur = f
+	return nil
+}
+
+// Finish a suite
+func (g *Generator) Finalize(suite *testcase.Suite) error {
+	f := g.cur
+	if f == nil {
+		return nil
+	}
+	g.cur = nil
+	for _, e := range f.sections {
+		if len(e.Items) > 0 {
+			f.item.Items = append(f.item.Items, e)
+		}
+	}
+	f.item.Items = append(f.item.Items, f.items...)
+	if len(f.item.Items) > 0 {
+		g.folders = append(g.folders, f.item)
+	}
+	return nil
+}
+
+// Write the collection
+func (g *Generator) Close() error {
+	if len(g.folders) == 0 {
+		return nil // nothing to do
+	}
+
+	name := "Instaunit"
+	if u, err := url.Parse(g.conf.BaseURL); err == nil && u.Host != "" {
+		name = u.Host
+	}
+	c := Collection{
+		Info: Info{
+			Name:   name,
+			Schema: schemaURL,
+		},
+		Items: g.folders,
+	}
+	if g.conf.BaseURL != "" {
+		c.Variables = append(c.Variables, &Variable{Key: baseURLVar, Value: strings.TrimSuffix(g.conf.BaseURL, "/"), Type: "string"})
+	}
+	for _, k := range keys.Sorted(g.globals) {
+		c.Variables = append(c.Variables, &Variable{Key: k, Value: g.globals[k], Type: "string"})
+	}
+	for _, k := range keys.Sorted(g.vars) {
+		if _, ok := g.globals[k]; !ok {
+			c.Variables = append(c.Variables, &Variable{Key: k, Value: "", Type: "string"})
+		}
+	}
+
+	out, err := os.OpenFile(path.Join(g.docpath, collectionFile), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(c)
+}
+
+// Generate documentation
+func (g *Generator) Case(suite *testcase.Suite, c testcase.Case, req *http.Request, reqdata string, rsp *http.Response, rspdata []byte) error {
+	f := g.cur
+	if f == nil || req == nil {
+		return nil
+	}
+
+	var title string
+	if c.Title != "" {
+		title = strings.TrimSpace(c.Title)
+	} else {
+		title = fmt.Sprintf("%s %s", c.Request.Method, c.Request.URL)
+	}
+
+	if len(reqdata) > 0 && suite.Config.Doc.FormatEntities {
+		t := text.Coalesce(c.Request.Format, req.Header.Get("Content-Type"))
+		d, err := text.FormatEntity([]byte(reqdata), t)
+		if err == nil {
+			reqdata = string(d)
+		} else if err != text.ErrUnsupportedContentType {
+			fmt.Printf("* * * Invalid request entity could not be formatted: %v\n", t)
+		}
+	}
+
+	u := g.url(req.URL, c.Params)
+	u.substitute(c.Request.URL, c.Request.Params, f.vars)
+
+	r := &Request{
+		Method:      req.Method,
+		Headers:     g.headers(req.Header, g.samples.ForSuite(suite), f.vars, c.Request.Headers),
+		URL:         u,
+		Description: strings.TrimSpace(c.Comments),
+	}
+	if reqdata != "" {
+		r.Body = &Body{Mode: "raw", Raw: substitute(reqdata, c.Request.Entity, f.vars, true, true)}
+		if l := language(req.Header.Get("Content-Type")); l != "" {
+			r.Body.Options = &Options{}
+			r.Body.Options.Raw.Language = l
+		}
+	}
+
+	item := &Item{Name: title, Request: r}
+	if rsp != nil {
+		if len(rspdata) > 0 && suite.Config.Doc.FormatEntities {
+			t := text.Coalesce(c.Response.Format, rsp.Header.Get("Content-Type"))
+			d, err := text.FormatEntity(rspdata, t)
+			if err == nil {
+				rspdata = d
+			} else if err != text.ErrUnsupportedContentType {
+				fmt.Printf("* * * Invalid entity could not be formatted: %v\n", t)
+			}
+		}
+		item.Responses = []*Response{{
+			Name:            text.Coalesce(strings.TrimSpace(c.Response.Title), title),
+			OriginalRequest: r,
+			Status:          http.StatusText(rsp.StatusCode),
+			Code:            rsp.StatusCode,
+			Language:        text.Coalesce(language(rsp.Header.Get("Content-Type")), "text"),
+			Headers:         g.headers(rsp.Header, sample.Config{}, nil, nil),
+			Body:            string(rspdata),
+		}}
+	}
+
+	if s, ok := f.bykey[c.Section]; ok && c.Section != "" {
+		s.Items = append(s.Items, item)
+	} else {
+		f.items = append(f.items, item)
+	}
+	return nil
+}
+
+// Produce headers in order, replacing the values of secrets with variables.
+// The values of variables which are referred to by the header templates they
+// were produced from are replaced by references to them.
+func (g *Generator) headers(hdr http.Header, conf sample.Config, vars, tmpl map[string]string) []*Pair {
+	ctmpl := make(map[string]string, len(tmpl))
+	for k, v := range tmpl {
+		ctmpl[http.CanonicalHeaderKey(k)] = v
+	}
+
+	keys := make([]string, 0, len(hdr))
+	for k := range hdr {
+		if !strings.EqualFold(k, "Content-Length") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	pairs := make([]*Pair, 0, len(keys))
+	for _, k := range keys {
+		v := strings.Join(hdr[k], ",")
+		if conf.Redacts(k) {
+			n := variable(k)
+			g.vars[n] = struct{}{}
+			v = "{{" + n + "}}"
+			if s, _, ok := strings.Cut(hdr.Get(k), " "); ok && strings.HasSuffix(strings.ToLower(k), "authorization") {
+				v = s + " " + v // retain the scheme
+			}
+		} else if t, ok := ctmpl[http.CanonicalHeaderKey(k)]; ok {
+			v = substitute(v, t, vars, true, false)
+		}
+		pairs = append(pairs, &Pair{Key: k, Value: v})
+	}
+	return pairs
+}
+
+// Produce a URL which is relative to the base URL variable, if possible
+func (g *Generator) url(u *url.URL, params map[string]testcase.Parameter) *URL {
+	v := &URL{}
+	var rel string
+	base := strings.TrimSuffix(g.conf.BaseURL, "/")
+	abs := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path, RawPath: u.RawPath}).String()
+	if x := strings.TrimPrefix(abs, base); base != "" && x != abs && (x == "" || x[0] == '/') {
+		v.Host = []string{"{{" + baseURLVar + "}}"}
+		v.Raw = "{{" + baseURLVar + "}}" + x
+		rel = x
+	} else {
+		v.Host = strings.Split(u.Host, ".")
+		v.Raw = abs
+		rel = u.EscapedPath()
+	}
+	for _, e := range strings.Split(rel, "/") {
+		if e != "" {
+			v.Path = append(v.Path, e)
+		}
+	}
+	if q := u.RawQuery; q != "" {
+		v.Raw += "?" + q
+		for _, e := range strings.Split(q, "&") {
+			k, x, _ := strings.Cut(e, "=")
+			if d, err := url.QueryUnescape(k); err == nil {
+				k = d
+			}
+			if d, err := url.QueryUnescape(x); err == nil {
+				x = d
+			}
+			v.Query = append(v.Query, &Pair{Key: k, Value: x, Description: strings.TrimSpace(params[k].Description)})
+		}
+	}
+	return v
+}
+
+// Replace the values of variables which are referred to by the template a URL
+// was produced from, and the templates of its query parameters, with references
+// to the variables
+func (v *URL) substitute(tmpl string, params, vars map[string]string) {
+	tpath, tquery, _ := strings.Cut(tmpl, "?")
+	base, query, hasQuery := strings.Cut(v.Raw, "?")
+
+	if rel := "/" + strings.Join(v.Path, "/"); len(v.Path) > 0 {
+		trimmed := strings.TrimSuffix(base, "/")
+		if x := substitute(rel, strings.TrimSuffix(tpath, "/"), vars, false, false); x != rel && strings.HasSuffix(trimmed, rel) {
+			base = strings.TrimSuffix(trimmed, rel) + x + base[len(trimmed):]
+			v.Path = v.Path[:0]
+			for _, e := range strings.Split(x, "/") {
+				if e != "" {
+					v.Path = append(v.Path, e)
+				}
+			}
+		}
+	}
+
+	tq := make(map[string]string)
+	for _, e := range strings.Split(tquery, "&") {
+		if k, x, ok := strings.Cut(e, "="); ok {
+			tq[k] = x
+		}
+	}
+	for k, x := range params {
+		tq[k] = x
+	}
+	if hasQuery {
+		raw := strings.Split(query, "&")
+		for i, e := range v.Query {
+			t, ok := tq[e.Key]
+			if !ok || i >= len(raw) {
+				continue
+			}
+			if x := substitute(e.Value, t, vars, true, false); x != e.Value {
+				e.Value = x
+				k, _, _ := strings.Cut(raw[i], "=")
+				raw[i] = k + "=" + unescapeBraces.Replace(url.QueryEscape(x))
+			}
+		}
+		query = "?" + strings.Join(raw, "&")
+	}
+
+	v.Raw = base + query
+}
+
+// Variable references are not escaped in raw URLs
+var unescapeBraces = strings.NewReplacer("%7B", "{", "%7D", "}")
+
+// Produce the globals which can be represented by variables; those with
+// literal, scalar values. Globals which are expressions are evaluated when the
+// suite is run, so their values are not known here.
+func literalGlobals(globals map[string]interface{}) map[string]string {
+	vars := make(map[string]string)
+	for k, v := range globals {
+		switch x := v.(type) {
+		case string:
+			if !strings.Contains(x, "${") {
+				vars[k] = x
+			}
+		case int, int64, float64, bool:
+			vars[k] = fmt.Sprint(x)
+		}
+	}
+	return vars
+}
+
+// Replace the values of the variables which a template refers to with
+// references to the variables, e.g., if the template '/users/${id}' produced
+// '/users/1' the result is '/users/{{id}}'. The text is matched against the
+// template so that only the text which was produced by a reference is
+// replaced; if it does not match, it is returned unchanged. Unless the match
+// is anchored the text may have a prefix which the template does not produce
+// (e.g., a base URL) and a loose match disregards whitespace, which may have
+// been added when an entity was formatted.
+func substitute(s, tmpl string, vars map[string]string, anchored, loose bool) string {
+	var names []string
+	b := &strings.Builder{}
+	b.WriteString("(?s)")
+	if anchored {
+		b.WriteString("^")
+	}
+	for _, e := range segments(tmpl) {
+		if !e.expr {
+			for _, r := range e.text {
+				switch {
+				case !loose:
+					b.WriteString(regexp.QuoteMeta(string(r)))
+				case !unicode.IsSpace(r):
+					b.WriteString(regexp.QuoteMeta(string(r)) + `\s*`)
+				}
+			}
+		} else if v := vars[strings.TrimSpace(e.text)]; v != "" {
+			names = append(names, strings.TrimSpace(e.text))
+			b.WriteString("(" + regexp.QuoteMeta(v) + ")")
+		} else {
+			b.WriteString("(?:.*?)")
+		}
+	}
+	b.WriteString("$")
+	if len(names) == 0 {
+		return s
+	}
+
+	m := regexp.MustCompile(b.String()).FindStringSubmatchIndex(s)
+	if m == nil {
+		return s
+	}
+	out := &strings.Builder{}
+	var x int
+	for i, k := range names {
+		out.WriteString(s[x:m[(i+1)*2]])
+		out.WriteString("{{" + k + "}}")
+		x = m[(i+1)*2+1]
+	}
+	out.WriteString(s[x:])
+	return out.String()
+}
+
+// A literal or expression segment of a template
+type segment struct {
+	text string
+	expr bool
+}
+
+// Split a template into literal text and the expressions it refers to
+func segments(tmpl string) []segment {
+	var segs []segment
+	lit := &strings.Builder{}
+	for i := 0; i < len(tmpl); i++ {
+		if tmpl[i] == '\\' && i+1 < len(tmpl) {
+			i++
+			lit.WriteByte(tmpl[i])
+		} else if strings.HasPrefix(tmpl[i:], "${") {
+			n := strings.IndexByte(tmpl[i:], '}')
+			if n < 0 {
+				lit.WriteString(tmpl[i:])
+				break
+			}
+			if lit.Len() > 0 {
+				segs = append(segs, segment{text: lit.String()})
+				lit.Reset()
+			}
+			segs = append(segs, segment{text: tmpl[i+2 : i+n], expr: true})
+			i += n
+		} else {
+			lit.WriteByte(tmpl[i])
+		}
+	}
+	if lit.Len() > 0 {
+		segs = append(segs, segment{text: lit.String()})
+	}
+	return segs
+}
+
+// The name of the variable that provides the value of a header
+func variable(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(name))
+}
+
+// The language a Postman body is presented in
+func language(ctype string) string {
+	switch t := strings.ToLower(ctype); {
+	case strings.Contains(t, "json"):
+		return "json"
+	case strings.Contains(t, "xml"):
+		return "xml"
+	case strings.Contains(t, "html"):
+		return "html"
+	case strings.Contains(t, "javascript"):
+		return "javascript"
+	case t != "":
+		return "text"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,362 @@
+package sample
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/instaunit/instaunit/hunit/testcase"
+)
+
+// The value that replaces secrets
+const Redacted = "<redacted>"
+
+// Headers whose values are always redacted
+var secretHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"X-Api-Key",
+}
+
+// Code sample language
+type Lang uint32
+
+const (
+	LangCurl Lang = iota
+	LangHTTPie
+	LangGo
+	LangPython
+	LangJavascript
+	LangInvalid
+)
+
+var langNames = []string{
+	"curl",
+	"httpie",
+	"go",
+	"python",
+	"javascript",
+	"<invalid>",
+}
+
+var langTitles = []string{
+	"cURL",
+	"HTTPie",
+	"Go",
+	"Python",
+	"JavaScript",
+	"",
+}
+
+var langHighlights = []string{
+	"sh",
+	"sh",
+	"go",
+	"python",
+	"javascript",
+	"",
+}
+
+// Parse a language
+func ParseLang(s string) (Lang, error) {
+	switch strings.ToLower(s) {
+	case "curl":
+		return LangCurl, nil
+	case "httpie":
+		return LangHTTPie, nil
+	case "go":
+		return LangGo, nil
+	case "python":
+		return LangPython, nil
+	case "javascript", "js":
+		return LangJavascript, nil
+	default:
+		return LangInvalid, fmt.Errorf("Unsupported sample language: %v", s)
+	}
+}
+
+// Stringer
+func (l Lang) String() string {
+	if l >= LangInvalid {
+		return "<invalid>"
+	} else {
+		return langNames[int(l)]
+	}
+}
+
+// The display title of a language
+func (l Lang) Title() string {
+	if l >= LangInvalid {
+		return ""
+	} else {
+		return langTitles[int(l)]
+	}
+}
+
+// The identifier used to highlight a language in Markdown
+func (l Lang) Highlight() string {
+	if l >= LangInvalid {
+		return ""
+	} else {
+		return langHighlights[int(l)]
+	}
+}
+
+// Sample configuration
+type Config struct {
+	Langs   []Lang   // the languages to produce samples in
+	Secrets []string // headers whose values are redacted, in addition to the usual suspects
+}
+
+// Produce a configuration which also redacts the headers used by a suite's
+// authentication schemes
+func (c Config) ForSuite(suite *testcase.Suite) Config {
+	var redact []string
+	for _, e := range suite.Authns {
+		if strings.EqualFold(e.Location, "header") && e.Name != "" {
+			redact = append(redact, e.Name)
+		}
+	}
+	if len(redact) > 0 {
+		c.Secrets = append(append([]string(nil), c.Secrets...), redact...)
+	}
+	return c
+}
+
+// Determine if the value of a header is redacted
+func (c Config) Redacts(name string) bool {
+	for _, e := range secretHeaders {
+		if strings.EqualFold(name, e) {
+			return true
+		}
+	}
+	for _, e := range c.Secrets {
+		if strings.EqualFold(name, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// A code sample
+type Sample struct {
+	Lang   Lang
+	Source string
+}
+
+// A request, with secrets redacted
+type request struct {
+	Method  string
+	URL     string
+	Headers [][2]string // ordered by name
+	Entity  string
+	IsJSON  bool
+}
+
+// Produce code samples for a request
+func Generate(req *http.Request, entity string, conf Config) []Sample {
+	if req == nil || len(conf.Langs) == 0 {
+		return nil
+	}
+	r := newRequest(req, entity, conf)
+	var samples []Sample
+	for _, e := range conf.Langs {
+		var src string
+		switch e {
+		case LangCurl:
+			src = curl(r)
+		case LangHTTPie:
+			src = httpie(r)
+		case LangGo:
+			src = golang(r)
+		case LangPython:
+			src = python(r)
+		case LangJavascript:
+			src = javascript(r)
+		default:
+			continue
+		}
+		samples = append(samples, Sample{Lang: e, Source: src})
+	}
+	return samples
+}
+
+// Produce a copy of a request with the values of secret headers and any
+// password in its URL redacted
+func (c Config) Redact(req *http.Request) *http.Request {
+	if req == nil {
+		return nil
+	}
+	r := req.Clone(req.Context())
+	if u := r.URL; u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), Redacted)
+		}
+	}
+	for k, v := range r.Header {
+		if c.Redacts(k) {
+			x := make([]string, len(v))
+			for i, e := range v {
+				x[i] = redact(k, e)
+			}
+			r.Header[k] = x
+		}
+	}
+	return r
+}
+
+func newRequest(req *http.Request, entity string, conf Config) request {
+	req = conf.Redact(req)
+	r := request{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Entity: strings.TrimRight(entity, "\r\n"),
+	}
+	keys := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		if !strings.EqualFold(k, "Content-Length") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		r.Headers = append(r.Headers, [2]string{k, strings.Join(req.Header[k], ",")})
+	}
+	if ctype := req.Header.Get("Content-Type"); strings.Contains(ctype, "json") && json.Valid([]byte(r.Entity)) {
+		r.IsJSON = true
+	}
+	return r
+}
+
+// Redact a header value. The scheme of authorization headers is retained.
+func redact(name, v string) string {
+	if strings.HasSuffix(strings.ToLower(name), "authorization") {
+		if s, _, ok := strings.Cut(v, " "); ok {
+			return s + " " + Redacted
+		}
+	}
+	return Redacted
+}
+
+func curl(r request) string {
+	b := &strings.Builder{}
+	b.WriteString("curl")
+	if r.Method != http.MethodGet {
+		b.WriteString(" -X " + r.Method)
+	}
+	b.WriteString(" " + shellQuote(r.URL))
+	for _, e := range r.Headers {
+		b.WriteString(" \\\n  -H " + shellQuote(e[0]+": "+e[1]))
+	}
+	if r.Entity != "" {
+		b.WriteString(" \\\n  --data-raw " + shellQuote(r.Entity))
+	}
+	return b.String()
+}
+
+func httpie(r request) string {
+	b := &strings.Builder{}
+	b.WriteString("http")
+	if r.Entity != "" {
+		b.WriteString(" --raw " + shellQuote(r.Entity))
+	}
+	b.WriteString(" " + r.Method + " " + shellQuote(r.URL))
+	for _, e := range r.Headers {
+		b.WriteString(" \\\n  " + shellQuote(e[0]+":"+e[1]))
+	}
+	return b.String()
+}
+
+func golang(r request) string {
+	b := &strings.Builder{}
+	body := "nil"
+	if r.Entity != "" {
+		b.WriteString("body := strings.NewReader(" + goQuote(r.Entity) + ")\n")
+		body = "body"
+	}
+	fmt.Fprintf(b, "req, err := http.NewRequest(%s, %s, %s)\n", strconv.Quote(r.Method), strconv.Quote(r.URL), body)
+	b.WriteString("if err != nil {\n\t// handle error\n}\n")
+	for _, e := range r.Headers {
+		fmt.Fprintf(b, "req.Header.Set(%s, %s)\n", strconv.Quote(e[0]), strconv.Quote(e[1]))
+	}
+	b.WriteString("rsp, err := http.DefaultClient.Do(req)\n")
+	b.WriteString("if err != nil {\n\t// handle error\n}\n")
+	b.WriteString("defer rsp.Body.Close()")
+	return b.String()
+}
+
+func python(r request) string {
+	b := &strings.Builder{}
+	b.WriteString("import requests\n\n")
+	switch m := strings.ToLower(r.Method); m {
+	case "get", "post", "put", "patch", "delete", "head", "options":
+		fmt.Fprintf(b, "response = requests.%s(\n", m)
+	default:
+		fmt.Fprintf(b, "response = requests.request(\n    %s,\n", strconv.Quote(r.Method))
+	}
+	fmt.Fprintf(b, "    %s,\n", strconv.Quote(r.URL))
+	if len(r.Headers) > 0 {
+		b.WriteString("    headers={\n")
+		for _, e := range r.Headers {
+			fmt.Fprintf(b, "        %s: %s,\n", strconv.Quote(e[0]), strconv.Quote(e[1]))
+		}
+		b.WriteString("    },\n")
+	}
+	if r.Entity != "" {
+		fmt.Fprintf(b, "    data=%s,\n", strconv.Quote(r.Entity))
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+func javascript(r request) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "const response = await fetch(%s, {\n", jsQuote(r.URL))
+	fmt.Fprintf(b, "  method: %s,\n", jsQuote(r.Method))
+	if len(r.Headers) > 0 {
+		b.WriteString("  headers: {\n")
+		for _, e := range r.Headers {
+			fmt.Fprintf(b, "    %s: %s,\n", jsQuote(e[0]), jsQuote(e[1]))
+		}
+		b.WriteString("  },\n")
+	}
+	if r.Entity != "" {
+		if r.IsJSON {
+			v := &bytes.Buffer{}
+			json.Indent(v, []byte(strings.TrimSpace(r.Entity)), "  ", "  ")
+			fmt.Fprintf(b, "  body: JSON.stringify(%s),\n", v.String())
+		} else {
+			fmt.Fprintf(b, "  body: %s,\n", jsQuote(r.Entity))
+		}
+	}
+	b.WriteString("});")
+	return b.String()
+}
+
+// Quote a string for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Quote a string for Go, preferring a raw string
+func goQuote(s string) string {
+	if !strings.Contains(s, "`") && !strings.Contains(s, "\r") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+// Quote a string for JavaScript
+func jsQuote(s string) string {
+	b := &bytes.Buffer{}
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
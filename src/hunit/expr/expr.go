@@ -79,6 +79,22 @@ func Interpolate(s string, v Variables) (string, error) {
 	return interpolate(s, "${", "}", RuntimeContext(v, os.Environ()))
 }
 
+// Escape a string so that it is reproduced literally when it is interpolated
+func Escape(s string) string {
+	out := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			out.WriteString(`\\`)
+		case s[i] == '$' && matchAhead(s[i:], "${"):
+			out.WriteString(`\$`)
+		default:
+			out.WriteByte(s[i])
+		}
+	}
+	return out.String()
+}
+
 // Interpolate every expression in one set of variables in terms of another
 func InterpolateAll(a, v Variables) (Variables, error) {
 	var err error
@@ -86,3 +86,20 @@ func testInterpolate(t *testing.T, s string, e string, r error, c interface{}) {
 		assert.Equal(t, e, v)
 	}
 }
+
+/**
+ * Test escape
+ */
+func TestEscape(t *testing.T) {
+	tests := []string{
+		`No expressions here.`,
+		`Before ${a}, after.`,
+		`Before \${a}, after.`,
+		`Before \\${a}, after.`,
+		`{"quoted": "a \"b\" c", "path": "C:\\dir"}`,
+		`Dollars $5 and ${`,
+	}
+	for _, e := range tests {
+		testInterpolate(t, Escape(e), e, nil, context)
+	}
+}
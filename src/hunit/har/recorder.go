@@ -0,0 +1,413 @@
+package har
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Records HTTP exchanges and websocket messages as an HTTP Archive
+type Recorder struct {
+	sync.Mutex
+	creator Creator
+	entries []*Entry
+}
+
+// Create a recorder; the name and version identify the creator of the archive
+func NewRecorder(name, version string) *Recorder {
+	return &Recorder{creator: Creator{Name: name, Version: version}}
+}
+
+// Add an entry
+func (r *Recorder) add(e *Entry, start time.Time) {
+	r.Lock()
+	defer r.Unlock()
+	e.start = start
+	r.entries = append(r.entries, e)
+}
+
+// Produce the archive; entries are ordered by the time they started
+func (r *Recorder) HAR() HAR {
+	r.Lock()
+	defer r.Unlock()
+	entries := make([]*Entry, len(r.entries))
+	copy(entries, r.entries)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].start.Before(entries[j].start)
+	})
+	return HAR{Log: Log{
+		Version: Version,
+		Creator: r.creator,
+		Entries: entries,
+	}}
+}
+
+// Write the archive to a file
+func (r *Recorder) Write(p string) error {
+	h := r.HAR()
+	r.Lock() // messages may still be recorded on websockets
+	data, err := json.MarshalIndent(h, "", "  ")
+	r.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, append(data, '\n'), 0644)
+}
+
+// Load an archive
+func Load(r io.Reader) (*HAR, error) {
+	h := &HAR{}
+	err := json.NewDecoder(r).Decode(h)
+	if err != nil {
+		return nil, fmt.Errorf("Could not load HAR: %w", err)
+	}
+	return h, nil
+}
+
+// Produce a transport that records every exchange it performs, including the
+// requests that follow redirects, before delegating to the provided transport.
+// If the provided transport is nil, the default transport is used.
+func (r *Recorder) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{r, next}
+}
+
+type transport struct {
+	rec  *Recorder
+	next http.RoundTripper
+}
+
+// Phases of an exchange, collected from a client trace
+type phases struct {
+	sync.Mutex
+	dnsStart, dnsDone     time.Time
+	connStart, connDone   time.Time
+	tlsStart, tlsDone     time.Time
+	gotConn, wrote, first time.Time
+	addr                  string
+}
+
+func (p *phases) mark(t *time.Time, first bool) {
+	p.Lock()
+	defer p.Unlock()
+	if !first || t.IsZero() {
+		*t = time.Now()
+	}
+}
+
+func (p *phases) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { p.mark(&p.dnsStart, true) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { p.mark(&p.dnsDone, false) },
+		ConnectStart:         func(_, _ string) { p.mark(&p.connStart, true) },
+		ConnectDone:          func(_, _ string, _ error) { p.mark(&p.connDone, false) },
+		TLSHandshakeStart:    func() { p.mark(&p.tlsStart, true) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { p.mark(&p.tlsDone, false) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { p.mark(&p.wrote, false) },
+		GotFirstResponseByte: func() { p.mark(&p.first, true) },
+		GotConn: func(i httptrace.GotConnInfo) {
+			p.mark(&p.gotConn, false)
+			if i.Conn != nil {
+				if h, _, err := net.SplitHostPort(i.Conn.RemoteAddr().String()); err == nil {
+					p.Lock()
+					p.addr = h
+					p.Unlock()
+				}
+			}
+		},
+	}
+}
+
+// Produce timings for phases which started at the provided time and ended at
+// the time the response was read
+func (p *phases) timings(start, end time.Time) Timings {
+	p.Lock()
+	defer p.Unlock()
+	var blocked time.Time
+	for _, e := range []time.Time{p.dnsStart, p.connStart, p.gotConn} {
+		if !e.IsZero() {
+			blocked = e
+			break
+		}
+	}
+	t := Timings{
+		Blocked: millis(start, blocked),
+		DNS:     millis(p.dnsStart, p.dnsDone),
+		Connect: millis(p.connStart, p.connDone),
+		SSL:     millis(p.tlsStart, p.tlsDone),
+		Send:    millis(p.gotConn, p.wrote),
+		Wait:    millis(p.wrote, p.first),
+		Receive: millis(p.first, end),
+	}
+	if t.SSL >= 0 {
+		t.Connect = millis(p.connStart, p.tlsDone) // connect includes the TLS handshake
+	}
+	return t
+}
+
+// Perform and record an exchange
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p := &phases{}
+	ctx := req.Context()
+	req = req.WithContext(httptrace.WithClientTrace(ctx, p.trace()))
+
+	var reqdata []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody != nil {
+			b, err := req.GetBody()
+			if err == nil {
+				reqdata, _ = io.ReadAll(b)
+				b.Close()
+			}
+		} else {
+			d, err := io.ReadAll(req.Body)
+			req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			reqdata = d
+			req.Body = io.NopCloser(bytes.NewReader(d))
+		}
+	}
+
+	start := time.Now()
+	entry := &Entry{
+		StartedDateTime: start.Format(time.RFC3339Nano),
+		Request:         newRequest(req, reqdata),
+	}
+
+	rsp, err := t.next.RoundTrip(req)
+	if err != nil {
+		end := time.Now()
+		entry.Time = millis(start, end)
+		entry.Timings = p.timings(start, end)
+		entry.Response = Response{Cookies: []Cookie{}, Headers: []NameValue{}, HeadersSize: -1, BodySize: -1, Error: err.Error()}
+		t.rec.add(entry, start)
+		return nil, err
+	}
+
+	var rspdata []byte
+	if rsp.Body != nil {
+		rspdata, err = io.ReadAll(rsp.Body)
+		rsp.Body.Close()
+		rsp.Body = io.NopCloser(bytes.NewReader(rspdata))
+	}
+	end := time.Now()
+
+	entry.Time = millis(start, end)
+	entry.Timings = p.timings(start, end)
+	entry.ServerIPAddress = p.addr
+	entry.Response = newResponse(rsp, rspdata)
+	if err != nil {
+		entry.Response.Error = err.Error()
+		t.rec.add(entry, start)
+		return nil, err
+	}
+	t.rec.add(entry, start)
+	return rsp, nil
+}
+
+// A recorded websocket connection
+type Socket struct {
+	rec   *Recorder
+	entry *Entry
+}
+
+// Record the handshake of a websocket connection which started at the
+// provided time. Messages exchanged over the connection are recorded by the
+// returned socket.
+func (r *Recorder) Websocket(u string, header http.Header, start time.Time, rsp *http.Response, err error) *Socket {
+	end := time.Now()
+	req := &http.Request{Method: http.MethodGet, Proto: "HTTP/1.1", Header: header}
+	req.URL, _ = url.Parse(u)
+	if req.URL == nil {
+		req.URL = &url.URL{}
+	}
+	req.Host = req.URL.Host
+
+	entry := &Entry{
+		StartedDateTime: start.Format(time.RFC3339Nano),
+		Time:            millis(start, end),
+		Request:         newRequest(req, nil),
+		Timings:         Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: millis(start, end)},
+		ResourceType:    "websocket",
+		Messages:        []*Message{},
+	}
+	if rsp != nil {
+		var data []byte
+		if rsp.Body != nil {
+			data, _ = io.ReadAll(rsp.Body)
+		}
+		entry.Response = newResponse(rsp, data)
+	} else {
+		entry.Response = Response{Cookies: []Cookie{}, Headers: []NameValue{}, HeadersSize: -1, BodySize: -1}
+	}
+	if err != nil {
+		entry.Response.Error = err.Error()
+	}
+
+	r.add(entry, start)
+	return &Socket{r, entry}
+}
+
+// Record a text message sent over the connection
+func (s *Socket) Send(data string) {
+	s.message("send", data)
+}
+
+// Record a text message received over the connection
+func (s *Socket) Receive(data string) {
+	s.message("receive", data)
+}
+
+func (s *Socket) message(t, data string) {
+	if s == nil {
+		return
+	}
+	now := time.Now()
+	s.rec.Lock()
+	defer s.rec.Unlock()
+	s.entry.Messages = append(s.entry.Messages, &Message{
+		Type:   t,
+		Time:   float64(now.UnixNano()) / float64(time.Second),
+		Opcode: 1, // text
+		Data:   data,
+	})
+}
+
+func newRequest(req *http.Request, data []byte) Request {
+	r := Request{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: req.Proto,
+		Cookies:     []Cookie{},
+		Headers:     []NameValue{},
+		QueryString: queryString(req.URL.RawQuery),
+		HeadersSize: -1,
+		BodySize:    len(data),
+	}
+	if r.HTTPVersion == "" {
+		r.HTTPVersion = "HTTP/1.1"
+	}
+	if h := coalesce(req.Host, req.URL.Host); h != "" {
+		r.Headers = append(r.Headers, NameValue{"Host", h})
+	}
+	r.Headers = append(r.Headers, headers(req.Header)...)
+	for _, e := range req.Cookies() {
+		r.Cookies = append(r.Cookies, Cookie{Name: e.Name, Value: e.Value})
+	}
+	if len(data) > 0 {
+		r.PostData = &PostData{
+			MimeType: req.Header.Get("Content-Type"),
+			Text:     string(data),
+		}
+	}
+	return r
+}
+
+func newResponse(rsp *http.Response, data []byte) Response {
+	r := Response{
+		Status:      rsp.StatusCode,
+		StatusText:  http.StatusText(rsp.StatusCode),
+		HTTPVersion: coalesce(rsp.Proto, "HTTP/1.1"),
+		Cookies:     []Cookie{},
+		Headers:     headers(rsp.Header),
+		Content: Content{
+			Size:     len(data),
+			MimeType: rsp.Header.Get("Content-Type"),
+		},
+		RedirectURL: rsp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    len(data),
+	}
+	for _, e := range rsp.Cookies() {
+		c := Cookie{
+			Name:     e.Name,
+			Value:    e.Value,
+			Path:     e.Path,
+			Domain:   e.Domain,
+			HTTPOnly: e.HttpOnly,
+			Secure:   e.Secure,
+		}
+		if !e.Expires.IsZero() {
+			c.Expires = e.Expires.Format(time.RFC3339)
+		}
+		r.Cookies = append(r.Cookies, c)
+	}
+	if len(data) > 0 {
+		if utf8.Valid(data) {
+			r.Content.Text = string(data)
+		} else {
+			r.Content.Text = base64.StdEncoding.EncodeToString(data)
+			r.Content.Encoding = "base64"
+		}
+	}
+	return r
+}
+
+// Produce headers ordered by name
+func headers(hdr http.Header) []NameValue {
+	keys := make([]string, 0, len(hdr))
+	for k := range hdr {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	nv := make([]NameValue, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range hdr[k] {
+			nv = append(nv, NameValue{k, v})
+		}
+	}
+	return nv
+}
+
+// Produce query parameters in the order they appear
+func queryString(q string) []NameValue {
+	nv := make([]NameValue, 0)
+	for _, e := range strings.Split(q, "&") {
+		if e == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(e, "=")
+		if d, err := url.QueryUnescape(k); err == nil {
+			k = d
+		}
+		if d, err := url.QueryUnescape(v); err == nil {
+			v = d
+		}
+		nv = append(nv, NameValue{k, v})
+	}
+	return nv
+}
+
+// Milliseconds between two times, or -1 if either is not known
+func millis(a, b time.Time) float64 {
+	if a.IsZero() || b.IsZero() {
+		return -1
+	}
+	return float64(b.Sub(a)) / float64(time.Millisecond)
+}
+
+func coalesce(s ...string) string {
+	for _, e := range s {
+		if e != "" {
+			return e
+		}
+	}
+	return ""
+}
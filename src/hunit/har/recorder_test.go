@@ -0,0 +1,68 @@
+package har
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusFound)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer srv.Close()
+
+	rec := NewRecorder("test", "1")
+	client := &http.Client{Transport: rec.Transport(nil)}
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/old?a=1&b=x%20y", strings.NewReader("Hello"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	rsp, err := client.Do(req)
+	if !assert.Nil(t, err) {
+		return
+	}
+	data, err := io.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, `{"ok":true}`, string(data)) // the body is still readable after it is recorded
+
+	h := rec.HAR()
+	assert.Equal(t, Version, h.Log.Version)
+	assert.Equal(t, Creator{"test", "1"}, h.Log.Creator)
+	if assert.Len(t, h.Log.Entries, 2) {
+		e := h.Log.Entries[0]
+		assert.Equal(t, http.MethodPost, e.Request.Method)
+		assert.Equal(t, []NameValue{{"a", "1"}, {"b", "x y"}}, e.Request.QueryString)
+		assert.Equal(t, &PostData{MimeType: "text/plain", Text: "Hello"}, e.Request.PostData)
+		assert.Equal(t, http.StatusFound, e.Response.Status)
+		assert.Equal(t, "/new", e.Response.RedirectURL)
+
+		e = h.Log.Entries[1]
+		assert.Equal(t, http.MethodGet, e.Request.Method)
+		assert.Equal(t, srv.URL+"/new", e.Request.URL)
+		assert.Equal(t, http.StatusOK, e.Response.Status)
+		assert.Equal(t, Content{Size: 11, MimeType: "application/json", Text: `{"ok":true}`}, e.Response.Content)
+		assert.True(t, e.Timings.Wait >= 0)
+	}
+
+	_, err = client.Get("http://127.0.0.1:1/")
+	assert.NotNil(t, err)
+	h = rec.HAR()
+	if assert.Len(t, h.Log.Entries, 3) {
+		assert.Equal(t, 0, h.Log.Entries[2].Response.Status)
+		assert.NotEqual(t, "", h.Log.Entries[2].Response.Error)
+	}
+}
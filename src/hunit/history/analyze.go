@@ -0,0 +1,98 @@
+package history
+
+import (
+	"sort"
+)
+
+// Statistics describing a test case over its history
+type Stats struct {
+	Case    string // the location of the case
+	Name    string // the most recent name of the case
+	Runs    int
+	Passed  int
+	Failed  int
+	Skipped int
+	// The number of times the outcome changed between consecutive runs of
+	// the same version of the suite. Since the inputs are identical, any
+	// change suggests the case is flaky.
+	Flips     int
+	Mean      float64 // mean duration, in seconds
+	Last      float64 // most recent duration, in seconds
+	Trend     float64 // the proportional change in mean duration from the earlier half of runs to the later half
+	Durations []float64
+}
+
+// The proportion of runs, excluding those that were skipped, which passed
+func (s *Stats) PassRate() float64 {
+	if n := s.Passed + s.Failed; n > 0 {
+		return float64(s.Passed) / float64(n)
+	}
+	return 0
+}
+
+// Determine if the case is flaky
+func (s *Stats) Flaky() bool {
+	return s.Flips > 0
+}
+
+// Summarize the history of every case. Records are expected to be in the
+// order they were produced. Results are ordered by case.
+func Analyze(recs []Record) []*Stats {
+	stats := make(map[string]*Stats)
+	prev := make(map[[2]string]string) // (case, checksum) -> last outcome
+	for _, e := range recs {
+		s, ok := stats[e.Case]
+		if !ok {
+			s = &Stats{Case: e.Case}
+			stats[e.Case] = s
+		}
+		s.Name = e.Name
+		s.Runs++
+		switch e.Outcome {
+		case OutcomePassed:
+			s.Passed++
+		case OutcomeFailed:
+			s.Failed++
+		default:
+			s.Skipped++
+			continue // skipped cases didn't run; they say nothing about flakiness or duration
+		}
+		if e.Checksum != "" {
+			k := [2]string{e.Case, e.Checksum}
+			if p, ok := prev[k]; ok && p != e.Outcome {
+				s.Flips++
+			}
+			prev[k] = e.Outcome
+		}
+		s.Durations = append(s.Durations, e.Duration)
+	}
+
+	res := make([]*Stats, 0, len(stats))
+	for _, s := range stats {
+		if l := len(s.Durations); l > 0 {
+			s.Mean = mean(s.Durations)
+			s.Last = s.Durations[l-1]
+			if l >= 4 {
+				if a := mean(s.Durations[:l/2]); a > 0 {
+					s.Trend = mean(s.Durations[l/2:])/a - 1
+				}
+			}
+		}
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Case < res[j].Case
+	})
+	return res
+}
+
+func mean(v []float64) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+	var t float64
+	for _, e := range v {
+		t += e
+	}
+	return t / float64(len(v))
+}
@@ -0,0 +1,147 @@
+package history
+
+import (
+	"sort"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit/report/emit/json"
+)
+
+// Produce records from a JSON report
+func FromReport(r *json.Report) []Record {
+	var recs []Record
+	for _, s := range r.Suites {
+		for _, c := range s.Cases {
+			file := c.File
+			if file == "" {
+				file = s.Path
+			}
+			recs = append(recs, Record{
+				Run:      r.Id,
+				Time:     r.Created,
+				Suite:    s.Path,
+				Case:     CaseKey(file, c.Line, c.Row),
+				Name:     c.Name,
+				Outcome:  c.Status,
+				Duration: c.Duration,
+			})
+		}
+	}
+	return merge(recs)
+}
+
+// Select the records produced by a run
+func Run(recs []Record, run string) []Record {
+	var sel []Record
+	for _, e := range recs {
+		if e.Run == run {
+			sel = append(sel, e)
+		}
+	}
+	return sel
+}
+
+// Produce the identifiers of every run, in the order they were recorded
+func Runs(recs []Record) []string {
+	var runs []string
+	seen := make(map[string]struct{})
+	for _, e := range recs {
+		if _, ok := seen[e.Run]; !ok {
+			seen[e.Run] = struct{}{}
+			runs = append(runs, e.Run)
+		}
+	}
+	return runs
+}
+
+// Index the records of a run by case. A case that was run more than once,
+// e.g., because it repeats, is described by a single record.
+func outcomes(recs []Record) map[string]Record {
+	res := make(map[string]Record)
+	for _, e := range merge(recs) {
+		res[e.Case] = e
+	}
+	return res
+}
+
+// A case that differs between runs
+type Change struct {
+	Case   string
+	Name   string
+	Before string  // the outcome in the base run; empty if the case was added
+	After  string  // the outcome in the compared run; empty if the case was removed
+	Base   float64 // duration in the base run, in seconds
+	Head   float64 // duration in the compared run, in seconds
+}
+
+// The proportional change in duration
+func (c Change) Delta() float64 {
+	if c.Base <= 0 {
+		return 0
+	}
+	return c.Head/c.Base - 1
+}
+
+// Thresholds above which a change in duration is significant. Both must be
+// exceeded.
+type Thresholds struct {
+	Ratio float64       // proportional change
+	Min   time.Duration // absolute change
+}
+
+// The differences between two runs
+type Comparison struct {
+	Failing []Change // cases that passed (or were skipped) and now fail
+	Passing []Change // cases that failed and now pass
+	Added   []Change
+	Removed []Change
+	Slower  []Change
+	Faster  []Change
+}
+
+// Compare the results of a run to those of a base run
+func Compare(base, head []Record, t Thresholds) *Comparison {
+	b, h := outcomes(base), outcomes(head)
+	cmp := &Comparison{}
+	for k, x := range h {
+		o, ok := b[k]
+		if !ok {
+			cmp.Added = append(cmp.Added, Change{Case: k, Name: x.Name, After: x.Outcome, Head: x.Duration})
+			continue
+		}
+		c := Change{Case: k, Name: x.Name, Before: o.Outcome, After: x.Outcome, Base: o.Duration, Head: x.Duration}
+		switch {
+		case x.Outcome == OutcomeFailed && o.Outcome != OutcomeFailed:
+			cmp.Failing = append(cmp.Failing, c)
+		case x.Outcome == OutcomePassed && o.Outcome == OutcomeFailed:
+			cmp.Passing = append(cmp.Passing, c)
+		}
+		// durations are only meaningful for cases that ran
+		if o.Outcome != OutcomeSkipped && x.Outcome != OutcomeSkipped {
+			d := c.Head - c.Base
+			if abs(d) >= t.Min.Seconds() && abs(c.Delta()) >= t.Ratio {
+				if d > 0 {
+					cmp.Slower = append(cmp.Slower, c)
+				} else {
+					cmp.Faster = append(cmp.Faster, c)
+				}
+			}
+		}
+	}
+	for k, o := range b {
+		if _, ok := h[k]; !ok {
+			cmp.Removed = append(cmp.Removed, Change{Case: k, Name: o.Name, Before: o.Outcome, Base: o.Duration})
+		}
+	}
+	for _, e := range [][]Change{cmp.Failing, cmp.Passing, cmp.Added, cmp.Removed, cmp.Slower, cmp.Faster} {
+		sort.Slice(e, func(i, j int) bool { return e[i].Case < e[j].Case })
+	}
+	return cmp
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
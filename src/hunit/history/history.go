@@ -0,0 +1,160 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit"
+)
+
+// Test outcomes
+const (
+	OutcomePassed  = "passed"
+	OutcomeFailed  = "failed"
+	OutcomeSkipped = "skipped"
+)
+
+// The outcome of a test case in a run. A history file contains one record
+// per line, in the order the tests were run.
+type Record struct {
+	Run      string    `json:"run"`
+	Time     time.Time `json:"time"`
+	Suite    string    `json:"suite"`
+	Checksum string    `json:"checksum,omitempty"` // the checksum of the suite; results with the same checksum ran the same tests
+	Case     string    `json:"case"`               // the location of the case in the suite, as '<file>:<line>', followed by '#<row>' for a row of a matrix
+	Name     string    `json:"name"`
+	Outcome  string    `json:"outcome"`
+	Duration float64   `json:"duration"` // in seconds
+}
+
+// Produce records describing the results of a suite. A case which was run more
+// than once, e.g., because it repeats, is described by a single record: it
+// has failed if any of its runs failed and its duration is their mean.
+func Records(run, suite, checksum string, results []*hunit.Result) []Record {
+	now := time.Now()
+	recs := make([]Record, len(results))
+	for i, e := range results {
+		var outcome string
+		switch {
+		case e.Skipped:
+			outcome = OutcomeSkipped
+		case e.Success:
+			outcome = OutcomePassed
+		default:
+			outcome = OutcomeFailed
+		}
+		file := e.Case.Source.File
+		if file == "" {
+			file = suite
+		}
+		recs[i] = Record{
+			Run:      run,
+			Time:     now,
+			Suite:    suite,
+			Checksum: checksum,
+			Case:     CaseKey(file, e.Case.Source.Line, e.Row),
+			Name:     strings.TrimSpace(e.Name),
+			Outcome:  outcome,
+			Duration: e.Runtime.Seconds(),
+		}
+	}
+	return merge(recs)
+}
+
+// Produce the key which identifies a case: its location and, for a case
+// which is part of a matrix, the row it was run for
+func CaseKey(file string, line, row int) string {
+	if row > 0 {
+		return fmt.Sprintf("%s:%d#%d", file, line, row)
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+// Merge the records of a run which describe the same case into one, in the
+// order each case was first run
+func merge(recs []Record) []Record {
+	var res []Record
+	idx := make(map[string]int)
+	runs := make(map[string]int) // the number of runs of a case that were not skipped
+	for _, e := range recs {
+		i, ok := idx[e.Case]
+		if !ok {
+			idx[e.Case] = len(res)
+			if e.Outcome != OutcomeSkipped {
+				runs[e.Case] = 1
+			}
+			res = append(res, e)
+			continue
+		}
+		o := &res[i]
+		o.Name = e.Name
+		if e.Outcome == OutcomeSkipped {
+			continue
+		}
+		if e.Outcome == OutcomeFailed || o.Outcome == OutcomeSkipped {
+			o.Outcome = e.Outcome
+		}
+		n := runs[e.Case]
+		o.Duration = (o.Duration*float64(n) + e.Duration) / float64(n+1)
+		runs[e.Case] = n + 1
+	}
+	return res
+}
+
+// Append records to a history file, creating it if necessary
+func Append(p string, recs []Record) error {
+	err := os.MkdirAll(path.Dir(p), 0o755)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, e := range recs {
+		err = enc.Encode(e)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// Read every record from a history file
+func Read(p string) ([]Record, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Decode(f)
+}
+
+// Decode records
+func Decode(r io.Reader) ([]Record, error) {
+	var recs []Record
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; s.Scan(); n++ {
+		l := strings.TrimSpace(s.Text())
+		if l == "" {
+			continue
+		}
+		var rec Record
+		err := json.Unmarshal([]byte(l), &rec)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid history record on line %d: %w", n, err)
+		}
+		recs = append(recs, rec)
+	}
+	return recs, s.Err()
+}
@@ -0,0 +1,173 @@
+package history
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit"
+	"github.com/instaunit/instaunit/hunit/report/emit"
+	"github.com/instaunit/instaunit/hunit/report/emit/emittest"
+	"github.com/instaunit/instaunit/hunit/report/emit/json"
+	"github.com/instaunit/instaunit/hunit/testcase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyze(t *testing.T) {
+	rec := func(run, sum, c, outcome string, d float64) Record {
+		return Record{Run: run, Checksum: sum, Case: c, Name: c, Outcome: outcome, Duration: d}
+	}
+	recs := []Record{
+		rec("1", "a", "s.yml:1", OutcomePassed, 1),
+		rec("1", "a", "s.yml:5", OutcomePassed, 1),
+		rec("2", "a", "s.yml:1", OutcomePassed, 1),
+		rec("2", "a", "s.yml:5", OutcomeFailed, 1),
+		rec("3", "b", "s.yml:1", OutcomeFailed, 3), // the suite changed; this is not flaky
+		rec("3", "b", "s.yml:5", OutcomeSkipped, 0),
+		rec("4", "a", "s.yml:1", OutcomeFailed, 3),
+		rec("4", "a", "s.yml:5", OutcomePassed, 1),
+		// the rows of a matrix are distinct cases, so a row which consistently
+		// fails is not flaky because another row consistently passes
+		rec("1", "a", "s.yml:9#1", OutcomePassed, 1),
+		rec("1", "a", "s.yml:9#2", OutcomeFailed, 1),
+		rec("2", "a", "s.yml:9#1", OutcomePassed, 1),
+		rec("2", "a", "s.yml:9#2", OutcomeFailed, 1),
+	}
+
+	b := &bytes.Buffer{}
+	for _, e := range recs {
+		b.WriteString(`{"run":"` + e.Run + `","checksum":"` + e.Checksum + `","case":"` + e.Case + `","outcome":"` + e.Outcome + `","duration":1}` + "\n")
+	}
+	dec, err := Decode(b)
+	if assert.Nil(t, err) {
+		assert.Len(t, dec, len(recs))
+	}
+
+	stats := Analyze(recs)
+	if assert.Len(t, stats, 4) {
+		s := stats[0]
+		assert.Equal(t, "s.yml:1", s.Case)
+		assert.Equal(t, 4, s.Runs)
+		assert.Equal(t, 0.5, s.PassRate())
+		assert.Equal(t, 1, s.Flips) // passed twice, then failed, on the same version of the suite
+		assert.Equal(t, 2.0, s.Mean)
+		assert.Equal(t, 3.0, s.Last)
+		assert.Equal(t, 2.0, s.Trend)
+
+		s = stats[1]
+		assert.Equal(t, "s.yml:5", s.Case)
+		assert.Equal(t, 1, s.Skipped)
+		assert.Equal(t, 2, s.Flips)
+		assert.True(t, s.Flaky())
+		assert.InDelta(t, 2.0/3.0, s.PassRate(), 0.001)
+
+		s = stats[2]
+		assert.Equal(t, "s.yml:9#1", s.Case)
+		assert.Equal(t, 2, s.Runs)
+		assert.Equal(t, 1.0, s.PassRate())
+		assert.False(t, s.Flaky())
+
+		s = stats[3]
+		assert.Equal(t, "s.yml:9#2", s.Case)
+		assert.Equal(t, 2, s.Runs)
+		assert.Equal(t, 0.0, s.PassRate())
+		assert.False(t, s.Flaky())
+	}
+}
+
+func TestRecords(t *testing.T) {
+	results := []*hunit.Result{
+		{Name: "A", Success: true, Case: emittest.At("s.yml", 1), Runtime: time.Second},
+		{Name: "B", Success: true, Case: emittest.At("s.yml", 5), Row: 1, Runtime: time.Second},
+		{Name: "B", Success: false, Case: emittest.At("s.yml", 5), Row: 2, Runtime: time.Second},
+		{Name: "C", Success: true, Case: emittest.At("s.yml", 9), Runtime: time.Second}, // repeated
+		{Name: "C", Success: false, Case: emittest.At("s.yml", 9), Runtime: time.Second * 3},
+		{Name: "C", Skipped: true, Case: emittest.At("s.yml", 9)},
+		{Name: "D", Skipped: true, Case: emittest.At("s.yml", 13)},
+	}
+
+	recs := Records("1", "s.yml", "a", results)
+	if assert.Len(t, recs, 5) {
+		assert.Equal(t, Record{Run: "1", Time: recs[0].Time, Suite: "s.yml", Checksum: "a", Case: "s.yml:1", Name: "A", Outcome: OutcomePassed, Duration: 1}, recs[0])
+		assert.Equal(t, "s.yml:5#1", recs[1].Case)
+		assert.Equal(t, OutcomePassed, recs[1].Outcome)
+		assert.Equal(t, "s.yml:5#2", recs[2].Case)
+		assert.Equal(t, OutcomeFailed, recs[2].Outcome)
+		assert.Equal(t, "s.yml:9", recs[3].Case)
+		assert.Equal(t, OutcomeFailed, recs[3].Outcome) // any failure of a repeated case is a failure
+		assert.Equal(t, 2.0, recs[3].Duration)
+		assert.Equal(t, "s.yml:13", recs[4].Case)
+		assert.Equal(t, OutcomeSkipped, recs[4].Outcome)
+	}
+}
+
+func TestFromReport(t *testing.T) {
+	results := []*hunit.Result{
+		{Name: "A", Success: true, Case: emittest.At("", 1), Runtime: time.Second},
+		{Name: "B", Success: true, Case: emittest.At("", 5), Row: 1, Runtime: time.Second},
+		{Name: "B", Success: false, Case: emittest.At("", 5), Row: 2, Runtime: time.Second},
+		{Name: "C", Success: true, Case: emittest.At("", 9), Runtime: time.Second},
+		{Name: "C", Success: false, Case: emittest.At("", 9), Runtime: time.Second * 3},
+		{Name: "D", Skipped: true, Case: emittest.At("", 13)},
+	}
+
+	// a report is emitted and loaded, and describes the same records as the
+	// results it was produced from; cases without a file are attributed to
+	// their suite
+	b := &emittest.Buffer{}
+	g := json.New(b, "1")
+	assert.Nil(t, g.Init())
+	assert.Nil(t, g.Suite(testcase.Config{}, &testcase.Suite{}, &emit.Results{Path: "s.yml", Results: results}))
+	assert.Nil(t, g.Finalize())
+	rep, err := json.Load(&b.Buffer)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	recs := FromReport(rep)
+	expect := Records("1", "s.yml", "", results)
+	if assert.Len(t, recs, len(expect)) {
+		for i, e := range expect {
+			e.Time = rep.Created
+			assert.Equal(t, e, recs[i])
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	rec := func(c, outcome string, d float64) Record {
+		return Record{Case: c, Name: c, Outcome: outcome, Duration: d}
+	}
+	base := []Record{
+		rec("s.yml:1", OutcomePassed, 0.1),
+		rec("s.yml:5", OutcomeFailed, 0.1),
+		rec("s.yml:9", OutcomePassed, 0.1),
+		rec("s.yml:13", OutcomePassed, 0.1),
+		rec("s.yml:13", OutcomePassed, 0.1),
+	}
+	head := []Record{
+		rec("s.yml:1", OutcomeFailed, 0.1),
+		rec("s.yml:5", OutcomePassed, 0.1),
+		rec("s.yml:13", OutcomePassed, 0.1),
+		rec("s.yml:13", OutcomeFailed, 0.5), // any failure of a repeated case is a failure
+		rec("s.yml:17", OutcomePassed, 0.1),
+	}
+
+	cmp := Compare(base, head, Thresholds{Ratio: 0.5, Min: time.Millisecond * 10})
+	keys := func(c []Change) []string {
+		var k []string
+		for _, e := range c {
+			k = append(k, e.Case)
+		}
+		return k
+	}
+	assert.Equal(t, []string{"s.yml:1", "s.yml:13"}, keys(cmp.Failing))
+	assert.Equal(t, []string{"s.yml:5"}, keys(cmp.Passing))
+	assert.Equal(t, []string{"s.yml:17"}, keys(cmp.Added))
+	assert.Equal(t, []string{"s.yml:9"}, keys(cmp.Removed))
+	if assert.Equal(t, []string{"s.yml:13"}, keys(cmp.Slower)) {
+		assert.InDelta(t, 2.0, cmp.Slower[0].Delta(), 0.001) // 0.1s to a mean of 0.3s
+	}
+	assert.Len(t, cmp.Faster, 0)
+}
@@ -15,11 +15,13 @@ import (
 
 	"github.com/instaunit/instaunit/hunit/entity"
 	"github.com/instaunit/instaunit/hunit/expr"
+	"github.com/instaunit/instaunit/hunit/har"
 	"github.com/instaunit/instaunit/hunit/runtime"
 	"github.com/instaunit/instaunit/hunit/testcase"
 	"github.com/instaunit/instaunit/hunit/text"
 
 	"github.com/bww/go-util/v1/debug"
+	"github.com/bww/go-util/v1/maps"
 	textutil "github.com/bww/go-util/v1/text"
 	"github.com/gorilla/websocket"
 )
@@ -27,11 +29,13 @@ import (
 // Run a test suite
 func RunSuite(suite *testcase.Suite, context runtime.Context) ([]*Result, error) {
 	var futures []FutureResult
+	var rows []int // the matrix row of each future
 	results := make([]*Result, 0)
 	globals := dupVars(suite.Globals)
 
 	// this is weird, but yes, we're evaulating globals in terms of themselves
-	globals, err := expr.InterpolateAll(globals, globals)
+	// (and the variables provided by the context)
+	globals, err := expr.InterpolateAll(globals, maps.Merged(context.Variables, globals))
 	if err != nil {
 		return nil, fmt.Errorf("Could not evaluate global: %w", err)
 	}
@@ -39,8 +43,9 @@ func RunSuite(suite *testcase.Suite, context runtime.Context) ([]*Result, error)
 	precond := true
 	for _, f := range suite.Frames() {
 		e := f.Case // just unpack the case for now
+		row := f.Row
 		if !precond {
-			results = append(results, &Result{Name: fmt.Sprintf("%v %v (dependency failed)\n", e.Request.Method, e.Request.URL), Skipped: true, Case: e})
+			results = append(results, &Result{Name: fmt.Sprintf("%v %v (dependency failed)\n", e.Request.Method, e.Request.URL), Skipped: true, Case: e, Row: row})
 			continue
 		}
 
@@ -88,7 +93,7 @@ func RunSuite(suite *testcase.Suite, context runtime.Context) ([]*Result, error)
 					return // we're not locked here, so we can return early
 				}
 
-				r, f, v, err := RunTest(suite, e, context.WithVars(g, fvars))
+				r, f, v, err := RunTest(suite, e, context.WithVars(context.Variables, g, fvars))
 				lock.Lock()
 				if v != nil && e.Id != "" {
 					globals[e.Id] = v
@@ -103,10 +108,12 @@ func RunSuite(suite *testcase.Suite, context runtime.Context) ([]*Result, error)
 						precond = precond && r.Success
 					}
 					if r != nil {
+						r.Row = row
 						results = append(results, r)
 					}
 					if f != nil {
 						futures = append(futures, f)
+						rows = append(rows, row)
 					}
 				}
 				lock.Unlock()
@@ -125,11 +132,12 @@ func RunSuite(suite *testcase.Suite, context runtime.Context) ([]*Result, error)
 		if p := context.Config.Net.StreamIOGracePeriod; p > 0 {
 			d = d.Add(p)
 		}
-		for _, e := range futures {
+		for i, e := range futures {
 			r, err := e.Finish(d)
 			if err != nil {
 				return nil, err
 			}
+			r.Row = rows[i]
 			results = append(results, r)
 		}
 	}
@@ -268,12 +276,18 @@ func RunTest(suite *testcase.Suite, c testcase.Case, context runtime.Context) (*
 		if err != nil {
 			return result.Error(fmt.Errorf("Could not upgrade URL scheme: %w", err)), nil, vars, nil
 		}
-		conn, _, err := dialer.Dial(url, header)
+		dialed := time.Now()
+		conn, wsrsp, err := dialer.Dial(url, header)
+		var capture *har.Socket
+		if context.HAR != nil {
+			capture = context.HAR.Websocket(url, header, dialed, wsrsp, err)
+		}
 		if err != nil {
 			return result.Error(fmt.Errorf("Could not dial websocket: %w", err)), nil, vars, nil
 		}
 
 		monitor := NewStreamMonitor(url, context, conn, messages)
+		monitor.capture = capture
 		err = monitor.Run(result)
 		if err != nil {
 			return nil, nil, nil, err
@@ -323,6 +337,7 @@ func RunTest(suite *testcase.Suite, c testcase.Case, context runtime.Context) (*
 	}
 
 	req.Header = header
+	result.Method, result.URL = req.Method, req.URL.String()
 
 	if c.Request.Cookies != nil {
 		for k, v := range c.Request.Cookies {
@@ -353,6 +368,7 @@ func RunTest(suite *testcase.Suite, c testcase.Case, context runtime.Context) (*
 	if err != nil {
 		return result.Error(fmt.Errorf("Could not read response body: %w", err)), nil, vars, nil
 	}
+	result.Status = rsp.StatusCode
 
 	// check the response status
 	if c.Response.Status == 0 { // if the status is not explicitly defined we assume 200/OK is expected
@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/instaunit/instaunit/hunit/expr"
+	"github.com/instaunit/instaunit/hunit/har"
+)
+
+// Produce a suite from the exchanges recorded in an HTTP Archive. Responses
+// which were captured are expected semantically when they are JSON. Requests
+// which were redirected produce a single case expecting the response at the
+// end of the redirects, since redirects are followed when tests are run.
+// Websocket connections produce cases which exchange the recorded messages.
+func FromHAR(data []byte, conf Config) (*Suite, error) {
+	h, err := har.Load(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	suite := &Suite{}
+	entries := h.Log.Entries
+	for i := 0; i < len(entries); i++ {
+		e := entries[i]
+		if e == nil || e.Response.Status == 0 {
+			continue // the request failed or was never completed
+		}
+
+		c := Case{
+			Title: title(e.Request.Method, e.Request.URL),
+			Request: Request{
+				Method: e.Request.Method,
+				URL:    relativeURL(e.Request.URL, conf.BaseURL),
+			},
+		}
+		for _, h := range e.Request.Headers {
+			if importHeader(h.Name) {
+				if c.Request.Headers == nil {
+					c.Request.Headers = make(map[string]string)
+				}
+				c.Request.Headers[h.Name] = h.Value
+			}
+		}
+		if p := e.Request.PostData; p != nil && p.Text != "" {
+			c.Request.Entity = expr.Escape(p.Text)
+		}
+
+		if e.ResourceType == "websocket" || len(e.Messages) > 0 {
+			c.Stream = &Stream{}
+			for _, m := range e.Messages {
+				d := m.Data
+				switch m.Type {
+				case "send":
+					c.Stream.Messages = append(c.Stream.Messages, Message{Output: &d})
+				case "receive":
+					c.Stream.Messages = append(c.Stream.Messages, Message{Input: &d})
+				}
+			}
+			if len(c.Stream.Messages) == 0 {
+				continue // nothing to test
+			}
+			suite.Cases = append(suite.Cases, c)
+			continue
+		}
+
+		// follow redirects to the final response
+		for e.Response.RedirectURL != "" && i+1 < len(entries) {
+			n := entries[i+1]
+			if n == nil || n.Request.URL != resolveURL(e.Request.URL, e.Response.RedirectURL) {
+				break
+			}
+			e, i = n, i+1
+		}
+		if e.Response.Status == 0 {
+			continue
+		}
+
+		c.Response.Status = e.Response.Status
+		if text, ok := decodeContent(e.Response.Content.Text, e.Response.Content.Encoding); ok {
+			c.Response.Entity, c.Response.Comparison = expectEntity(e.Response.Content.MimeType, text)
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	if len(suite.Cases) == 0 {
+		return nil, fmt.Errorf("No exchanges could be imported")
+	}
+	return suite, nil
+}
+
+// Resolve a redirect location relative to the URL that was requested
+func resolveURL(base, ref string) string {
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	r, err := b.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return r.String()
+}
+
+// Decode base64 content, if necessary
+func decodeContent(text, encoding string) (string, bool) {
+	if !strings.EqualFold(encoding, "base64") {
+		return text, true
+	}
+	d, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return "", false
+	}
+	return string(d), true
+}
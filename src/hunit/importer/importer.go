@@ -0,0 +1,234 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/instaunit/instaunit/hunit/expr"
+	"github.com/instaunit/instaunit/hunit/httputil/mimetype"
+	"github.com/instaunit/instaunit/hunit/testcase"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+var ErrUnknownFormat = errors.New("Could not determine the format of the source")
+
+// Source format
+type Format uint32
+
+const (
+	FormatOpenAPI Format = iota
+	FormatHAR
+	FormatPostman
+	FormatInvalid
+)
+
+var formatNames = []string{
+	"openapi",
+	"har",
+	"postman",
+	"<invalid>",
+}
+
+// Parse a format
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "openapi":
+		return FormatOpenAPI, nil
+	case "har":
+		return FormatHAR, nil
+	case "postman":
+		return FormatPostman, nil
+	default:
+		return FormatInvalid, fmt.Errorf("Unsupported format: %v", s)
+	}
+}
+
+// Stringer
+func (f Format) String() string {
+	if f >= FormatInvalid {
+		return "<invalid>"
+	} else {
+		return formatNames[int(f)]
+	}
+}
+
+// Determine the format of a source from its content
+func Detect(data []byte) (Format, error) {
+	var v map[string]interface{}
+	err := yaml.Unmarshal(data, &v)
+	if err != nil {
+		return FormatInvalid, fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+	}
+	if _, ok := v["openapi"]; ok {
+		return FormatOpenAPI, nil
+	}
+	if _, ok := v["log"]; ok {
+		return FormatHAR, nil
+	}
+	if info, ok := v["info"].(map[string]interface{}); ok {
+		if s, ok := info["schema"].(string); ok && strings.Contains(s, "getpostman.com") {
+			return FormatPostman, nil
+		}
+	}
+	return FormatInvalid, ErrUnknownFormat
+}
+
+// Import options
+type Config struct {
+	BaseURL string // URLs under the base URL are made relative to it
+}
+
+// Produce a suite from a source in the provided format
+func Import(f Format, data []byte, conf Config) (*Suite, error) {
+	switch f {
+	case FormatOpenAPI:
+		return FromOpenAPI(data, conf)
+	case FormatHAR:
+		return FromHAR(data, conf)
+	case FormatPostman:
+		return FromPostman(data, conf)
+	default:
+		return nil, fmt.Errorf("Unsupported format: %v", f)
+	}
+}
+
+// The subset of the suite format which is produced by an import
+type Suite struct {
+	Title    string `yaml:"title,omitempty"`
+	Comments string `yaml:"doc,omitempty"`
+	TOC      *TOC   `yaml:"toc,omitempty"`
+	Cases    []Case `yaml:"tests"`
+}
+
+type TOC struct {
+	Sections []testcase.Section `yaml:"sections"`
+}
+
+type Route struct {
+	Path string `yaml:"path"`
+}
+
+type Request struct {
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Entity  string            `yaml:"entity,omitempty"`
+}
+
+type Response struct {
+	Status     int                 `yaml:"status,omitempty"`
+	Entity     string              `yaml:"entity,omitempty"`
+	Comparison testcase.Comparison `yaml:"compare,omitempty"`
+}
+
+type Message struct {
+	Output *string `yaml:"send,omitempty"`
+	Input  *string `yaml:"receive,omitempty"`
+}
+
+type Stream struct {
+	Messages []Message `yaml:"messages"`
+}
+
+type Case struct {
+	Title    string   `yaml:"title,omitempty"`
+	Section  string   `yaml:"section,omitempty"`
+	Comments string   `yaml:"doc,omitempty"`
+	Route    *Route   `yaml:"route,omitempty"`
+	Request  Request  `yaml:"request"`
+	Response Response `yaml:"response"`
+	Stream   *Stream  `yaml:"websocket,omitempty"`
+}
+
+// Write a suite
+func Write(dst io.Writer, suite *Suite) error {
+	enc := yaml.NewEncoder(dst)
+	enc.SetIndent(2)
+	err := enc.Encode(suite)
+	if err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// Request headers which are managed by the client, or which are specific to
+// the client that made a recording, and are not imported
+var ignoredHeaders = map[string]struct{}{
+	"Accept-Encoding":     {},
+	"Connection":          {},
+	"Content-Length":      {},
+	"Cookie":              {},
+	"Host":                {},
+	"Keep-Alive":          {},
+	"Origin":              {},
+	"Proxy-Authorization": {},
+	"Proxy-Connection":    {},
+	"Referer":             {},
+	"Te":                  {},
+	"Trailer":             {},
+	"Transfer-Encoding":   {},
+	"Upgrade":             {},
+	"User-Agent":          {},
+}
+
+// Determine if a request header is imported
+func importHeader(name string) bool {
+	if strings.HasPrefix(name, ":") || strings.HasPrefix(strings.ToLower(name), "sec-") {
+		return false // pseudo-headers and browser metadata
+	}
+	_, ok := ignoredHeaders[canonicalHeader(name)]
+	return !ok
+}
+
+func canonicalHeader(name string) string {
+	parts := strings.Split(strings.ToLower(name), "-")
+	for i, e := range parts {
+		if e != "" {
+			parts[i] = strings.ToUpper(e[:1]) + e[1:]
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// Make a URL relative to the base URL, if it is under it
+func relativeURL(u, base string) string {
+	base = strings.TrimSuffix(base, "/")
+	if base == "" {
+		return u
+	}
+	if x := strings.TrimPrefix(u, base); x != u && (x == "" || x[0] == '/' || x[0] == '?') {
+		if x == "" || x[0] == '?' {
+			x = "/" + x
+		}
+		return x
+	}
+	return u
+}
+
+// Produce an expected response entity. JSON entities are compared
+// semantically; others are not compared, since they are often dynamic.
+func expectEntity(ctype, data string) (string, testcase.Comparison) {
+	if data == "" || !isJSON(ctype) || !json.Valid([]byte(data)) {
+		return "", testcase.CompareLiteral
+	}
+	return expr.Escape(data), testcase.CompareSemantic
+}
+
+// Determine if a content type describes JSON
+func isJSON(ctype string) bool {
+	t := strings.ToLower(strings.TrimSpace(strings.Split(ctype, ";")[0]))
+	return t == mimetype.JSON || strings.HasSuffix(t, "+json")
+}
+
+// Produce a title for a request
+func title(method, u string) string {
+	if p, err := url.Parse(u); err == nil && p.Path != "" {
+		u = p.Path
+	}
+	return method + " " + u
+}
@@ -0,0 +1,181 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/instaunit/instaunit/hunit/testcase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const openapiSource = `
+openapi: 3.0.3
+info:
+  title: Users
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /users/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: integer, example: 7}}
+    get:
+      summary: Fetch a user
+      parameters:
+        - {name: verbose, in: query, required: true, schema: {type: boolean}}
+        - {name: page, in: query, schema: {type: integer}}
+      responses:
+        "404": {description: Not found}
+        "200": {description: Ok}
+    put:
+      operationId: updateUser
+      requestBody:
+        content:
+          application/json:
+            example: {"name": "${name}"}
+      responses:
+        "204": {description: Updated}
+`
+
+const harSource = `{"log": {"version": "1.2", "creator": {"name": "test", "version": "1"}, "entries": [
+  {"request": {"method": "GET", "url": "http://localhost:8080/old", "headers": [{"name": "Host", "value": "localhost:8080"}, {"name": "X-Tenant", "value": "t1"}, {"name": ":authority", "value": "localhost"}]},
+   "response": {"status": 302, "redirectURL": "/new", "content": {"size": 0, "mimeType": ""}}},
+  {"request": {"method": "GET", "url": "http://localhost:8080/new", "headers": []},
+   "response": {"status": 200, "content": {"size": 18, "mimeType": "application/json; charset=utf-8", "text": "{\"price\": \"${5}\"}"}}},
+  {"request": {"method": "POST", "url": "http://other.example.com/x", "headers": [], "postData": {"mimeType": "text/plain", "text": "Hello"}},
+   "response": {"status": 201, "content": {"size": 5, "mimeType": "text/plain", "text": "Hello"}}},
+  {"request": {"method": "GET", "url": "http://localhost:8080/fail", "headers": []},
+   "response": {"status": 0, "content": {"size": 0, "mimeType": ""}, "_error": "refused"}},
+  {"_resourceType": "websocket", "request": {"method": "GET", "url": "ws://localhost:8080/feed", "headers": []},
+   "response": {"status": 101, "content": {"size": 0, "mimeType": ""}},
+   "_webSocketMessages": [{"type": "send", "time": 1, "opcode": 1, "data": "ping"}, {"type": "receive", "time": 2, "opcode": 1, "data": "pong"}]}
+]}}`
+
+const postmanSource = `{
+  "info": {"name": "Users", "description": {"content": "About users"}, "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+  "item": [
+    {"name": "Reading", "item": [
+      {"name": "Get a user", "request": {
+        "method": "GET",
+        "header": [{"key": "Authorization", "value": "Bearer {{token}}"}, {"key": "X-Debug", "value": "1", "disabled": true}],
+        "url": {"raw": "{{baseUrl}}/users/1?verbose=true", "host": ["{{baseUrl}}"], "path": ["users", "1"]}},
+       "response": [{"name": "Ok", "code": 200, "header": [{"key": "Content-Type", "value": "application/json"}], "body": "{\"id\": 1}"}]}
+    ]},
+    {"name": "Ping", "request": "https://example.com/ping"},
+    {"name": "Create a user", "request": {
+      "method": "post",
+      "header": "X-Tenant: t1\nUser-Agent: PostmanRuntime",
+      "body": {"mode": "raw", "raw": "{\"name\": \"{{name}}\"}", "options": {"raw": {"language": "json"}}},
+      "url": "{{baseUrl}}/users"}}
+  ],
+  "variable": [{"key": "baseUrl", "value": "http://localhost:8080"}, {"key": "token", "value": ""}, {"key": "name", "value": "Bob"}]
+}`
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		Source string
+		Expect Format
+	}{
+		{openapiSource, FormatOpenAPI},
+		{harSource, FormatHAR},
+		{postmanSource, FormatPostman},
+		{`{"info": {"name": "Not postman"}}`, FormatInvalid},
+	}
+	for _, e := range tests {
+		f, err := Detect([]byte(e.Source))
+		assert.Equal(t, e.Expect, f)
+		if e.Expect == FormatInvalid {
+			assert.ErrorIs(t, err, ErrUnknownFormat)
+		} else {
+			assert.Nil(t, err)
+		}
+	}
+}
+
+func TestFromOpenAPI(t *testing.T) {
+	suite, err := FromOpenAPI([]byte(openapiSource), Config{})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, &Suite{
+		Title: "Users",
+		Cases: []Case{
+			{
+				Title:    "Fetch a user",
+				Route:    &Route{Path: "/users/{id}"},
+				Request:  Request{Method: "GET", URL: "/v1/users/7?verbose=true"},
+				Response: Response{Status: 200},
+			},
+			{
+				Title: "updateUser",
+				Route: &Route{Path: "/users/{id}"},
+				Request: Request{
+					Method:  "PUT",
+					URL:     "/v1/users/7",
+					Headers: map[string]string{"Content-Type": "application/json"},
+					Entity:  "{\n  \"name\": \"\\${name}\"\n}",
+				},
+				Response: Response{Status: 204},
+			},
+		},
+	}, suite)
+}
+
+func TestFromHAR(t *testing.T) {
+	suite, err := FromHAR([]byte(harSource), Config{BaseURL: "http://localhost:8080/"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	ping, pong := "ping", "pong"
+	assert.Equal(t, &Suite{
+		Cases: []Case{
+			{
+				Title:    "GET /old",
+				Request:  Request{Method: "GET", URL: "/old", Headers: map[string]string{"X-Tenant": "t1"}},
+				Response: Response{Status: 200, Entity: `{"price": "\${5}"}`, Comparison: testcase.CompareSemantic},
+			},
+			{
+				Title:    "POST /x",
+				Request:  Request{Method: "POST", URL: "http://other.example.com/x", Entity: "Hello"},
+				Response: Response{Status: 201},
+			},
+			{
+				Title:   "GET /feed",
+				Request: Request{Method: "GET", URL: "ws://localhost:8080/feed"},
+				Stream:  &Stream{Messages: []Message{{Output: &ping}, {Input: &pong}}},
+			},
+		},
+	}, suite)
+}
+
+func TestFromPostman(t *testing.T) {
+	suite, err := FromPostman([]byte(postmanSource), Config{BaseURL: "https://example.com"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, &Suite{
+		Title:    "Users",
+		Comments: "About users",
+		TOC:      &TOC{Sections: []testcase.Section{{Key: "reading", Title: "Reading"}}},
+		Cases: []Case{
+			{
+				Title:    "Get a user",
+				Section:  "reading",
+				Request:  Request{Method: "GET", URL: "/users/1?verbose=true", Headers: map[string]string{"Authorization": "Bearer {{token}}"}},
+				Response: Response{Status: 200, Entity: `{"id": 1}`, Comparison: testcase.CompareSemantic},
+			},
+			{
+				Title:   "Ping",
+				Request: Request{Method: "GET", URL: "/ping"},
+			},
+			{
+				Title: "Create a user",
+				Request: Request{
+					Method:  "POST",
+					URL:     "/users",
+					Headers: map[string]string{"Content-Type": "application/json", "X-Tenant": "t1"},
+					Entity:  `{"name": "Bob"}`,
+				},
+			},
+		},
+	}, suite)
+}
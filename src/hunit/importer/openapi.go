@@ -0,0 +1,172 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/instaunit/instaunit/hunit/expr"
+	"github.com/instaunit/instaunit/hunit/httputil"
+	"github.com/instaunit/instaunit/hunit/openapi"
+)
+
+// Produce a suite with one case for every operation declared by an OpenAPI 3
+// document. Requests use the examples declared for parameters and request
+// bodies, or values synthesized from their schemas, and expect the first
+// successful status the operation declares.
+func FromOpenAPI(data []byte, conf Config) (*Suite, error) {
+	doc, err := openapi.Load(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Could not load OpenAPI document: %w", err)
+	}
+
+	var prefix string
+	if len(doc.Servers) > 0 {
+		if u, err := url.Parse(doc.Servers[0].URL); err == nil {
+			prefix = strings.TrimSuffix(u.Path, "/")
+		}
+	}
+
+	suite := &Suite{
+		Title:    doc.Info.Title,
+		Comments: strings.TrimSpace(doc.Info.Description),
+	}
+	for _, r := range doc.Routes() {
+		op := r.Operation
+		c := Case{
+			Title:    coalesce(strings.TrimSpace(op.Summary), op.Id, r.Method+" "+r.Path),
+			Comments: strings.TrimSpace(op.Description),
+			Request: Request{
+				Method: r.Method,
+			},
+			Response: Response{
+				Status: successStatus(op),
+			},
+		}
+
+		path := r.Path
+		query := url.Values{}
+		for _, p := range r.Params {
+			if p == nil {
+				continue
+			}
+			switch p.In {
+			case openapi.InPath:
+				path = strings.ReplaceAll(path, "{"+p.Name+"}", url.PathEscape(paramValue(p)))
+			case openapi.InQuery:
+				if p.Required {
+					query.Set(p.Name, paramValue(p))
+				}
+			case openapi.InHeader:
+				if p.Required {
+					if c.Request.Headers == nil {
+						c.Request.Headers = make(map[string]string)
+					}
+					c.Request.Headers[p.Name] = paramValue(p)
+				}
+			}
+		}
+		c.Request.URL = prefix + path
+		if len(query) > 0 {
+			c.Request.URL += "?" + query.Encode()
+		}
+		if path != r.Path {
+			c.Route = &Route{Path: r.Path}
+		}
+
+		if body := op.RequestBody; body != nil {
+			ctype, media := body.Content.Preferred()
+			if media != nil {
+				entity, err := marshalSpecimen(ctype, media.Specimen())
+				if err != nil {
+					return nil, fmt.Errorf("Could not produce request entity: %s %s: %w", r.Method, r.Path, err)
+				}
+				if entity != "" {
+					if c.Request.Headers == nil {
+						c.Request.Headers = make(map[string]string)
+					}
+					c.Request.Headers["Content-Type"] = ctype
+					c.Request.Entity = expr.Escape(entity)
+				}
+			}
+		}
+
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	return suite, nil
+}
+
+// Produce a value for a parameter
+func paramValue(p *openapi.Parameter) string {
+	var v interface{}
+	if p.Example != nil {
+		v = p.Example
+	} else {
+		keys := make([]string, 0, len(p.Examples))
+		for k := range p.Examples {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if e := p.Examples[k]; e != nil && e.Value != nil {
+				v = e.Value
+				break
+			}
+		}
+		if v == nil && p.Schema != nil {
+			v = p.Schema.Synthesize()
+		}
+	}
+	if v == nil {
+		return p.Name
+	}
+	return fmt.Sprint(v)
+}
+
+// Marshal a specimen for the provided content type. Strings are produced
+// literally for types which are not JSON.
+func marshalSpecimen(ctype string, v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	if s, ok := v.(string); ok && !httputil.MatchesContentType("*/*json", ctype) {
+		return s, nil
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Select the first successful status declared by an operation; 200 if none
+// is declared explicitly
+func successStatus(op *openapi.Operation) int {
+	var codes []int
+	for k := range op.Responses {
+		if n, err := strconv.Atoi(k); err == nil {
+			codes = append(codes, n)
+		}
+	}
+	sort.Ints(codes)
+	for _, e := range codes {
+		if e >= 200 && e < 300 {
+			return e
+		}
+	}
+	return 200
+}
+
+func coalesce(s ...string) string {
+	for _, e := range s {
+		if e != "" {
+			return e
+		}
+	}
+	return ""
+}
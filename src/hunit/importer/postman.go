@@ -0,0 +1,339 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/instaunit/instaunit/hunit/expr"
+	"github.com/instaunit/instaunit/hunit/httputil/mimetype"
+	"github.com/instaunit/instaunit/hunit/testcase"
+	"github.com/instaunit/instaunit/hunit/text/slug"
+)
+
+// Postman variable references, e.g., '{{baseUrl}}'
+var postmanVar = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// Postman descriptions are either a string or an object with content
+type pmDescription string
+
+func (d *pmDescription) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		*d = pmDescription(s)
+		return nil
+	}
+	var v struct {
+		Content string `json:"content"`
+	}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	*d = pmDescription(v.Content)
+	return nil
+}
+
+type pmPair struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+// Headers are either a list of pairs or a string of header lines
+type pmHeaders []pmPair
+
+func (h *pmHeaders) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		for _, l := range strings.Split(s, "\n") {
+			if k, v, ok := strings.Cut(l, ":"); ok {
+				*h = append(*h, pmPair{Key: strings.TrimSpace(k), Value: strings.TrimSpace(v)})
+			}
+		}
+		return nil
+	}
+	var v []pmPair
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	*h = v
+	return nil
+}
+
+// Get the value of a header
+func (h pmHeaders) get(name string) string {
+	for _, e := range h {
+		if !e.Disabled && strings.EqualFold(e.Key, name) {
+			return e.Value
+		}
+	}
+	return ""
+}
+
+// URLs are either a string or an object
+type pmURL struct {
+	Raw string
+}
+
+func (u *pmURL) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		u.Raw = s
+		return nil
+	}
+	var v struct {
+		Raw      string   `json:"raw"`
+		Protocol string   `json:"protocol"`
+		Host     []string `json:"host"`
+		Path     []string `json:"path"`
+		Query    []pmPair `json:"query"`
+	}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	if v.Raw != "" {
+		u.Raw = v.Raw
+		return nil
+	}
+	b := &strings.Builder{}
+	if v.Protocol != "" {
+		b.WriteString(v.Protocol + "://")
+	}
+	b.WriteString(strings.Join(v.Host, "."))
+	if len(v.Path) > 0 {
+		b.WriteString("/" + strings.Join(v.Path, "/"))
+	}
+	var q []string
+	for _, e := range v.Query {
+		if !e.Disabled {
+			q = append(q, url.QueryEscape(e.Key)+"="+url.QueryEscape(e.Value))
+		}
+	}
+	if len(q) > 0 {
+		b.WriteString("?" + strings.Join(q, "&"))
+	}
+	u.Raw = b.String()
+	return nil
+}
+
+type pmBody struct {
+	Mode       string   `json:"mode"`
+	Raw        string   `json:"raw"`
+	URLEncoded []pmPair `json:"urlencoded"`
+	GraphQL    *struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+	Disabled bool `json:"disabled"`
+}
+
+// Requests are either a URL or an object
+type pmRequest struct {
+	Method      string
+	Headers     pmHeaders
+	Body        *pmBody
+	URL         pmURL
+	Description pmDescription
+}
+
+func (r *pmRequest) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		r.Method, r.URL = "GET", pmURL{Raw: s}
+		return nil
+	}
+	var v struct {
+		Method      string        `json:"method"`
+		Headers     pmHeaders     `json:"header"`
+		Body        *pmBody       `json:"body"`
+		URL         pmURL         `json:"url"`
+		Description pmDescription `json:"description"`
+	}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	*r = pmRequest(v)
+	return nil
+}
+
+type pmResponse struct {
+	Name    string    `json:"name"`
+	Code    int       `json:"code"`
+	Headers pmHeaders `json:"header"`
+	Body    string    `json:"body"`
+}
+
+type pmItem struct {
+	Name        string        `json:"name"`
+	Description pmDescription `json:"description"`
+	Items       []*pmItem     `json:"item"`
+	Request     *pmRequest    `json:"request"`
+	Responses   []*pmResponse `json:"response"`
+}
+
+type pmCollection struct {
+	Info struct {
+		Name        string        `json:"name"`
+		Description pmDescription `json:"description"`
+	} `json:"info"`
+	Items     []*pmItem `json:"item"`
+	Variables []struct {
+		Key   string      `json:"key"`
+		Value interface{} `json:"value"`
+	} `json:"variable"`
+}
+
+// Produce a suite from a Postman v2 collection. Every folder at the top level
+// of the collection becomes a section, which contains every request in the
+// folder. A variable at the start of a URL is taken to be the base URL and is
+// removed, so that the request is relative to the base URL for tests; other
+// variables are replaced by the values the collection declares for them. The
+// first example response of a request is expected.
+func FromPostman(data []byte, conf Config) (*Suite, error) {
+	var coll pmCollection
+	err := json.Unmarshal(data, &coll)
+	if err != nil {
+		return nil, fmt.Errorf("Could not load Postman collection: %w", err)
+	}
+
+	vars := make(map[string]string)
+	for _, e := range coll.Variables {
+		if e.Value != nil {
+			if v := fmt.Sprint(e.Value); v != "" {
+				vars[e.Key] = v
+			}
+		}
+	}
+	subst := func(s string) string {
+		return postmanVar.ReplaceAllStringFunc(s, func(m string) string {
+			if v, ok := vars[postmanVar.FindStringSubmatch(m)[1]]; ok {
+				return v
+			}
+			return m
+		})
+	}
+
+	suite := &Suite{
+		Title:    coll.Info.Name,
+		Comments: strings.TrimSpace(string(coll.Info.Description)),
+	}
+	slugs := make(map[string]int)
+
+	var walk func(items []*pmItem, section string)
+	walk = func(items []*pmItem, section string) {
+		for _, e := range items {
+			if e == nil {
+				continue
+			}
+			if e.Request == nil {
+				s := section
+				if s == "" && len(e.Items) > 0 {
+					s, slugs = slug.Github(e.Name, slugs)
+					if s != "" {
+						if suite.TOC == nil {
+							suite.TOC = &TOC{}
+						}
+						suite.TOC.Sections = append(suite.TOC.Sections, testcase.Section{Key: s, Title: e.Name})
+					}
+				}
+				walk(e.Items, s)
+				continue
+			}
+			suite.Cases = append(suite.Cases, postmanCase(e, section, subst, conf))
+		}
+	}
+	walk(coll.Items, "")
+
+	if len(suite.Cases) == 0 {
+		return nil, fmt.Errorf("No requests could be imported")
+	}
+	return suite, nil
+}
+
+// Produce a case from a request item
+func postmanCase(e *pmItem, section string, subst func(string) string, conf Config) Case {
+	r := e.Request
+	u := r.URL.Raw
+	if m := postmanVar.FindStringIndex(u); m != nil && m[0] == 0 {
+		u = u[m[1]:] // the base URL
+		if !strings.HasPrefix(u, "/") {
+			u = "/" + u
+		}
+	}
+	u = subst(u)
+
+	c := Case{
+		Title:    coalesce(strings.TrimSpace(e.Name), title(r.Method, u)),
+		Section:  section,
+		Comments: strings.TrimSpace(coalesce(string(r.Description), string(e.Description))),
+		Request: Request{
+			Method: coalesce(strings.ToUpper(r.Method), "GET"),
+			URL:    relativeURL(u, conf.BaseURL),
+		},
+	}
+	setHeader := func(k, v string) {
+		if c.Request.Headers == nil {
+			c.Request.Headers = make(map[string]string)
+		}
+		c.Request.Headers[k] = v
+	}
+	for _, h := range r.Headers {
+		if !h.Disabled && importHeader(h.Key) {
+			setHeader(h.Key, subst(h.Value))
+		}
+	}
+
+	if b := r.Body; b != nil && !b.Disabled {
+		var entity, ctype string
+		switch b.Mode {
+		case "raw":
+			entity = subst(b.Raw)
+			if strings.EqualFold(b.Options.Raw.Language, "json") {
+				ctype = mimetype.JSON
+			}
+		case "urlencoded":
+			v := url.Values{}
+			for _, p := range b.URLEncoded {
+				if !p.Disabled {
+					v.Add(p.Key, subst(p.Value))
+				}
+			}
+			entity, ctype = v.Encode(), "application/x-www-form-urlencoded"
+		case "graphql":
+			if g := b.GraphQL; g != nil {
+				q := map[string]interface{}{"query": g.Query}
+				var vars interface{}
+				if json.Unmarshal([]byte(g.Variables), &vars) == nil {
+					q["variables"] = vars
+				}
+				d, _ := json.MarshalIndent(q, "", "  ")
+				entity, ctype = string(d), mimetype.JSON
+			}
+		}
+		if entity != "" {
+			c.Request.Entity = expr.Escape(entity)
+			if ctype != "" && r.Headers.get("Content-Type") == "" {
+				setHeader("Content-Type", ctype)
+			}
+		}
+	}
+
+	if len(e.Responses) > 0 {
+		if x := e.Responses[0]; x != nil {
+			c.Response.Status = x.Code
+			c.Response.Entity, c.Response.Comparison = expectEntity(x.Headers.get("Content-Type"), x.Body)
+		}
+	}
+	return c
+}
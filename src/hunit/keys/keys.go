@@ -0,0 +1,15 @@
+package keys
+
+import (
+	"sort"
+)
+
+// Produce the keys of a map, in order
+func Sorted[E any](m map[string]E) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
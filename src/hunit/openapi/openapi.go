@@ -0,0 +1,379 @@
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/instaunit/instaunit/hunit/httputil"
+	"github.com/instaunit/instaunit/hunit/httputil/mimetype"
+	"github.com/instaunit/instaunit/hunit/keys"
+	"github.com/instaunit/instaunit/hunit/schema"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("Only OpenAPI 3 documents are supported")
+	ErrMalformedDocument  = errors.New("Malformed OpenAPI document")
+)
+
+// Parameter locations
+const (
+	InPath   = "path"
+	InQuery  = "query"
+	InHeader = "header"
+	InCookie = "cookie"
+)
+
+// The order in which methods are enumerated
+var methods = []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"}
+
+// An OpenAPI document
+type Document struct {
+	OpenAPI    string               `yaml:"openapi"`
+	Info       Info                 `yaml:"info"`
+	Servers    []Server             `yaml:"servers"`
+	Paths      map[string]*PathItem `yaml:"paths"`
+	Components Components           `yaml:"components"`
+}
+
+type Info struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	Version     string `yaml:"version"`
+}
+
+type Server struct {
+	URL         string `yaml:"url"`
+	Description string `yaml:"description"`
+}
+
+type Components struct {
+	Schemas map[string]*schema.Schema `yaml:"schemas"`
+}
+
+// A path and the operations available on it
+type PathItem struct {
+	Summary     string       `yaml:"summary"`
+	Description string       `yaml:"description"`
+	Parameters  []*Parameter `yaml:"parameters"`
+	Get         *Operation   `yaml:"get"`
+	Put         *Operation   `yaml:"put"`
+	Post        *Operation   `yaml:"post"`
+	Delete      *Operation   `yaml:"delete"`
+	Options     *Operation   `yaml:"options"`
+	Head        *Operation   `yaml:"head"`
+	Patch       *Operation   `yaml:"patch"`
+	Trace       *Operation   `yaml:"trace"`
+}
+
+// Obtain the operation for a method, if there is one
+func (p *PathItem) Operation(method string) *Operation {
+	switch strings.ToUpper(method) {
+	case "GET":
+		return p.Get
+	case "PUT":
+		return p.Put
+	case "POST":
+		return p.Post
+	case "DELETE":
+		return p.Delete
+	case "OPTIONS":
+		return p.Options
+	case "HEAD":
+		return p.Head
+	case "PATCH":
+		return p.Patch
+	case "TRACE":
+		return p.Trace
+	default:
+		return nil
+	}
+}
+
+type Operation struct {
+	Id          string               `yaml:"operationId"`
+	Summary     string               `yaml:"summary"`
+	Description string               `yaml:"description"`
+	Tags        []string             `yaml:"tags"`
+	Deprecated  bool                 `yaml:"deprecated"`
+	Parameters  []*Parameter         `yaml:"parameters"`
+	RequestBody *RequestBody         `yaml:"requestBody"`
+	Responses   map[string]*Response `yaml:"responses"`
+}
+
+type Parameter struct {
+	Name        string              `yaml:"name"`
+	In          string              `yaml:"in"`
+	Description string              `yaml:"description"`
+	Required    bool                `yaml:"required"`
+	Schema      *schema.Schema      `yaml:"schema"`
+	Example     interface{}         `yaml:"example"`
+	Examples    map[string]*Example `yaml:"examples"`
+}
+
+type RequestBody struct {
+	Description string  `yaml:"description"`
+	Required    bool    `yaml:"required"`
+	Content     Content `yaml:"content"`
+}
+
+type Response struct {
+	Description string             `yaml:"description"`
+	Headers     map[string]*Header `yaml:"headers"`
+	Content     Content            `yaml:"content"`
+}
+
+type Header struct {
+	Description string         `yaml:"description"`
+	Required    bool           `yaml:"required"`
+	Schema      *schema.Schema `yaml:"schema"`
+	Example     interface{}    `yaml:"example"`
+}
+
+type Example struct {
+	Summary     string      `yaml:"summary"`
+	Description string      `yaml:"description"`
+	Value       interface{} `yaml:"value"`
+}
+
+// Content by media type
+type Content map[string]*MediaType
+
+// Obtain the preferred media type from the content. JSON is preferred, then
+// any other JSON-like type, followed by the first type in lexical order.
+func (c Content) Preferred() (string, *MediaType) {
+	if len(c) == 0 {
+		return "", nil
+	}
+	if v, ok := c[mimetype.JSON]; ok {
+		return mimetype.JSON, v
+	}
+	names := keys.Sorted(c)
+	for _, k := range names {
+		if httputil.MatchesContentType("*/*json", k) {
+			return k, c[k]
+		}
+	}
+	return names[0], c[names[0]]
+}
+
+// Find the media type that applies to the provided content type, if any
+func (c Content) Match(ctype string) (string, *MediaType) {
+	for _, k := range keys.Sorted(c) {
+		if httputil.MatchesContentType(k, ctype) {
+			return k, c[k]
+		}
+	}
+	return "", nil
+}
+
+type MediaType struct {
+	Schema   *schema.Schema      `yaml:"schema"`
+	Example  interface{}         `yaml:"example"`
+	Examples map[string]*Example `yaml:"examples"`
+}
+
+// Produce a representative value for the media type. The explicit example is
+// preferred, followed by the first of the named examples, then the schema's
+// example. If no example is declared a value is synthesized from the schema.
+func (m *MediaType) Specimen() interface{} {
+	if m.Example != nil {
+		return m.Example
+	}
+	for _, k := range keys.Sorted(m.Examples) {
+		if e := m.Examples[k]; e != nil && e.Value != nil {
+			return e.Value
+		}
+	}
+	if m.Schema != nil {
+		return m.Schema.Synthesize()
+	}
+	return nil
+}
+
+// An operation and the route it is declared on
+type Route struct {
+	Path      string
+	Method    string
+	Params    []*Parameter // path-level and operation-level parameters, merged
+	Operation *Operation
+}
+
+// Enumerate every operation in the document, ordered by path and then method
+func (d *Document) Routes() []Route {
+	var routes []Route
+	for _, p := range keys.Sorted(d.Paths) {
+		item := d.Paths[p]
+		if item == nil {
+			continue
+		}
+		for _, m := range methods {
+			op := item.Operation(m)
+			if op == nil {
+				continue
+			}
+			routes = append(routes, Route{
+				Path:      p,
+				Method:    m,
+				Params:    mergeParams(item.Parameters, op.Parameters),
+				Operation: op,
+			})
+		}
+	}
+	return routes
+}
+
+// Operation-level parameters override path-level parameters with the same name
+// and location.
+func mergeParams(base, override []*Parameter) []*Parameter {
+	var params []*Parameter
+	for _, e := range base {
+		var shadowed bool
+		for _, x := range override {
+			if x.Name == e.Name && x.In == e.In {
+				shadowed = true
+				break
+			}
+		}
+		if !shadowed {
+			params = append(params, e)
+		}
+	}
+	return append(params, override...)
+}
+
+// Load a document from a file
+func LoadFile(p string) (*Document, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Load a document. Both YAML and JSON representations are supported. Local
+// references (e.g., '#/components/schemas/Name') are resolved; references
+// to external documents and recursive references are left in place.
+func Load(r io.Reader) (*Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &yaml.Node{}
+	err = yaml.Unmarshal(data, root)
+	if err != nil {
+		return nil, err
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) < 1 {
+		return nil, ErrMalformedDocument
+	}
+
+	err = resolve(root.Content[0], root.Content[0], make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	err = root.Decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, ErrUnsupportedVersion
+	}
+
+	return doc, nil
+}
+
+// Resolve local references in a node, in place
+func resolve(root, node *yaml.Node, active map[string]bool) error {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, e := range node.Content {
+			err := resolve(root, e, active)
+			if err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		if ref, ok := reference(node); ok {
+			if !strings.HasPrefix(ref, "#/") || active[ref] {
+				return nil // external or recursive; leave the reference in place
+			}
+			target, err := lookup(root, ref)
+			if err != nil {
+				return err
+			}
+			dup := copyNode(target)
+			active[ref] = true
+			err = resolve(root, dup, active)
+			delete(active, ref)
+			if err != nil {
+				return err
+			}
+			*node = *dup
+			return nil
+		}
+		for i := 1; i < len(node.Content); i += 2 {
+			err := resolve(root, node.Content[i], active)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Obtain the reference from a mapping node, if it is a reference
+func reference(node *yaml.Node) (string, bool) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if k := node.Content[i]; k.Kind == yaml.ScalarNode && k.Value == "$ref" {
+			return node.Content[i+1].Value, true
+		}
+	}
+	return "", false
+}
+
+// Look up a node by its JSON pointer
+func lookup(root *yaml.Node, ref string) (*yaml.Node, error) {
+	node := root
+outer:
+	for _, e := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		e = strings.ReplaceAll(strings.ReplaceAll(e, "~1", "/"), "~0", "~")
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == e {
+					node = node.Content[i+1]
+					continue outer
+				}
+			}
+		case yaml.SequenceNode:
+			var x int
+			if _, err := fmt.Sscanf(e, "%d", &x); err == nil && x >= 0 && x < len(node.Content) {
+				node = node.Content[x]
+				continue outer
+			}
+		}
+		return nil, fmt.Errorf("Unresolved reference: %v", ref)
+	}
+	return node, nil
+}
+
+// Deep copy a node
+func copyNode(n *yaml.Node) *yaml.Node {
+	d := *n
+	if n.Content != nil {
+		d.Content = make([]*yaml.Node, len(n.Content))
+		for i, e := range n.Content {
+			d.Content[i] = copyNode(e)
+		}
+	}
+	return &d
+}
@@ -0,0 +1,166 @@
+package openapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/instaunit/instaunit/hunit/schema"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const documentSource = `
+openapi: 3.0.3
+info: {title: Users, version: 1.0.0}
+paths:
+  /users/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: string}}
+      - {name: verbose, in: query, schema: {type: boolean}}
+    post:
+      operationId: updateUser
+      parameters:
+        - {name: verbose, in: query, required: true, schema: {type: boolean}}
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/User'}
+      responses:
+        "200":
+          description: Ok
+          content:
+            text/plain: {example: Ok}
+            application/vnd.user+json:
+              examples:
+                b: {value: {name: B}}
+                a: {value: {name: A}}
+    get:
+      responses:
+        "200":
+          description: Ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/User'}
+  /a~1b:
+    get:
+      responses:
+        "200": {$ref: '#/paths/~1users~1{id}/get/responses/200'}
+components:
+  schemas:
+    User:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string, example: Joe}
+        manager: {$ref: '#/components/schemas/User'}
+`
+
+func TestLoad(t *testing.T) {
+	doc, err := Load(strings.NewReader(documentSource))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "Users", doc.Info.Title)
+
+	routes := doc.Routes()
+	if !assert.Len(t, routes, 3) {
+		return
+	}
+	assert.Equal(t, "/a~1b GET", routes[0].Path+" "+routes[0].Method)
+	assert.Equal(t, "/users/{id} GET", routes[1].Path+" "+routes[1].Method)
+	assert.Equal(t, "/users/{id} POST", routes[2].Path+" "+routes[2].Method)
+
+	// operation parameters override path parameters
+	r := routes[2]
+	if assert.Len(t, r.Params, 2) {
+		assert.Equal(t, "id", r.Params[0].Name)
+		assert.Equal(t, "verbose", r.Params[1].Name)
+		assert.True(t, r.Params[1].Required)
+	}
+
+	// local references are resolved; recursive references are left in place
+	_, media := r.Operation.RequestBody.Content.Preferred()
+	if assert.NotNil(t, media) && assert.NotNil(t, media.Schema) {
+		s := media.Schema
+		assert.Equal(t, schema.Types{schema.TypeObject}, s.Type)
+		assert.Equal(t, []string{"name"}, s.Required)
+		if m := s.Properties["manager"]; assert.NotNil(t, m) {
+			assert.Equal(t, "#/components/schemas/User", m.Ref)
+		}
+		if v, ok := media.Specimen().(map[string]interface{}); assert.True(t, ok) {
+			assert.Equal(t, "Joe", v["name"])
+		}
+	}
+
+	// references are resolved through escaped pointers
+	if rsp := routes[0].Operation.Responses["200"]; assert.NotNil(t, rsp) {
+		ctype, media := rsp.Content.Preferred()
+		assert.Equal(t, "application/json", ctype)
+		assert.NotNil(t, media)
+	}
+
+	// JSON-like types are preferred, and named examples are used in order
+	content := r.Operation.Responses["200"].Content
+	ctype, media := content.Preferred()
+	if assert.Equal(t, "application/vnd.user+json", ctype) {
+		assert.Equal(t, map[string]interface{}{"name": "A"}, media.Specimen())
+	}
+	ctype, media = content.Match("text/plain; charset=utf-8")
+	if assert.Equal(t, "text/plain", ctype) {
+		assert.Equal(t, "Ok", media.Specimen())
+	}
+	ctype, media = content.Match("application/xml")
+	assert.Equal(t, "", ctype)
+	assert.Nil(t, media)
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		Source string
+		Error  error
+	}{
+		{`swagger: "2.0"`, ErrUnsupportedVersion},
+		{``, ErrMalformedDocument},
+		{"openapi: 3.0.0\npaths: {/a: {$ref: '#/missing'}}", nil},
+	}
+	for _, e := range tests {
+		_, err := Load(strings.NewReader(e.Source))
+		if assert.NotNil(t, err, e.Source) && e.Error != nil {
+			assert.ErrorIs(t, err, e.Error, e.Source)
+		}
+	}
+}
+
+func TestLoad31(t *testing.T) {
+	doc, err := Load(strings.NewReader(`
+openapi: 3.1.0
+info: {title: Prices, version: 1.0.0}
+paths:
+  /prices:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                amount: {type: [number, "null"], exclusiveMinimum: 0, exclusiveMaximum: 100}
+      responses:
+        "204": {description: Created}
+`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	routes := doc.Routes()
+	if !assert.Len(t, routes, 1) {
+		return
+	}
+	_, media := routes[0].Operation.RequestBody.Content.Preferred()
+	if assert.NotNil(t, media) && assert.NotNil(t, media.Schema) {
+		s := media.Schema
+		assert.Nil(t, s.Validate(map[string]interface{}{"amount": 1.0}))
+		assert.Nil(t, s.Validate(map[string]interface{}{"amount": nil}))
+		assert.NotNil(t, s.Validate(map[string]interface{}{"amount": 0.0}))
+		assert.NotNil(t, s.Validate(map[string]interface{}{"amount": 100.0}))
+	}
+}
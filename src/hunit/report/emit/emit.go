@@ -9,6 +9,7 @@ import (
 
 // Suite results
 type Results struct {
+	Path    string // the suite file
 	Results []*hunit.Result
 	Runtime time.Duration
 }
@@ -18,16 +19,28 @@ type Doctype uint32
 
 const (
 	DoctypeJUnitXML Doctype = iota
+	DoctypeJSON
+	DoctypeHTML
+	DoctypeTAP
+	DoctypeGithub
 	DoctypeInvalid
 )
 
 var doctypeNames = []string{
 	"junit",
+	"json",
+	"html",
+	"tap",
+	"github",
 	"<invalid>",
 }
 
 var doctypeExts = []string{
 	".xml",
+	".json",
+	".html",
+	".tap",
+	".txt",
 	".???",
 }
 
@@ -36,6 +49,14 @@ func ParseDoctype(s string) (Doctype, error) {
 	switch s {
 	case "junit":
 		return DoctypeJUnitXML, nil
+	case "json":
+		return DoctypeJSON, nil
+	case "html":
+		return DoctypeHTML, nil
+	case "tap":
+		return DoctypeTAP, nil
+	case "github":
+		return DoctypeGithub, nil
 	default:
 		return DoctypeInvalid, fmt.Errorf("Unsupported type: %v", s)
 	}
@@ -0,0 +1,23 @@
+package emittest
+
+import (
+	"bytes"
+
+	"github.com/instaunit/instaunit/hunit/testcase"
+)
+
+// A buffer that reports can be written to. Closing it has no effect, so the
+// report remains available after it is finalized.
+type Buffer struct {
+	bytes.Buffer
+}
+
+// Close the buffer
+func (b *Buffer) Close() error {
+	return nil
+}
+
+// Produce a test case that is declared at the provided location
+func At(file string, line int) testcase.Case {
+	return testcase.Case{Source: testcase.Source{File: file, Line: line}}
+}
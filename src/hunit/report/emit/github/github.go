@@ -0,0 +1,83 @@
+package github
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/instaunit/instaunit/hunit/report/emit"
+	"github.com/instaunit/instaunit/hunit/report/emit/json"
+	tc "github.com/instaunit/instaunit/hunit/testcase"
+)
+
+// A GitHub Actions annotation report generator. Failed and skipped cases are
+// written as workflow commands which, when they are written to the output of a
+// workflow step, annotate the test suite at the case's source location.
+//
+// See: https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions
+type Generator struct {
+	w io.WriteCloser
+}
+
+// Produce a new emitter
+func New(w io.WriteCloser) *Generator {
+	return &Generator{w: w}
+}
+
+// Initialize the report
+func (g *Generator) Init() error {
+	return nil
+}
+
+// Finalize the report
+func (g *Generator) Finalize() error {
+	return g.w.Close()
+}
+
+// Generate a report for the provided suite
+func (g *Generator) Suite(conf tc.Config, suite *tc.Suite, results *emit.Results) error {
+	for _, e := range results.Results {
+		c := json.NewCase(e)
+		var cmd, msg string
+		switch c.Status {
+		case json.StatusFailed:
+			cmd, msg = "error", strings.Join(c.Errors, "\n\n")
+			if msg == "" {
+				msg = "The test failed"
+			}
+		case json.StatusSkipped:
+			cmd, msg = "warning", "The test was skipped because a dependency failed"
+		default:
+			continue
+		}
+		_, err := fmt.Fprintln(g.w, command(cmd, c, msg))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Produce a workflow command annotating a case
+func command(cmd string, c json.Case, msg string) string {
+	var props []string
+	if c.File != "" {
+		props = append(props, "file="+escapeProperty(c.File))
+		if c.Line > 0 {
+			props = append(props, fmt.Sprintf("line=%d", c.Line))
+		}
+		if c.Column > 0 {
+			props = append(props, fmt.Sprintf("col=%d", c.Column))
+		}
+	}
+	props = append(props, "title="+escapeProperty(c.Name))
+	return fmt.Sprintf("::%s %s::%s", cmd, strings.Join(props, ","), escapeData(strings.TrimSpace(msg)))
+}
+
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
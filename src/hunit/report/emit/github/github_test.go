@@ -0,0 +1,44 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/instaunit/instaunit/hunit"
+	"github.com/instaunit/instaunit/hunit/report/emit"
+	"github.com/instaunit/instaunit/hunit/report/emit/emittest"
+	tc "github.com/instaunit/instaunit/hunit/testcase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	b := &emittest.Buffer{}
+	g := New(b)
+	assert.Nil(t, g.Init())
+
+	src := func(file string, l, c int) tc.Case {
+		return tc.Case{Source: tc.Source{File: file, Line: l, Column: c}}
+	}
+	err := g.Suite(tc.Config{}, &tc.Suite{}, &emit.Results{
+		Path: "tests/users.yml",
+		Results: []*hunit.Result{
+			{Name: "GET /users", Success: true, Case: src("tests/users.yml", 1, 3)},
+			{
+				Name:   "GET /users?a=1,b=2",
+				Errors: []string{"Unexpected status code:\n  expected: 200\n    actual: 404", "100%\r wrong"},
+				Case:   src("tests/users, v1:a.yml", 5, 3),
+			},
+			{Name: "GET /groups", Case: src("tests/users.yml", 9, 0)},
+			{Name: "DELETE /users/1", Skipped: true, Case: src("", 13, 3)},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, g.Finalize())
+
+	// properties escape ':' and ',' in addition to the characters that are
+	// escaped in messages
+	assert.Equal(t, `::error file=tests/users%2C v1%3Aa.yml,line=5,col=3,title=GET /users?a=1%2Cb=2::Unexpected status code:%0A  expected: 200%0A    actual: 404%0A%0A100%25%0D wrong
+::error file=tests/users.yml,line=9,title=GET /groups::The test failed
+::warning title=DELETE /users/1::The test was skipped because a dependency failed
+`, b.String())
+}
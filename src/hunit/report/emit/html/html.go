@@ -0,0 +1,149 @@
+package html
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/instaunit/instaunit/hunit/report/emit"
+	"github.com/instaunit/instaunit/hunit/report/emit/json"
+	tc "github.com/instaunit/instaunit/hunit/testcase"
+)
+
+//go:embed report.html
+var source string
+
+var report = template.Must(template.New("report").Funcs(template.FuncMap{
+	"diff":     diffLines,
+	"percent":  percent,
+	"duration": duration,
+	"inc":      func(v int) int { return v + 1 },
+}).Parse(source))
+
+// A line of an error message. Errors produced by failed assertions include a
+// diff of the expected and actual values; those lines are marked so they can
+// be colored.
+type line struct {
+	Text  string
+	Class string
+}
+
+// A suite and the longest duration of its cases, which timing bars are
+// relative to
+type suite struct {
+	json.Suite
+	Longest float64
+}
+
+// An HTML report generator. The report is a single file with no external
+// dependencies, so that it can be archived and shared.
+type Generator struct {
+	w       io.WriteCloser
+	id      string
+	created time.Time
+	report  json.Report
+	suites  []suite
+}
+
+// Produce a new emitter
+func New(w io.WriteCloser, id string) *Generator {
+	return &Generator{w: w, id: id}
+}
+
+// Initialize the report
+func (g *Generator) Init() error {
+	g.report = json.Report{Id: g.id, Created: time.Now()}
+	g.suites = nil
+	return nil
+}
+
+// Finalize the report
+func (g *Generator) Finalize() error {
+	err := report.Execute(g.w, struct {
+		json.Report
+		Suites []suite
+	}{
+		Report: g.report,
+		Suites: g.suites,
+	})
+	if err != nil {
+		return err
+	}
+	return g.w.Close()
+}
+
+// Generate a report for the provided suite
+func (g *Generator) Suite(conf tc.Config, s *tc.Suite, results *emit.Results) error {
+	x := suite{
+		Suite: json.Suite{
+			Title:    strings.TrimSpace(s.Title),
+			Path:     results.Path,
+			Tests:    len(results.Results),
+			Duration: results.Runtime.Seconds(),
+			Cases:    make([]json.Case, len(results.Results)),
+		},
+	}
+	for i, e := range results.Results {
+		c := json.NewCase(e)
+		switch c.Status {
+		case json.StatusFailed:
+			x.Failures++
+		case json.StatusSkipped:
+			x.Skipped++
+		}
+		if c.Duration > x.Longest {
+			x.Longest = c.Duration
+		}
+		x.Cases[i] = c
+	}
+
+	g.suites = append(g.suites, x)
+	g.report.Tests += x.Tests
+	g.report.Failures += x.Failures
+	g.report.Skipped += x.Skipped
+	g.report.Duration += x.Duration
+	return nil
+}
+
+// Split an error into lines, marking those that are part of a diff
+func diffLines(s string) []line {
+	var l []line
+	for _, e := range strings.Split(strings.TrimRight(s, "\n"), "\n") {
+		var class string
+		switch t := strings.TrimLeft(e, " \t"); {
+		case isDiffLine(t, '-'):
+			class = "removed"
+		case isDiffLine(t, '+'):
+			class = "added"
+		case strings.HasPrefix(t, "expected:"):
+			class = "removed"
+		case strings.HasPrefix(t, "actual:"):
+			class = "added"
+		}
+		l = append(l, line{e, class})
+	}
+	return l
+}
+
+// Determine if a line is marked as a diff line. Diffs produced by go-cmp may
+// use a non-breaking space after the marker.
+func isDiffLine(s string, m rune) bool {
+	r := []rune(s)
+	return len(r) > 1 && r[0] == m && unicode.IsSpace(r[1])
+}
+
+// Produce the proportion of a value to a total as a percentage
+func percent(v, t float64) float64 {
+	if t <= 0 {
+		return 0
+	}
+	return v / t * 100
+}
+
+// Format a duration in seconds
+func duration(v float64) string {
+	return time.Duration(v * float64(time.Second)).Round(time.Microsecond).String()
+}
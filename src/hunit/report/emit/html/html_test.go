@@ -0,0 +1,73 @@
+package html
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit"
+	"github.com/instaunit/instaunit/hunit/report/emit"
+	"github.com/instaunit/instaunit/hunit/report/emit/emittest"
+	tc "github.com/instaunit/instaunit/hunit/testcase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	b := &emittest.Buffer{}
+	g := New(b, "1")
+	assert.Nil(t, g.Init())
+
+	err := g.Suite(tc.Config{}, &tc.Suite{Title: "Users"}, &emit.Results{
+		Path: "tests/users.yml",
+		Results: []*hunit.Result{
+			{Name: "GET /users", Success: true, Runtime: time.Second, Case: emittest.At("tests/users.yml", 1)},
+			{
+				Name:    "GET /users/<1>",
+				Errors:  []string{"Entities do not match:\n  - {\"name\": \"Joe\"}\n  + {\"name\": \"<script>alert(1)</script>\"}"},
+				Reqdata: []byte("GET /users/1 HTTP/1.1"),
+				Rspdata: []byte(`HTTP/1.1 200 OK` + "\n\n" + `{"name": "<script>alert(1)</script>"}`),
+				Runtime: time.Second * 2,
+				Case:    emittest.At("tests/users.yml", 5),
+			},
+			{Name: "DELETE /users/1", Skipped: true, Case: emittest.At("tests/users.yml", 9)},
+		},
+		Runtime: time.Second * 3,
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, g.Finalize())
+	out := b.String()
+
+	assert.Contains(t, out, "<title>Test Results (1 failed)</title>")
+	assert.Contains(t, out, "<span>3 tests</span>")
+	assert.Contains(t, out, "<span>1 failed</span>")
+	assert.Contains(t, out, "<span>1 skipped</span>")
+	assert.Contains(t, out, `<details class="suite" open>`) // suites with failures are expanded
+	assert.Contains(t, out, `Users<span class="counts">3 tests, 1 failed, 1 skipped, 3s &mdash; tests/users.yml</span>`)
+
+	assert.Contains(t, out, `<details class="case passed-case">`)
+	assert.Contains(t, out, `<details class="case failed-case">`)
+	assert.Contains(t, out, `<details class="case skipped-case">`)
+	assert.Contains(t, out, `<span class="status skipped">skipped</span>`)
+	assert.Contains(t, out, `<div class="location">tests/users.yml:5</div>`)
+	assert.Contains(t, out, `<span style="width: 50.0%">`) // relative to the longest case
+
+	// the lines of a diff are marked, and untrusted data is escaped
+	assert.Contains(t, out, `<span class="removed">  - {&#34;name&#34;: &#34;Joe&#34;}</span>`)
+	assert.Contains(t, out, `<span class="added">  &#43; {&#34;name&#34;: &#34;&lt;script&gt;alert(1)&lt;/script&gt;&#34;}</span>`)
+	assert.Contains(t, out, `<pre>HTTP/1.1 200 OK`+"\n\n"+`{&#34;name&#34;: &#34;&lt;script&gt;alert(1)&lt;/script&gt;&#34;}</pre>`)
+	assert.Contains(t, out, `title="GET /users/&lt;1&gt;">GET /users/&lt;1&gt;</span>`)
+	assert.NotContains(t, out, "<script>alert")
+	assert.Equal(t, 1, strings.Count(out, "<script>"))
+}
+
+func TestDiffLines(t *testing.T) {
+	assert.Equal(t, []line{
+		{"Entities do not match:", ""},
+		{"  - a", "removed"},
+		{"  +\u00a0b", "added"},
+		{"  expected: 1", "removed"},
+		{"    actual: 2", "added"},
+		{"-not a diff", ""}, // the marker is followed by a space
+	}, diffLines("Entities do not match:\n  - a\n  +\u00a0b\n  expected: 1\n    actual: 2\n-not a diff\n"))
+}
@@ -0,0 +1,158 @@
+package json
+
+import (
+	enc "encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit"
+	"github.com/instaunit/instaunit/hunit/expr"
+	"github.com/instaunit/instaunit/hunit/report/emit"
+	tc "github.com/instaunit/instaunit/hunit/testcase"
+)
+
+// Test outcomes
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// A test case result
+type Case struct {
+	Name     string                 `json:"name"`
+	Title    string                 `json:"title,omitempty"`
+	File     string                 `json:"file,omitempty"`
+	Line     int                    `json:"line,omitempty"`
+	Column   int                    `json:"column,omitempty"`
+	Row      int                    `json:"row,omitempty"` // the row of the matrix the case was run for, from 1
+	Status   string                 `json:"status"`
+	Errors   []string               `json:"errors,omitempty"`
+	Duration float64                `json:"duration"` // in seconds
+	Request  string                 `json:"request,omitempty"`
+	Response string                 `json:"response,omitempty"`
+	Vars     map[string]interface{} `json:"vars,omitempty"`
+}
+
+// A test suite result
+type Suite struct {
+	Title    string  `json:"title,omitempty"`
+	Path     string  `json:"path,omitempty"`
+	Tests    int     `json:"tests"`
+	Failures int     `json:"failures"`
+	Skipped  int     `json:"skipped"`
+	Duration float64 `json:"duration"` // in seconds
+	Cases    []Case  `json:"cases"`
+}
+
+// A test run
+type Report struct {
+	Id       string    `json:"id,omitempty"`
+	Created  time.Time `json:"created"`
+	Tests    int       `json:"tests"`
+	Failures int       `json:"failures"`
+	Skipped  int       `json:"skipped"`
+	Duration float64   `json:"duration"` // in seconds
+	Suites   []Suite   `json:"suites"`
+}
+
+// Read a report
+func Load(r io.Reader) (*Report, error) {
+	rep := &Report{}
+	err := enc.NewDecoder(r).Decode(rep)
+	if err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
+
+// A JSON report generator
+type Generator struct {
+	w      io.WriteCloser
+	report Report
+}
+
+// Produce a new emitter
+func New(w io.WriteCloser, id string) *Generator {
+	return &Generator{w: w, report: Report{Id: id}}
+}
+
+// Initialize the report
+func (g *Generator) Init() error {
+	g.report = Report{Id: g.report.Id, Created: time.Now(), Suites: []Suite{}}
+	return nil
+}
+
+// Finalize the report
+func (g *Generator) Finalize() error {
+	e := enc.NewEncoder(g.w)
+	e.SetIndent("", "  ")
+	err := e.Encode(g.report)
+	if err != nil {
+		return err
+	}
+	return g.w.Close()
+}
+
+// Generate a report for the provided suite
+func (g *Generator) Suite(conf tc.Config, suite *tc.Suite, results *emit.Results) error {
+	s := Suite{
+		Title:    strings.TrimSpace(suite.Title),
+		Path:     results.Path,
+		Tests:    len(results.Results),
+		Duration: results.Runtime.Seconds(),
+		Cases:    make([]Case, len(results.Results)),
+	}
+	for i, e := range results.Results {
+		c := NewCase(e)
+		switch c.Status {
+		case StatusFailed:
+			s.Failures++
+		case StatusSkipped:
+			s.Skipped++
+		}
+		s.Cases[i] = c
+	}
+
+	g.report.Suites = append(g.report.Suites, s)
+	g.report.Tests += s.Tests
+	g.report.Failures += s.Failures
+	g.report.Skipped += s.Skipped
+	g.report.Duration += s.Duration
+	return nil
+}
+
+// Describe a result
+func NewCase(r *hunit.Result) Case {
+	c := Case{
+		Name:     strings.TrimSpace(r.Name),
+		Title:    r.Case.Title,
+		File:     r.Case.Source.File,
+		Line:     r.Case.Source.Line,
+		Column:   r.Case.Source.Column,
+		Row:      r.Row,
+		Errors:   r.Errors,
+		Duration: r.Runtime.Seconds(),
+		Request:  string(r.Reqdata),
+		Response: string(r.Rspdata),
+	}
+	switch {
+	case r.Skipped:
+		c.Status = StatusSkipped
+	case r.Success:
+		c.Status = StatusPassed
+	default:
+		c.Status = StatusFailed
+	}
+	switch v := r.Context.Variables["vars"].(type) {
+	case expr.Variables:
+		c.Vars = v
+	case map[string]interface{}:
+		c.Vars = v
+	}
+	if len(c.Vars) == 0 {
+		c.Vars = nil
+	}
+	return c
+}
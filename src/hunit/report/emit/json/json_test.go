@@ -0,0 +1,100 @@
+package json
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit"
+	"github.com/instaunit/instaunit/hunit/expr"
+	"github.com/instaunit/instaunit/hunit/report/emit"
+	"github.com/instaunit/instaunit/hunit/report/emit/emittest"
+	"github.com/instaunit/instaunit/hunit/runtime"
+	tc "github.com/instaunit/instaunit/hunit/testcase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCase(t *testing.T) {
+	src := tc.Source{File: "tests/users.yml", Line: 5, Column: 3}
+	tests := []struct {
+		Result *hunit.Result
+		Expect Case
+	}{
+		{
+			&hunit.Result{Name: " GET /users\n", Success: true, Runtime: time.Millisecond * 1500, Case: tc.Case{Title: "List", Source: src}},
+			Case{Name: "GET /users", Title: "List", File: "tests/users.yml", Line: 5, Column: 3, Status: StatusPassed, Duration: 1.5},
+		},
+		{
+			&hunit.Result{
+				Name:    "GET /users/1",
+				Errors:  []string{"Nope"},
+				Reqdata: []byte("GET /users/1 HTTP/1.1"),
+				Rspdata: []byte("HTTP/1.1 404 Not Found"),
+				Context: runtime.Context{Variables: expr.Variables{"vars": expr.Variables{"id": "1"}}},
+				Case:    tc.Case{Source: src},
+				Row:     2,
+			},
+			Case{Name: "GET /users/1", File: "tests/users.yml", Line: 5, Column: 3, Row: 2, Status: StatusFailed, Errors: []string{"Nope"}, Request: "GET /users/1 HTTP/1.1", Response: "HTTP/1.1 404 Not Found", Vars: map[string]interface{}{"id": "1"}},
+		},
+		{
+			&hunit.Result{Name: "GET /users/2", Skipped: true, Context: runtime.Context{Variables: expr.Variables{"vars": map[string]interface{}{}}}},
+			Case{Name: "GET /users/2", Status: StatusSkipped},
+		},
+	}
+	for _, e := range tests {
+		assert.Equal(t, e.Expect, NewCase(e.Result), e.Result.Name)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	b := &emittest.Buffer{}
+	g := New(b, "run-1")
+	assert.Nil(t, g.Init())
+
+	err := g.Suite(tc.Config{}, &tc.Suite{Title: " Users\n"}, &emit.Results{
+		Path: "tests/users.yml",
+		Results: []*hunit.Result{
+			{Name: "A", Success: true, Runtime: time.Second, Case: emittest.At("tests/users.yml", 1)},
+			{Name: "B", Errors: []string{"Nope"}, Runtime: time.Second, Case: emittest.At("tests/users.yml", 5), Row: 1},
+			{Name: "B", Success: true, Runtime: time.Second, Case: emittest.At("tests/users.yml", 5), Row: 2},
+			{Name: "C", Skipped: true, Case: emittest.At("tests/users.yml", 9)},
+		},
+		Runtime: time.Second * 3,
+	})
+	assert.Nil(t, err)
+	err = g.Suite(tc.Config{}, &tc.Suite{}, &emit.Results{
+		Path:    "tests/groups.yml",
+		Results: []*hunit.Result{{Name: "D", Success: true}},
+		Runtime: time.Second,
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, g.Finalize())
+
+	rep, err := Load(&b.Buffer)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "run-1", rep.Id)
+	assert.False(t, rep.Created.IsZero())
+	assert.Equal(t, 5, rep.Tests)
+	assert.Equal(t, 1, rep.Failures)
+	assert.Equal(t, 1, rep.Skipped)
+	assert.Equal(t, 4.0, rep.Duration)
+	if assert.Len(t, rep.Suites, 2) {
+		s := rep.Suites[0]
+		assert.Equal(t, "Users", s.Title)
+		assert.Equal(t, "tests/users.yml", s.Path)
+		assert.Equal(t, 4, s.Tests)
+		if assert.Len(t, s.Cases, 4) {
+			assert.Equal(t, Case{Name: "A", File: "tests/users.yml", Line: 1, Status: StatusPassed, Duration: 1}, s.Cases[0])
+			assert.Equal(t, Case{Name: "B", File: "tests/users.yml", Line: 5, Row: 1, Status: StatusFailed, Errors: []string{"Nope"}, Duration: 1}, s.Cases[1])
+			assert.Equal(t, 2, s.Cases[2].Row)
+			assert.Equal(t, StatusSkipped, s.Cases[3].Status)
+		}
+		assert.Equal(t, []Case{{Name: "D", Status: StatusPassed}}, rep.Suites[1].Cases)
+	}
+
+	_, err = Load(bytes.NewReader([]byte(`{"suites": [`)))
+	assert.NotNil(t, err)
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"os"
+	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -22,18 +25,43 @@ type testfail struct {
 	Detail  string `xml:",cdata"`
 }
 
+type output struct {
+	Text string `xml:",cdata"`
+}
+
+type testskip struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
 type testcase struct {
-	Id       string     `xml:"id,attr,omitempty"`
-	Name     string     `xml:"name,attr,omitempty"`
-	Duration float64    `xml:"time,attr"`
-	Failures []testfail `xml:"failure,omitempty"`
+	Id        string     `xml:"id,attr,omitempty"`
+	Name      string     `xml:"name,attr,omitempty"`
+	Classname string     `xml:"classname,attr,omitempty"`
+	File      string     `xml:"file,attr,omitempty"`
+	Line      int        `xml:"line,attr,omitempty"`
+	Duration  float64    `xml:"time,attr"`
+	Skipped   *testskip  `xml:"skipped,omitempty"`
+	Failures  []testfail `xml:"failure,omitempty"`
+	Output    *output    `xml:"system-out,omitempty"`
+}
+
+type property struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
 }
 
 type testsuite struct {
-	Id       string     `xml:"id,attr,omitempty"`
-	Name     string     `xml:"name,attr,omitempty"`
-	Duration float64    `xml:"time,attr"`
-	Cases    []testcase `xml:"testcase"`
+	Id         string     `xml:"id,attr,omitempty"`
+	Name       string     `xml:"name,attr,omitempty"`
+	File       string     `xml:"file,attr,omitempty"`
+	Tests      int        `xml:"tests,attr"`
+	Failures   int        `xml:"failures,attr"`
+	Skipped    int        `xml:"skipped,attr"`
+	Timestamp  string     `xml:"timestamp,attr,omitempty"`
+	Hostname   string     `xml:"hostname,attr,omitempty"`
+	Duration   float64    `xml:"time,attr"`
+	Properties []property `xml:"properties>property,omitempty"`
+	Cases      []testcase `xml:"testcase"`
 }
 
 type testsuites struct {
@@ -41,22 +69,25 @@ type testsuites struct {
 	Name     string      `xml:"name,attr,omitempty"`
 	Tests    int         `xml:"tests,attr"`
 	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
 	Duration float64     `xml:"time,attr"`
 	Suites   []testsuite `xml:"testsuite,omitempty"`
 }
 
 // A junit report generator
 type Generator struct {
-	w               io.WriteCloser
-	id              string
-	tests, failures int
-	duration        time.Duration
-	suites          []testsuite
+	w                        io.WriteCloser
+	id                       string
+	hostname                 string
+	tests, failures, skipped int
+	duration                 time.Duration
+	suites                   []testsuite
 }
 
 // Produce a new emitter
 func New(w io.WriteCloser, id string) *Generator {
-	return &Generator{w: w, id: id}
+	host, _ := os.Hostname() // if we can't determine it, it's omitted
+	return &Generator{w: w, id: id, hostname: host}
 }
 
 // Initialize the report
@@ -71,6 +102,7 @@ func (g *Generator) Finalize() error {
 		Id:       g.id,
 		Tests:    g.tests,
 		Failures: g.failures,
+		Skipped:  g.skipped,
 		Duration: float64(g.duration) / float64(time.Second),
 		Suites:   g.suites,
 	}
@@ -87,20 +119,25 @@ func (g *Generator) Finalize() error {
 
 // Generate a report for the provided suite
 func (g *Generator) Suite(conf tc.Config, suite *tc.Suite, results *emit.Results) error {
-	var success, failure int
+	var failure, skipped int
 	for _, e := range results.Results {
-		if e.Success {
-			success++
-		} else {
+		if e.Skipped {
+			skipped++
+		} else if !e.Success {
 			failure++
 		}
 	}
 
 	sid := len(g.suites) + 1
+	class := classname(results.Path)
 	tc := make([]testcase, len(results.Results))
 	for i, e := range results.Results {
 		var tf []testfail
-		if len(e.Errors) > 0 {
+		var ts *testskip
+		var out *output
+		if e.Skipped {
+			ts = &testskip{Message: "A dependency failed"}
+		} else if len(e.Errors) > 0 {
 			for _, err := range e.Errors {
 				tf = append(tf, testfail{
 					Type:   severityError,
@@ -113,24 +150,55 @@ func (g *Generator) Suite(conf tc.Config, suite *tc.Suite, results *emit.Results
 				Message: "The test failed. That's all we know.",
 			})
 		}
+		if len(tf) > 0 && (len(e.Reqdata) > 0 || len(e.Rspdata) > 0) {
+			out = &output{Text: string(e.Reqdata) + "\n" + string(e.Rspdata)}
+		}
 		tc[i] = testcase{
-			Id:       fmt.Sprintf("%s_%d_%d", g.id, sid, i+1),
-			Name:     strings.TrimSpace(e.Name),
-			Duration: float64(e.Runtime) / float64(time.Second),
-			Failures: tf,
+			Id:        fmt.Sprintf("%s_%d_%d", g.id, sid, i+1),
+			Name:      strings.TrimSpace(e.Name),
+			Classname: class,
+			File:      e.Case.Source.File,
+			Line:      e.Case.Source.Line,
+			Duration:  float64(e.Runtime) / float64(time.Second),
+			Skipped:   ts,
+			Failures:  tf,
+			Output:    out,
 		}
 	}
 
+	name := strings.TrimSpace(suite.Title)
+	if name == "" {
+		name = results.Path
+	}
 	ts := testsuite{
-		Id:       fmt.Sprintf("%s_%d", g.id, sid),
-		Name:     strings.TrimSpace(suite.Title),
-		Duration: float64(results.Runtime) / float64(time.Second),
-		Cases:    tc,
+		Id:        fmt.Sprintf("%s_%d", g.id, sid),
+		Name:      name,
+		File:      results.Path,
+		Tests:     len(results.Results),
+		Failures:  failure,
+		Skipped:   skipped,
+		Timestamp: time.Now().Add(-results.Runtime).Format("2006-01-02T15:04:05"),
+		Hostname:  g.hostname,
+		Duration:  float64(results.Runtime) / float64(time.Second),
+		Properties: []property{
+			{Name: "instaunit.run", Value: g.id},
+			{Name: "instaunit.suite", Value: results.Path},
+		},
+		Cases: tc,
 	}
 
 	g.suites = append(g.suites, ts)
 	g.tests += len(results.Results)
 	g.failures += failure
+	g.skipped += skipped
 	g.duration += results.Runtime
 	return nil
 }
+
+// Produce a class name from the path to a suite; e.g., 'tests/users.yml'
+// becomes 'tests.users'.
+func classname(p string) string {
+	p = strings.TrimSuffix(p, path.Ext(p))
+	p = strings.TrimPrefix(path.Clean(filepath.ToSlash(p)), "./")
+	return strings.ReplaceAll(p, "/", ".")
+}
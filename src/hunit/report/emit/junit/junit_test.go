@@ -0,0 +1,68 @@
+package junit
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit"
+	"github.com/instaunit/instaunit/hunit/report/emit"
+	"github.com/instaunit/instaunit/hunit/report/emit/emittest"
+	tc "github.com/instaunit/instaunit/hunit/testcase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuite(t *testing.T) {
+	b := &emittest.Buffer{}
+	g := New(b, "1")
+	assert.Nil(t, g.Init())
+
+	err := g.Suite(tc.Config{}, &tc.Suite{}, &emit.Results{
+		Path: "tests/users.yml",
+		Results: []*hunit.Result{
+			{Name: "A", Success: true, Case: emittest.At("tests/users.yml", 1)},
+			{Name: "B", Errors: []string{"Nope"}, Reqdata: []byte("GET / HTTP/1.1"), Case: emittest.At("tests/users.yml", 5)},
+			{Name: "C", Skipped: true, Case: emittest.At("tests/users.yml", 9)},
+		},
+		Runtime: time.Second,
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, g.Finalize())
+
+	var ts testsuites
+	err = xml.NewDecoder(&b.Buffer).Decode(&ts)
+	if assert.Nil(t, err) && assert.Len(t, ts.Suites, 1) {
+		assert.Equal(t, 3, ts.Tests)
+		assert.Equal(t, 1, ts.Failures)
+		assert.Equal(t, 1, ts.Skipped)
+		s := ts.Suites[0]
+		assert.Equal(t, "tests/users.yml", s.Name)
+		assert.Equal(t, 1, s.Skipped)
+		if assert.Len(t, s.Cases, 3) {
+			assert.Equal(t, "tests.users", s.Cases[0].Classname)
+			assert.Equal(t, 5, s.Cases[1].Line)
+			assert.Nil(t, s.Cases[0].Output)
+			if assert.NotNil(t, s.Cases[1].Output) {
+				assert.Contains(t, s.Cases[1].Output.Text, "GET / HTTP/1.1")
+			}
+			assert.NotNil(t, s.Cases[2].Skipped)
+			assert.Len(t, s.Cases[2].Failures, 0)
+		}
+	}
+}
+
+func TestClassname(t *testing.T) {
+	tests := []struct {
+		Path   string
+		Expect string
+	}{
+		{"users.yml", "users"},
+		{"tests/users.yml", "tests.users"},
+		{"./tests/users.yml", "tests.users"},
+		{".github/tests/users.yml", ".github.tests.users"},
+	}
+	for _, e := range tests {
+		assert.Equal(t, e.Expect, classname(e.Path), e.Path)
+	}
+}
@@ -0,0 +1,132 @@
+package tap
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/instaunit/instaunit/hunit/report/emit"
+	"github.com/instaunit/instaunit/hunit/report/emit/json"
+	tc "github.com/instaunit/instaunit/hunit/testcase"
+	"github.com/instaunit/instaunit/hunit/text"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Diagnostics describing a failed test, written as a YAML block
+type diagnostic struct {
+	Message    string   `yaml:"message"`
+	Severity   string   `yaml:"severity"`
+	File       string   `yaml:"file,omitempty"`
+	Line       int      `yaml:"line,omitempty"`
+	DurationMS float64  `yaml:"duration_ms"`
+	Errors     []string `yaml:"errors,omitempty"`
+	Request    string   `yaml:"request,omitempty"`
+	Response   string   `yaml:"response,omitempty"`
+}
+
+type suite struct {
+	Name  string
+	Cases []json.Case
+}
+
+// A Test Anything Protocol (version 13) report generator. Every case in the
+// run is a test point; suites are introduced by comments.
+type Generator struct {
+	w      io.WriteCloser
+	tests  int
+	suites []suite
+}
+
+// Produce a new emitter
+func New(w io.WriteCloser) *Generator {
+	return &Generator{w: w}
+}
+
+// Initialize the report
+func (g *Generator) Init() error {
+	g.tests, g.suites = 0, nil
+	return nil
+}
+
+// Finalize the report
+func (g *Generator) Finalize() error {
+	err := g.write()
+	if err != nil {
+		return err
+	}
+	return g.w.Close()
+}
+
+func (g *Generator) write() error {
+	_, err := fmt.Fprintf(g.w, "TAP version 13\n1..%d\n", g.tests)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, s := range g.suites {
+		_, err = fmt.Fprintf(g.w, "# %s\n", s.Name)
+		if err != nil {
+			return err
+		}
+		for _, c := range s.Cases {
+			n++
+			err = writeCase(g.w, n, c)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeCase(w io.Writer, n int, c json.Case) error {
+	desc := strings.ReplaceAll(strings.ReplaceAll(c.Name, "#", "\\#"), "\n", " ")
+	switch c.Status {
+	case json.StatusPassed:
+		_, err := fmt.Fprintf(w, "ok %d - %s\n", n, desc)
+		return err
+	case json.StatusSkipped:
+		_, err := fmt.Fprintf(w, "ok %d - %s # SKIP dependency failed\n", n, desc)
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "not ok %d - %s\n", n, desc)
+	if err != nil {
+		return err
+	}
+	msg := "The test failed"
+	if len(c.Errors) > 0 {
+		msg = strings.SplitN(c.Errors[0], "\n", 2)[0]
+	}
+	data, err := yaml.Marshal(diagnostic{
+		Message:    msg,
+		Severity:   "fail",
+		File:       c.File,
+		Line:       c.Line,
+		DurationMS: math.Round(c.Duration*1e6) / 1e3,
+		Errors:     c.Errors,
+		Request:    c.Request,
+		Response:   c.Response,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "  ---\n%s\n  ...\n", text.Indent(strings.TrimRight(string(data), "\n"), "  "))
+	return err
+}
+
+// Generate a report for the provided suite
+func (g *Generator) Suite(conf tc.Config, s *tc.Suite, results *emit.Results) error {
+	x := suite{Name: strings.TrimSpace(s.Title), Cases: make([]json.Case, len(results.Results))}
+	if x.Name == "" {
+		x.Name = results.Path
+	}
+	for i, e := range results.Results {
+		x.Cases[i] = json.NewCase(e)
+	}
+	g.suites = append(g.suites, x)
+	g.tests += len(x.Cases)
+	return nil
+}
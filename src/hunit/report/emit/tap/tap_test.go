@@ -0,0 +1,75 @@
+package tap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit"
+	"github.com/instaunit/instaunit/hunit/report/emit"
+	"github.com/instaunit/instaunit/hunit/report/emit/emittest"
+	tc "github.com/instaunit/instaunit/hunit/testcase"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	b := &emittest.Buffer{}
+	g := New(b)
+	assert.Nil(t, g.Init())
+
+	err := g.Suite(tc.Config{}, &tc.Suite{Title: " Users\n"}, &emit.Results{
+		Path: "tests/users.yml",
+		Results: []*hunit.Result{
+			{Name: "GET /users", Success: true, Case: emittest.At("tests/users.yml", 1)},
+			{
+				Name:    "GET /users/1 #2\n",
+				Errors:  []string{"Unexpected status code:\n  expected: 200\n    actual: 404", "Entities do not match"},
+				Reqdata: []byte("GET /users/1 HTTP/1.1\n"),
+				Runtime: time.Microsecond * 12345,
+				Case:    emittest.At("tests/users.yml", 5),
+			},
+			{Name: "DELETE /users/1", Skipped: true, Case: emittest.At("tests/users.yml", 9)},
+		},
+	})
+	assert.Nil(t, err)
+	err = g.Suite(tc.Config{}, &tc.Suite{}, &emit.Results{
+		Path: "tests/groups.yml",
+		Results: []*hunit.Result{
+			{Name: "GET /groups"},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, g.Finalize())
+
+	// '#' is escaped in descriptions, which would otherwise begin a directive,
+	// and a suite without a title is introduced by its path
+	assert.Equal(t, `TAP version 13
+1..4
+# Users
+ok 1 - GET /users
+not ok 2 - GET /users/1 \#2
+  ---
+  message: 'Unexpected status code:'
+  severity: fail
+  file: tests/users.yml
+  line: 5
+  duration_ms: 12.345
+  errors:
+      - |-
+        Unexpected status code:
+          expected: 200
+            actual: 404
+      - Entities do not match
+  request: |
+      GET /users/1 HTTP/1.1
+  ...
+ok 3 - DELETE /users/1 # SKIP dependency failed
+# tests/groups.yml
+not ok 4 - GET /groups
+  ---
+  message: The test failed
+  severity: fail
+  duration_ms: 0
+  ...
+`, b.String())
+}
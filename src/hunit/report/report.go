@@ -5,7 +5,11 @@ import (
 	"io"
 
 	"github.com/instaunit/instaunit/hunit/report/emit"
+	"github.com/instaunit/instaunit/hunit/report/emit/github"
+	"github.com/instaunit/instaunit/hunit/report/emit/html"
+	"github.com/instaunit/instaunit/hunit/report/emit/json"
 	"github.com/instaunit/instaunit/hunit/report/emit/junit"
+	"github.com/instaunit/instaunit/hunit/report/emit/tap"
 	"github.com/instaunit/instaunit/hunit/testcase"
 )
 
@@ -21,6 +25,14 @@ func New(t emit.Doctype, o io.WriteCloser, id string) (Generator, error) {
 	switch t {
 	case emit.DoctypeJUnitXML:
 		return junit.New(o, id), nil
+	case emit.DoctypeJSON:
+		return json.New(o, id), nil
+	case emit.DoctypeHTML:
+		return html.New(o, id), nil
+	case emit.DoctypeTAP:
+		return tap.New(o), nil
+	case emit.DoctypeGithub:
+		return github.New(o), nil
 	default:
 		return nil, fmt.Errorf("Unsupported report type: %v", t)
 	}
@@ -14,11 +14,15 @@ type Result struct {
 	Success bool            `json:"success"`
 	Skipped bool            `json:"skipped"`
 	Errors  []string        `json:"errors,omitempty"`
+	Method  string          `json:"method,omitempty"` // the method of the request made, if one was made
+	URL     string          `json:"url,omitempty"`    // the URL of the request made, if one was made
+	Status  int             `json:"status,omitempty"` // the status of the response received, if one was
 	Reqdata []byte          `json:"request_data,omitempty"`
 	Rspdata []byte          `json:"response_data,omitempty"`
 	Context runtime.Context `json:"context"`
 	Runtime time.Duration   `json:"duration"`
 	Case    testcase.Case   `json:"case"`
+	Row     int             `json:"row,omitempty"` // the row of the matrix the case was run for, from 1; zero if the case is not part of a matrix
 }
 
 // Assert equality. If the values are not equal an error is added to the result.
@@ -5,6 +5,7 @@ import (
 
 	"github.com/instaunit/instaunit/hunit/doc"
 	"github.com/instaunit/instaunit/hunit/expr"
+	"github.com/instaunit/instaunit/hunit/har"
 	"github.com/instaunit/instaunit/hunit/testcase"
 )
 
@@ -18,6 +19,7 @@ type Context struct {
 	Gendoc    []doc.Generator
 	Variables expr.Variables
 	Client    *http.Client
+	HAR       *har.Recorder // records exchanges, if non-nil
 }
 
 // Derive a context from the receiver with the provided variables
@@ -36,6 +38,7 @@ func (c Context) WithVars(vars ...expr.Variables) Context {
 		Debug:     c.Debug,
 		Gendoc:    c.Gendoc,
 		Client:    c.Client,
+		HAR:       c.HAR,
 		Variables: v,
 	}
 }
@@ -0,0 +1,218 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// JSON types
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+	TypeArray   = "array"
+	TypeObject  = "object"
+	TypeNull    = "null"
+)
+
+// A set of types. Schemas may declare either a single type or, as of JSON
+// Schema draft 4 and OpenAPI 3.1, a list of types.
+type Types []string
+
+// Determine if the set contains the provided type
+func (t Types) Has(v string) bool {
+	for _, e := range t {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Marshal
+func (t Types) MarshalYAML() (interface{}, error) {
+	if len(t) == 1 {
+		return t[0], nil
+	} else {
+		return []string(t), nil
+	}
+}
+
+// Marshal
+func (t Types) MarshalJSON() ([]byte, error) {
+	v, _ := t.MarshalYAML()
+	return json.Marshal(v)
+}
+
+// Unmarshal
+func (t *Types) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*t = Types{value.Value}
+		return nil
+	case yaml.SequenceNode:
+		var v []string
+		err := value.Decode(&v)
+		if err != nil {
+			return err
+		}
+		*t = Types(v)
+		return nil
+	default:
+		return fmt.Errorf("Invalid schema type on line %d", value.Line)
+	}
+}
+
+// Additional properties are either allowed or disallowed outright or are
+// described by a schema.
+type Additional struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+// Marshal
+func (a Additional) MarshalYAML() (interface{}, error) {
+	if a.Schema != nil {
+		return a.Schema, nil
+	} else {
+		return a.Allowed, nil
+	}
+}
+
+// Marshal
+func (a Additional) MarshalJSON() ([]byte, error) {
+	v, _ := a.MarshalYAML()
+	return json.Marshal(v)
+}
+
+// Unmarshal
+func (a *Additional) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var v bool
+		err := value.Decode(&v)
+		if err != nil {
+			return err
+		}
+		*a = Additional{Allowed: v}
+		return nil
+	}
+	s := &Schema{}
+	err := value.Decode(s)
+	if err != nil {
+		return err
+	}
+	*a = Additional{Allowed: true, Schema: s}
+	return nil
+}
+
+// A schema; this is the subset of JSON Schema that is used by OpenAPI to
+// describe entities and parameters.
+type Schema struct {
+	Ref         string             `yaml:"$ref,omitempty" json:"$ref,omitempty"`
+	Title       string             `yaml:"title,omitempty" json:"title,omitempty"`
+	Description string             `yaml:"description,omitempty" json:"description,omitempty"`
+	Type        Types              `yaml:"type,omitempty" json:"type,omitempty"`
+	Format      string             `yaml:"format,omitempty" json:"format,omitempty"`
+	Nullable    bool               `yaml:"nullable,omitempty" json:"nullable,omitempty"`
+	Enum        []interface{}      `yaml:"enum,omitempty" json:"enum,omitempty"`
+	Const       interface{}        `yaml:"const,omitempty" json:"const,omitempty"`
+	Default     interface{}        `yaml:"default,omitempty" json:"default,omitempty"`
+	Example     interface{}        `yaml:"example,omitempty" json:"example,omitempty"`
+	Properties  map[string]*Schema `yaml:"properties,omitempty" json:"properties,omitempty"`
+	Required    []string           `yaml:"required,omitempty" json:"required,omitempty"`
+	Additional  *Additional        `yaml:"additionalProperties,omitempty" json:"additionalProperties,omitempty"`
+	Items       *Schema            `yaml:"items,omitempty" json:"items,omitempty"`
+	AllOf       []*Schema          `yaml:"allOf,omitempty" json:"allOf,omitempty"`
+	AnyOf       []*Schema          `yaml:"anyOf,omitempty" json:"anyOf,omitempty"`
+	OneOf       []*Schema          `yaml:"oneOf,omitempty" json:"oneOf,omitempty"`
+	Not         *Schema            `yaml:"not,omitempty" json:"not,omitempty"`
+	Minimum     *float64           `yaml:"minimum,omitempty" json:"minimum,omitempty"`
+	Maximum     *float64           `yaml:"maximum,omitempty" json:"maximum,omitempty"`
+	ExclMinimum bool               `yaml:"exclusiveMinimum,omitempty" json:"exclusiveMinimum,omitempty"`
+	ExclMaximum bool               `yaml:"exclusiveMaximum,omitempty" json:"exclusiveMaximum,omitempty"`
+	MultipleOf  *float64           `yaml:"multipleOf,omitempty" json:"multipleOf,omitempty"`
+	MinLength   *int               `yaml:"minLength,omitempty" json:"minLength,omitempty"`
+	MaxLength   *int               `yaml:"maxLength,omitempty" json:"maxLength,omitempty"`
+	Pattern     string             `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	MinItems    *int               `yaml:"minItems,omitempty" json:"minItems,omitempty"`
+	MaxItems    *int               `yaml:"maxItems,omitempty" json:"maxItems,omitempty"`
+	UniqueItems bool               `yaml:"uniqueItems,omitempty" json:"uniqueItems,omitempty"`
+	MinProps    *int               `yaml:"minProperties,omitempty" json:"minProperties,omitempty"`
+	MaxProps    *int               `yaml:"maxProperties,omitempty" json:"maxProperties,omitempty"`
+}
+
+// Unmarshal. Exclusive bounds are booleans which qualify 'minimum' and
+// 'maximum' in OpenAPI 3.0, and the bounds themselves in OpenAPI 3.1 and later
+// drafts of JSON Schema; both forms are supported. A numeric bound is described
+// as the corresponding minimum or maximum, unless that is already stricter.
+func (s *Schema) UnmarshalYAML(value *yaml.Node) error {
+	type plain Schema
+	if value.Kind != yaml.MappingNode {
+		return value.Decode((*plain)(s))
+	}
+
+	node := *value
+	node.Content = nil
+	var excl []*yaml.Node // key, value pairs
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		k, v := value.Content[i], value.Content[i+1]
+		if (k.Value == "exclusiveMinimum" || k.Value == "exclusiveMaximum") && v.Kind == yaml.ScalarNode && v.Tag != "!!bool" {
+			excl = append(excl, k, v)
+		} else {
+			node.Content = append(node.Content, k, v)
+		}
+	}
+	err := node.Decode((*plain)(s))
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(excl); i += 2 {
+		var b float64
+		err = excl[i+1].Decode(&b)
+		if err != nil {
+			return fmt.Errorf("Invalid %s on line %d: %w", excl[i].Value, excl[i+1].Line, err)
+		}
+		if excl[i].Value == "exclusiveMinimum" {
+			if s.Minimum == nil || *s.Minimum <= b {
+				s.Minimum, s.ExclMinimum = &b, true
+			}
+		} else {
+			if s.Maximum == nil || *s.Maximum >= b {
+				s.Maximum, s.ExclMaximum = &b, true
+			}
+		}
+	}
+	return nil
+}
+
+// Determine if the schema permits the provided type. A schema which declares
+// no type permits any type.
+func (s *Schema) Permits(t string) bool {
+	if len(s.Type) == 0 {
+		return true
+	}
+	if s.Type.Has(t) {
+		return true
+	}
+	if t == TypeNull && s.Nullable {
+		return true
+	}
+	if t == TypeInteger && s.Type.Has(TypeNumber) {
+		return true
+	}
+	return false
+}
+
+// Determine if a property is required by the schema
+func (s *Schema) IsRequired(p string) bool {
+	for _, e := range s.Required {
+		if e == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const testSchema = `
+type: object
+required: [id, name]
+additionalProperties: false
+properties:
+  id:
+    type: string
+    format: uuid
+  name:
+    type: string
+    minLength: 1
+  age:
+    type: integer
+    minimum: 0
+  tags:
+    type: array
+    maxItems: 2
+    items:
+      type: string
+      enum: [a, b, c]
+  parent:
+    type: [string, "null"]
+`
+
+func loadSchema(t *testing.T, src string) *Schema {
+	s := &Schema{}
+	err := yaml.Unmarshal([]byte(src), s)
+	if !assert.Nil(t, err, fmt.Sprint(err)) {
+		t.FailNow()
+	}
+	return s
+}
+
+func TestValidate(t *testing.T) {
+	s := loadSchema(t, testSchema)
+	tests := []struct {
+		Value  string
+		Expect []string
+	}{
+		{
+			`{"id": "3fa85f64-5717-4562-b3fc-2c963f66afa6", "name": "Joe"}`,
+			nil,
+		},
+		{
+			`{"id": "3fa85f64-5717-4562-b3fc-2c963f66afa6", "name": "Joe", "age": 30, "tags": ["a", "b"], "parent": null}`,
+			nil,
+		},
+		{
+			`{"name": ""}`,
+			[]string{"id: property is required", "name: length must be at least 1"},
+		},
+		{
+			`{"id": "nope", "name": "Joe", "age": 1.5, "tags": ["a", "b", "z"], "other": true}`,
+			[]string{
+				"age: expected integer, got number",
+				"id: value is not a valid uuid",
+				"other: property is not permitted",
+				"tags: must contain at most 2 items",
+				"tags[2]: value must be one of: [a b c]",
+			},
+		},
+		{
+			`[]`,
+			[]string{"expected object, got array"},
+		},
+	}
+	for _, e := range tests {
+		var v interface{}
+		err := json.Unmarshal([]byte(e.Value), &v)
+		if !assert.Nil(t, err, fmt.Sprint(err)) {
+			continue
+		}
+		err = s.Validate(v)
+		if e.Expect == nil {
+			assert.Nil(t, err, e.Value)
+		} else if assert.NotNil(t, err, e.Value) {
+			var msgs []string
+			for _, x := range err.(ValidationErrors) {
+				msgs = append(msgs, x.Error())
+			}
+			assert.Equal(t, e.Expect, msgs, e.Value)
+		}
+	}
+}
+
+func TestSynthesize(t *testing.T) {
+	s := loadSchema(t, testSchema)
+	v := s.Synthesize()
+	// synthesized values always conform to the schema they're derived from
+	assert.Nil(t, s.Validate(roundtrip(t, v)))
+	assert.Equal(t, map[string]interface{}{
+		"id":     "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+		"name":   "string",
+		"age":    int64(0),
+		"tags":   []interface{}{"a"},
+		"parent": "string",
+	}, v)
+}
+
+// Values are validated in the form they take after being decoded from JSON
+func roundtrip(t *testing.T, v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if !assert.Nil(t, err, fmt.Sprint(err)) {
+		t.FailNow()
+	}
+	var d interface{}
+	err = json.Unmarshal(data, &d)
+	if !assert.Nil(t, err, fmt.Sprint(err)) {
+		t.FailNow()
+	}
+	return d
+}
+
+func TestExclusiveBounds(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		Source      string
+		Min, Max    *float64
+		ExclMinimum bool
+		ExclMaximum bool
+		Valid       []float64
+		Invalid     []float64
+	}{
+		{ // OpenAPI 3.0
+			"{type: number, minimum: 0, exclusiveMinimum: true, maximum: 10, exclusiveMaximum: false}",
+			f(0), f(10), true, false,
+			[]float64{1, 10},
+			[]float64{0, 11},
+		},
+		{ // OpenAPI 3.1
+			"{type: number, exclusiveMinimum: 0, exclusiveMaximum: 10.5}",
+			f(0), f(10.5), true, true,
+			[]float64{1, 10},
+			[]float64{0, 10.5},
+		},
+		{ // a stricter inclusive bound is retained
+			"{type: number, minimum: 5, exclusiveMinimum: 0, maximum: 10, exclusiveMaximum: 10}",
+			f(5), f(10), false, true,
+			[]float64{5},
+			[]float64{4, 10},
+		},
+	}
+	for _, e := range tests {
+		s := loadSchema(t, e.Source)
+		assert.Equal(t, e.Min, s.Minimum, e.Source)
+		assert.Equal(t, e.Max, s.Maximum, e.Source)
+		assert.Equal(t, e.ExclMinimum, s.ExclMinimum, e.Source)
+		assert.Equal(t, e.ExclMaximum, s.ExclMaximum, e.Source)
+		for _, v := range e.Valid {
+			assert.Nil(t, s.Validate(v), "%s: %v", e.Source, v)
+		}
+		for _, v := range e.Invalid {
+			assert.NotNil(t, s.Validate(v), "%s: %v", e.Source, v)
+		}
+	}
+
+	err := yaml.Unmarshal([]byte("{exclusiveMinimum: nope}"), &Schema{})
+	assert.NotNil(t, err)
+}
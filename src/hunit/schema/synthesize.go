@@ -0,0 +1,173 @@
+package schema
+
+import (
+	"strings"
+)
+
+// Representative values for well-known string formats
+var formatSpecimens = map[string]string{
+	"date-time": "2006-01-02T15:04:05Z",
+	"date":      "2006-01-02",
+	"time":      "15:04:05",
+	"email":     "user@example.com",
+	"uri":       "https://example.com/",
+	"url":       "https://example.com/",
+	"hostname":  "example.com",
+	"ipv4":      "192.0.2.1",
+	"ipv6":      "2001:db8::1",
+	"uuid":      "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+	"byte":      "c3RyaW5n",
+	"password":  "password",
+}
+
+// Synthesize a value that conforms to the schema. Examples, defaults and
+// enumerations are preferred when the schema declares them; otherwise a
+// representative value is produced for the schema's type. The result is
+// deterministic, so that responses are stable between runs.
+func (s *Schema) Synthesize() interface{} {
+	return s.synthesize(0)
+}
+
+// Recursive schemas are cut off at a reasonable depth
+const maxSynthesisDepth = 8
+
+func (s *Schema) synthesize(depth int) interface{} {
+	if s == nil || depth > maxSynthesisDepth {
+		return nil
+	}
+	if s.Example != nil {
+		return s.Example
+	}
+	if s.Default != nil {
+		return s.Default
+	}
+	if s.Const != nil {
+		return s.Const
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[0]
+	}
+	if len(s.AllOf) > 0 {
+		return s.synthesizeAll(depth)
+	}
+	if len(s.OneOf) > 0 {
+		return s.OneOf[0].synthesize(depth + 1)
+	}
+	if len(s.AnyOf) > 0 {
+		return s.AnyOf[0].synthesize(depth + 1)
+	}
+
+	var t string
+	for _, e := range s.Type {
+		if e != TypeNull {
+			t = e
+			break
+		}
+	}
+	if t == "" {
+		switch {
+		case s.Properties != nil || s.Additional != nil:
+			t = TypeObject
+		case s.Items != nil:
+			t = TypeArray
+		default:
+			t = TypeString
+		}
+	}
+
+	switch t {
+	case TypeString:
+		return s.synthesizeString()
+	case TypeInteger:
+		return int64(s.synthesizeNumber())
+	case TypeNumber:
+		return s.synthesizeNumber()
+	case TypeBoolean:
+		return true
+	case TypeArray:
+		n := 1
+		if s.MinItems != nil && *s.MinItems > n {
+			n = *s.MinItems
+		}
+		if s.MaxItems != nil && *s.MaxItems < n {
+			n = *s.MaxItems
+		}
+		v := make([]interface{}, n)
+		for i := range v {
+			v[i] = s.Items.synthesize(depth + 1)
+		}
+		return v
+	case TypeObject:
+		v := make(map[string]interface{})
+		for k, e := range s.Properties {
+			v[k] = e.synthesize(depth + 1)
+		}
+		return v
+	default:
+		return nil
+	}
+}
+
+// Synthesize the union of every schema in an allOf composition
+func (s *Schema) synthesizeAll(depth int) interface{} {
+	var obj map[string]interface{}
+	var last interface{}
+	for _, e := range s.AllOf {
+		v := e.synthesize(depth + 1)
+		if m, ok := v.(map[string]interface{}); ok {
+			if obj == nil {
+				obj = make(map[string]interface{})
+			}
+			for k, x := range m {
+				obj[k] = x
+			}
+		} else if v != nil {
+			last = v
+		}
+	}
+	for k, e := range s.Properties {
+		if obj == nil {
+			obj = make(map[string]interface{})
+		}
+		obj[k] = e.synthesize(depth + 1)
+	}
+	if obj != nil {
+		return obj
+	}
+	return last
+}
+
+func (s *Schema) synthesizeString() string {
+	v, ok := formatSpecimens[s.Format]
+	if !ok {
+		v = "string"
+	}
+	if s.MinLength != nil && len(v) < *s.MinLength {
+		v += strings.Repeat("x", *s.MinLength-len(v))
+	}
+	if s.MaxLength != nil && len(v) > *s.MaxLength {
+		v = v[:*s.MaxLength]
+	}
+	return v
+}
+
+func (s *Schema) synthesizeNumber() float64 {
+	var v float64
+	if m := s.Minimum; m != nil {
+		v = *m
+		if s.ExclMinimum {
+			v++
+		}
+	} else if m := s.Maximum; m != nil && *m < 0 {
+		v = *m
+		if s.ExclMaximum {
+			v--
+		}
+	}
+	if m := s.MultipleOf; m != nil && *m != 0 {
+		if r := v / *m; r != float64(int64(r)) {
+			v = float64(int64(r)+1) * *m
+		}
+	}
+	return v
+}
@@ -0,0 +1,324 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/instaunit/instaunit/hunit/keys"
+)
+
+// A validation error describes a single way in which a value does not conform
+// to a schema.
+type ValidationError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Path != "" {
+		return fmt.Sprintf("%s: %s", e.Path, e.Message)
+	} else {
+		return e.Message
+	}
+}
+
+// A set of validation errors
+type ValidationErrors []ValidationError
+
+func (errs ValidationErrors) Error() string {
+	switch len(errs) {
+	case 0:
+		return "No error"
+	case 1:
+		return errs[0].Error()
+	}
+	b := &strings.Builder{}
+	b.WriteString(fmt.Sprintf("%d validation errors:", len(errs)))
+	for _, e := range errs {
+		b.WriteString("\n  - ")
+		b.WriteString(e.Error())
+	}
+	return b.String()
+}
+
+// Validate a value against a schema. The value is expected to be in the form
+// produced by unmarshaling JSON into an interface{}. Every violation that can
+// be identified is reported. If the value conforms, nil is returned.
+func (s *Schema) Validate(v interface{}) error {
+	return s.ValidateAt("", v)
+}
+
+// Validate a value against a schema. Errors are reported relative to the
+// provided path, which describes where the value was found.
+func (s *Schema) ValidateAt(p string, v interface{}) error {
+	errs := s.validate(p, v, nil)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Validate at a path
+func (s *Schema) validate(p string, v interface{}, errs ValidationErrors) ValidationErrors {
+	if s == nil {
+		return errs
+	}
+
+	t := TypeOf(v)
+	if !s.Permits(t) {
+		return append(errs, ValidationError{p, fmt.Sprintf("expected %s, got %s", strings.Join(s.Type, " or "), t)})
+	}
+
+	if len(s.Enum) > 0 {
+		var found bool
+		for _, e := range s.Enum {
+			if valuesEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, ValidationError{p, fmt.Sprintf("value must be one of: %v", s.Enum)})
+		}
+	}
+	if s.Const != nil && !valuesEqual(s.Const, v) {
+		errs = append(errs, ValidationError{p, fmt.Sprintf("value must be: %v", s.Const)})
+	}
+
+	switch c := v.(type) {
+	case string:
+		errs = s.validateString(p, c, errs)
+	case []interface{}:
+		errs = s.validateArray(p, c, errs)
+	case map[string]interface{}:
+		errs = s.validateObject(p, c, errs)
+	default:
+		if f, ok := toFloat(v); ok {
+			errs = s.validateNumber(p, f, errs)
+		}
+	}
+
+	for _, e := range s.AllOf {
+		errs = e.validate(p, v, errs)
+	}
+	if len(s.AnyOf) > 0 {
+		var n int
+		for _, e := range s.AnyOf {
+			if len(e.validate(p, v, nil)) == 0 {
+				n++
+				break
+			}
+		}
+		if n == 0 {
+			errs = append(errs, ValidationError{p, "value does not match any permitted schema"})
+		}
+	}
+	if len(s.OneOf) > 0 {
+		var n int
+		for _, e := range s.OneOf {
+			if len(e.validate(p, v, nil)) == 0 {
+				n++
+			}
+		}
+		if n != 1 {
+			errs = append(errs, ValidationError{p, fmt.Sprintf("value must match exactly one schema; it matches %d", n)})
+		}
+	}
+	if s.Not != nil && len(s.Not.validate(p, v, nil)) == 0 {
+		errs = append(errs, ValidationError{p, "value matches a prohibited schema"})
+	}
+
+	return errs
+}
+
+func (s *Schema) validateString(p string, v string, errs ValidationErrors) ValidationErrors {
+	n := utf8.RuneCountInString(v)
+	if s.MinLength != nil && n < *s.MinLength {
+		errs = append(errs, ValidationError{p, fmt.Sprintf("length must be at least %d", *s.MinLength)})
+	}
+	if s.MaxLength != nil && n > *s.MaxLength {
+		errs = append(errs, ValidationError{p, fmt.Sprintf("length must be at most %d", *s.MaxLength)})
+	}
+	if s.Pattern != "" {
+		m, err := regexp.MatchString(s.Pattern, v)
+		if err != nil {
+			errs = append(errs, ValidationError{p, fmt.Sprintf("invalid pattern: %v", err)})
+		} else if !m {
+			errs = append(errs, ValidationError{p, fmt.Sprintf("value must match pattern: %s", s.Pattern)})
+		}
+	}
+	if s.Format != "" && !validFormat(s.Format, v) {
+		errs = append(errs, ValidationError{p, fmt.Sprintf("value is not a valid %s", s.Format)})
+	}
+	return errs
+}
+
+func (s *Schema) validateNumber(p string, v float64, errs ValidationErrors) ValidationErrors {
+	if m := s.Minimum; m != nil {
+		if v < *m || (s.ExclMinimum && v == *m) {
+			errs = append(errs, ValidationError{p, fmt.Sprintf("value must be greater than %s%v", orEqual(!s.ExclMinimum), *m)})
+		}
+	}
+	if m := s.Maximum; m != nil {
+		if v > *m || (s.ExclMaximum && v == *m) {
+			errs = append(errs, ValidationError{p, fmt.Sprintf("value must be less than %s%v", orEqual(!s.ExclMaximum), *m)})
+		}
+	}
+	if m := s.MultipleOf; m != nil && *m != 0 {
+		if r := math.Mod(v, *m); math.Abs(r) > 1e-9 && math.Abs(r-*m) > 1e-9 {
+			errs = append(errs, ValidationError{p, fmt.Sprintf("value must be a multiple of %v", *m)})
+		}
+	}
+	return errs
+}
+
+func (s *Schema) validateArray(p string, v []interface{}, errs ValidationErrors) ValidationErrors {
+	if s.MinItems != nil && len(v) < *s.MinItems {
+		errs = append(errs, ValidationError{p, fmt.Sprintf("must contain at least %d items", *s.MinItems)})
+	}
+	if s.MaxItems != nil && len(v) > *s.MaxItems {
+		errs = append(errs, ValidationError{p, fmt.Sprintf("must contain at most %d items", *s.MaxItems)})
+	}
+	if s.UniqueItems {
+		for i := 0; i < len(v); i++ {
+			for j := i + 1; j < len(v); j++ {
+				if valuesEqual(v[i], v[j]) {
+					errs = append(errs, ValidationError{p, fmt.Sprintf("items must be unique; #%d and #%d are equal", i, j)})
+				}
+			}
+		}
+	}
+	if s.Items != nil {
+		for i, e := range v {
+			errs = s.Items.validate(fmt.Sprintf("%s[%d]", p, i), e, errs)
+		}
+	}
+	return errs
+}
+
+func (s *Schema) validateObject(p string, v map[string]interface{}, errs ValidationErrors) ValidationErrors {
+	if s.MinProps != nil && len(v) < *s.MinProps {
+		errs = append(errs, ValidationError{p, fmt.Sprintf("must contain at least %d properties", *s.MinProps)})
+	}
+	if s.MaxProps != nil && len(v) > *s.MaxProps {
+		errs = append(errs, ValidationError{p, fmt.Sprintf("must contain at most %d properties", *s.MaxProps)})
+	}
+	for _, e := range s.Required {
+		if _, ok := v[e]; !ok {
+			errs = append(errs, ValidationError{joinPath(p, e), "property is required"})
+		}
+	}
+	for _, k := range keys.Sorted(v) {
+		e := v[k]
+		if x, ok := s.Properties[k]; ok {
+			errs = x.validate(joinPath(p, k), e, errs)
+		} else if a := s.Additional; a != nil {
+			if a.Schema != nil {
+				errs = a.Schema.validate(joinPath(p, k), e, errs)
+			} else if !a.Allowed {
+				errs = append(errs, ValidationError{joinPath(p, k), "property is not permitted"})
+			}
+		}
+	}
+	return errs
+}
+
+// Determine the JSON type of a value
+func TypeOf(v interface{}) string {
+	switch c := v.(type) {
+	case nil:
+		return TypeNull
+	case bool:
+		return TypeBoolean
+	case string:
+		return TypeString
+	case float64:
+		if c == math.Trunc(c) && !math.IsInf(c, 0) {
+			return TypeInteger
+		} else {
+			return TypeNumber
+		}
+	case float32:
+		return TypeOf(float64(c))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return TypeInteger
+	case []interface{}:
+		return TypeArray
+	case map[string]interface{}:
+		return TypeObject
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// Validate well-known formats; unknown formats are always valid
+func validFormat(f, v string) bool {
+	switch f {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, v)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", v)
+		return err == nil
+	case "email":
+		_, err := mail.ParseAddress(v)
+		return err == nil
+	case "uri", "url":
+		u, err := url.Parse(v)
+		return err == nil && u.Scheme != ""
+	case "uuid":
+		return uuidPattern.MatchString(v)
+	default:
+		return true
+	}
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Compare values; numeric values are compared without regard to their type
+// since values decoded from YAML and JSON are represented differently.
+func valuesEqual(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			return x == y
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	r := reflect.ValueOf(v)
+	switch r.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(r.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(r.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return r.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+func orEqual(v bool) string {
+	if v {
+		return "or equal to "
+	} else {
+		return ""
+	}
+}
+
+func joinPath(p, k string) string {
+	if p == "" {
+		return k
+	} else {
+		return p + "." + k
+	}
+}
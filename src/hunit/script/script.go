@@ -1,9 +1,11 @@
 package script
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/instaunit/instaunit/hunit/expr"
 
@@ -11,6 +13,8 @@ import (
 	"github.com/robertkrimen/otto"
 )
 
+var ErrTimeout = errors.New("Script did not complete in time")
+
 type InvalidTypeError struct {
 	source string
 	expect string
@@ -43,12 +47,20 @@ func (s Script) Bool(v expr.Variables) (bool, error) {
 }
 
 func (s Script) Eval(v expr.Variables) (interface{}, error) {
+	return s.EvalWithin(v, 0)
+}
+
+// Evaluate the script, giving up if it does not complete within the timeout,
+// in which case ErrTimeout is returned. If the timeout is zero, evaluation is
+// not bounded. Only JavaScript can be interrupted; expressions always run to
+// completion.
+func (s Script) EvalWithin(v expr.Variables, timeout time.Duration) (interface{}, error) {
 	cxt := expr.RuntimeContext(v, os.Environ())
 	switch strings.ToLower(s.Type) {
 	case "epl", "":
 		return s.evalEPL(cxt)
 	case "js", "javascript":
-		return s.evalJS(cxt)
+		return s.evalJS(cxt, timeout)
 	default:
 		return false, fmt.Errorf("Unsupported script type: %v", s.Type)
 	}
@@ -66,12 +78,30 @@ func (s Script) evalEPL(cxt expr.Variables) (interface{}, error) {
 	return res, nil
 }
 
-func (s Script) evalJS(cxt expr.Variables) (interface{}, error) {
+// Panicked by the VM to halt a script that has run out of time
+type halt struct{}
+
+func (s Script) evalJS(cxt expr.Variables, timeout time.Duration) (res interface{}, err error) {
 	vm := otto.New()
 	for k, v := range cxt {
 		vm.Set(k, v)
 	}
-	res, err := vm.Run(s.Source)
+	if timeout > 0 {
+		vm.Interrupt = make(chan func(), 1)
+		t := time.AfterFunc(timeout, func() {
+			vm.Interrupt <- func() { panic(halt{}) }
+		})
+		defer t.Stop()
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(halt); !ok {
+					panic(r)
+				}
+				res, err = nil, ErrTimeout
+			}
+		}()
+	}
+	res, err = vm.Run(s.Source)
 	if err != nil {
 		return nil, err
 	}
@@ -3,8 +3,10 @@ package script
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/instaunit/instaunit/hunit/expr"
+	"github.com/robertkrimen/otto"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -119,3 +121,21 @@ func TestScripts(t *testing.T) {
 		}
 	}
 }
+
+func TestEvalWithin(t *testing.T) {
+	start := time.Now()
+	_, err := Script{"js", `while (true) {}`}.EvalWithin(context, time.Millisecond*50)
+	assert.Equal(t, ErrTimeout, err)
+	assert.Less(t, time.Since(start), time.Second)
+
+	res, err := Script{"js", `a + 1`}.EvalWithin(context, time.Second)
+	if assert.Nil(t, err) {
+		v, err := res.(otto.Value).Export()
+		assert.Nil(t, err)
+		assert.EqualValues(t, 124, v)
+	}
+
+	_, err = Script{"js", `throw new Error("Nope")`}.EvalWithin(context, time.Second)
+	assert.NotNil(t, err)
+	assert.NotEqual(t, ErrTimeout, err)
+}
@@ -0,0 +1,447 @@
+package admin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit/service"
+
+	"github.com/bww/go-router/v2"
+	"github.com/bww/go-util/v1/debug"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// The admin API is routed under this path on every service. Backends must not
+// allow it to be shadowed by their own routes.
+const Path = "/_instaunit/admin"
+
+const prefix = "[admin]"
+
+// Backends that support reconfiguring their endpoints at runtime implement
+// this interface. Endpoints are provided in the same format as the resource
+// the service was created from.
+type Configurable interface {
+	// Add endpoints; those that are equivalent to an existing endpoint replace it
+	AddEndpoints(data []byte) (int, error)
+	// Replace every endpoint
+	SetEndpoints(data []byte) (int, error)
+	// Produce the current endpoints
+	Endpoints() ([]byte, error)
+	// Restore the endpoints the service was created with
+	ResetEndpoints() error
+}
+
+// A service's admin API. Every request the service receives is recorded and
+// may be subjected to a fault before it is handled.
+type Admin struct {
+	sync.Mutex
+	backend Configurable // may be nil
+	router  router.Router
+	calls   []*Call
+	faults  []*Fault
+	nextId  int
+}
+
+// Create an admin API. The backend is optional; if it is nil endpoints
+// cannot be reconfigured.
+func New(backend Configurable) *Admin {
+	a := &Admin{backend: backend}
+	r := router.New()
+	r.Add(Path+"/requests", a.listCalls).Methods("GET")
+	r.Add(Path+"/requests", a.resetCalls).Methods("DELETE")
+	r.Add(Path+"/faults", a.listFaults).Methods("GET")
+	r.Add(Path+"/faults", a.addFault).Methods("POST")
+	r.Add(Path+"/faults", a.resetFaults).Methods("DELETE")
+	r.Add(Path+"/faults/{id}", a.removeFault).Methods("DELETE")
+	r.Add(Path+"/endpoints", a.listEndpoints).Methods("GET")
+	r.Add(Path+"/endpoints", a.addEndpoints).Methods("POST")
+	r.Add(Path+"/endpoints", a.setEndpoints).Methods("PUT")
+	r.Add(Path+"/reset", a.reset).Methods("POST")
+	a.router = r
+	return a
+}
+
+// Wrap a service handler. Admin requests are handled directly; every other
+// request is recorded, subjected to any matching fault, and then handled.
+func (a *Admin) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == Path || strings.HasPrefix(req.URL.Path, Path+"/") {
+			a.routeRequest(rsp, req)
+			return
+		}
+		if req.URL.Path == service.StatusPath {
+			next.ServeHTTP(rsp, req) // monitoring is not a call to the service
+			return
+		}
+
+		call, err := a.record(req)
+		if err != nil {
+			fmt.Printf("%s * * * Could not record request: %v: %v\n", prefix, req.URL, err)
+			http.Error(rsp, "Could not read request", http.StatusInternalServerError)
+			return
+		}
+
+		w := &recorder{ResponseWriter: rsp}
+		var fault string
+		if f := a.fault(req); f != nil {
+			fault = f.Id
+			a.inject(w, req, f)
+		} else {
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), callKey{}, callRef{a, call})))
+		}
+
+		a.Lock()
+		call.Status, call.Fault = w.Status(), fault
+		a.Unlock()
+	})
+}
+
+type callKey struct{}
+
+type callRef struct {
+	admin *Admin
+	call  *Call
+}
+
+// Note problems with a request in its call history. Backends use this to flag
+// requests which they handled but which were not what they expected. If the
+// request did not pass through an admin handler this does nothing.
+func Flag(req *http.Request, problems ...string) {
+	ref, ok := req.Context().Value(callKey{}).(callRef)
+	if !ok {
+		return
+	}
+	ref.admin.Lock()
+	defer ref.admin.Unlock()
+	ref.call.Errors = append(ref.call.Errors, problems...)
+}
+
+// Record a request
+func (a *Admin) record(req *http.Request) (*Call, error) {
+	var data []byte
+	if req.Body != nil {
+		var err error
+		data, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(data))
+	}
+
+	headers := make(map[string]string)
+	for k, v := range req.Header {
+		if len(v) > 0 {
+			headers[k] = v[0]
+		}
+	}
+
+	call := &Call{
+		Time:    time.Now(),
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Query:   req.URL.RawQuery,
+		Headers: headers,
+		Entity:  string(data),
+	}
+
+	a.Lock()
+	a.calls = append(a.calls, call)
+	a.Unlock()
+	return call, nil
+}
+
+// Find the first fault which applies to a request, if any
+func (a *Admin) fault(req *http.Request) *Fault {
+	a.Lock()
+	defer a.Unlock()
+	for i, e := range a.faults {
+		if !e.Matches(req) {
+			continue
+		}
+		if e.Count > 0 {
+			e.Count--
+			if e.Count == 0 {
+				a.faults = append(a.faults[:i], a.faults[i+1:]...)
+			}
+		}
+		return e
+	}
+	return nil
+}
+
+// Inject a fault
+func (a *Admin) inject(rsp *recorder, req *http.Request, f *Fault) {
+	if debug.VERBOSE {
+		fmt.Printf("%s !! Fault %s: %s %s\n", prefix, f.Id, req.Method, req.URL.Path)
+	}
+	if f.Delay > 0 {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-time.After(f.Delay):
+		}
+	}
+	if f.Abort {
+		if conn, _, err := http.NewResponseController(rsp.ResponseWriter).Hijack(); err == nil {
+			conn.Close()
+			return
+		}
+		panic(http.ErrAbortHandler) // the connection can't be hijacked; abort the request
+	}
+	for k, v := range f.Headers {
+		rsp.Header().Set(k, v)
+	}
+	status := f.Status
+	if status == 0 {
+		status = http.StatusServiceUnavailable
+	}
+	rsp.WriteHeader(status)
+	if f.Entity != "" {
+		io.WriteString(rsp, f.Entity)
+	}
+}
+
+// Handle an admin request
+func (a *Admin) routeRequest(rsp http.ResponseWriter, req *http.Request) {
+	res, err := a.router.Handle((*router.Request)(req))
+	if err != nil {
+		fmt.Printf("%s * * * Could not handle request: %v: %v\n", prefix, req.URL, err)
+		res, _ = errorResponse(http.StatusInternalServerError, err)
+	}
+	for k, v := range res.Header {
+		rsp.Header().Set(k, v[0])
+	}
+	if res.Status != 0 {
+		rsp.WriteHeader(res.Status)
+	} else {
+		rsp.WriteHeader(http.StatusOK)
+	}
+	if e := res.Entity; e != nil {
+		defer e.Close()
+		io.Copy(rsp, e)
+	}
+}
+
+// List recorded calls, optionally filtered by method and path
+func (a *Admin) listCalls(req *router.Request, cxt router.Context) (*router.Response, error) {
+	query := req.URL.Query()
+	method, path := query.Get("method"), query.Get("path")
+
+	a.Lock()
+	calls := make([]Call, 0, len(a.calls))
+	for _, e := range a.calls {
+		if (method == "" || strings.EqualFold(method, e.Method)) && (path == "" || path == e.Path) {
+			calls = append(calls, *e)
+		}
+	}
+	a.Unlock()
+
+	return router.NewResponse(http.StatusOK).SetJSON(calls)
+}
+
+// Discard recorded calls
+func (a *Admin) resetCalls(req *router.Request, cxt router.Context) (*router.Response, error) {
+	a.Lock()
+	a.calls = nil
+	a.Unlock()
+	return router.NewResponse(http.StatusNoContent), nil
+}
+
+// List active faults
+func (a *Admin) listFaults(req *router.Request, cxt router.Context) (*router.Response, error) {
+	a.Lock()
+	faults := make([]Fault, len(a.faults))
+	for i, e := range a.faults {
+		faults[i] = *e
+	}
+	a.Unlock()
+	return router.NewResponse(http.StatusOK).SetJSON(faults)
+}
+
+// Add a fault
+func (a *Admin) addFault(req *router.Request, cxt router.Context) (*router.Response, error) {
+	var f Fault
+	err := decode(req.Body, &f)
+	if err != nil {
+		return errorResponse(http.StatusBadRequest, err)
+	}
+	err = f.Validate()
+	if err != nil {
+		return errorResponse(http.StatusBadRequest, err)
+	}
+
+	a.Lock()
+	a.nextId++
+	f.Id = strconv.Itoa(a.nextId)
+	a.faults = append(a.faults, &f)
+	a.Unlock()
+
+	if debug.VERBOSE {
+		fmt.Printf("%s -- Added fault %s: %v\n", prefix, f.Id, f)
+	}
+	return router.NewResponse(http.StatusCreated).SetJSON(f)
+}
+
+// Remove a fault
+func (a *Admin) removeFault(req *router.Request, cxt router.Context) (*router.Response, error) {
+	id := cxt.Vars["id"]
+	a.Lock()
+	defer a.Unlock()
+	for i, e := range a.faults {
+		if e.Id == id {
+			a.faults = append(a.faults[:i], a.faults[i+1:]...)
+			return router.NewResponse(http.StatusNoContent), nil
+		}
+	}
+	return errorResponse(http.StatusNotFound, fmt.Errorf("No such fault: %s", id))
+}
+
+// Remove every fault
+func (a *Admin) resetFaults(req *router.Request, cxt router.Context) (*router.Response, error) {
+	a.Lock()
+	a.faults = nil
+	a.Unlock()
+	return router.NewResponse(http.StatusNoContent), nil
+}
+
+// Produce the current endpoints
+func (a *Admin) listEndpoints(req *router.Request, cxt router.Context) (*router.Response, error) {
+	if a.backend == nil {
+		return errorResponse(http.StatusNotImplemented, fmt.Errorf("This service does not support reconfiguring endpoints"))
+	}
+	data, err := a.backend.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	return router.NewResponse(http.StatusOK).SetBytes("application/yaml", data)
+}
+
+// Add or replace endpoints
+func (a *Admin) addEndpoints(req *router.Request, cxt router.Context) (*router.Response, error) {
+	return a.updateEndpoints(req, false)
+}
+
+// Replace every endpoint
+func (a *Admin) setEndpoints(req *router.Request, cxt router.Context) (*router.Response, error) {
+	return a.updateEndpoints(req, true)
+}
+
+func (a *Admin) updateEndpoints(req *router.Request, replace bool) (*router.Response, error) {
+	if a.backend == nil {
+		return errorResponse(http.StatusNotImplemented, fmt.Errorf("This service does not support reconfiguring endpoints"))
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	var n int
+	if replace {
+		n, err = a.backend.SetEndpoints(data)
+	} else {
+		n, err = a.backend.AddEndpoints(data)
+	}
+	if err != nil {
+		return errorResponse(http.StatusBadRequest, err)
+	}
+	if debug.VERBOSE {
+		fmt.Printf("%s -- Updated %d endpoints\n", prefix, n)
+	}
+	return router.NewResponse(http.StatusOK).SetJSON(struct {
+		Updated int `json:"updated"`
+	}{n})
+}
+
+// Reset the service to the state it started in: recorded calls and faults
+// are discarded and the original endpoints are restored.
+func (a *Admin) reset(req *router.Request, cxt router.Context) (*router.Response, error) {
+	a.Lock()
+	a.calls, a.faults = nil, nil
+	a.Unlock()
+	if a.backend != nil {
+		err := a.backend.ResetEndpoints()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if debug.VERBOSE {
+		fmt.Printf("%s -- Reset service\n", prefix)
+	}
+	return router.NewResponse(http.StatusNoContent), nil
+}
+
+// Decode a request entity; JSON is valid YAML, so we accept either
+func decode(src io.Reader, dst interface{}) error {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err = dec.Decode(dst)
+	if err == io.EOF {
+		return fmt.Errorf("Request entity is empty")
+	}
+	return err
+}
+
+func errorResponse(status int, err error) (*router.Response, error) {
+	return router.NewResponse(status).SetJSON(struct {
+		Message string `json:"message"`
+	}{err.Error()})
+}
+
+// Response recorder; this retains the status written and allows the
+// connection to be hijacked, which is required for websockets.
+type recorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) Status() int {
+	return r.status
+}
+
+func (r *recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil && r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Describe a value as JSON; this is used for logging
+func describe(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,75 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdmin(t *testing.T) {
+	a := New(nil)
+	srv := httptest.NewServer(a.Handler(http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/a" {
+			Flag(req, "unexpected")
+		}
+		rsp.WriteHeader(http.StatusOK)
+		io.WriteString(rsp, "ok")
+	})))
+	defer srv.Close()
+
+	send := func(method, path, entity string) (int, string) {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(entity))
+		if !assert.Nil(t, err) {
+			return 0, ""
+		}
+		rsp, err := http.DefaultClient.Do(req)
+		if !assert.Nil(t, err) {
+			return 0, ""
+		}
+		defer rsp.Body.Close()
+		data, err := io.ReadAll(rsp.Body)
+		assert.Nil(t, err)
+		return rsp.StatusCode, string(data)
+	}
+
+	status, _ := send("GET", "/a", "")
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = send("POST", Path+"/faults", `{"methods": ["GET"], "path": "/b/*", "status": 502, "entity": "bad", "count": 1}`)
+	assert.Equal(t, http.StatusCreated, status)
+
+	status, entity := send("GET", "/b/1", "")
+	assert.Equal(t, http.StatusBadGateway, status)
+	assert.Equal(t, "bad", entity)
+	status, _ = send("GET", "/b/1", "") // the fault has been used up
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = send("POST", "/b/1", "Hello")
+	assert.Equal(t, http.StatusOK, status)
+
+	status, entity = send("GET", Path+"/requests", "")
+	assert.Equal(t, http.StatusOK, status)
+	var calls []Call
+	if assert.Nil(t, json.Unmarshal([]byte(entity), &calls)) && assert.Len(t, calls, 4) {
+		assert.Equal(t, "/a", calls[0].Path)
+		assert.Equal(t, []string{"unexpected"}, calls[0].Errors)
+		assert.Nil(t, calls[1].Errors)
+		assert.Equal(t, http.StatusBadGateway, calls[1].Status)
+		assert.Equal(t, "1", calls[1].Fault)
+		assert.Equal(t, "", calls[2].Fault)
+		assert.Equal(t, "Hello", calls[3].Entity)
+	}
+
+	status, _ = send("POST", Path+"/faults", `{"status": 1000}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = send("PUT", Path+"/endpoints", `[]`)
+	assert.Equal(t, http.StatusNotImplemented, status)
+
+	status, _ = send("POST", Path+"/reset", "")
+	assert.Equal(t, http.StatusNoContent, status)
+	_, entity = send("GET", Path+"/requests", "")
+	assert.Equal(t, "[]", strings.TrimSpace(entity))
+}
@@ -0,0 +1,98 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// A request received by a service
+type Call struct {
+	Time    time.Time         `json:"time"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Query   string            `json:"query,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Entity  string            `json:"entity,omitempty"`
+	Status  int               `json:"status"`
+	Fault   string            `json:"fault,omitempty"`  // the fault injected, if any
+	Errors  []string          `json:"errors,omitempty"` // problems the service found with the request
+}
+
+// A fault injected into requests. Faults apply to requests matching their
+// methods and path, which is a pattern as understood by path.Match; if either
+// is omitted, every request matches. Unless a count is provided, a fault
+// remains in effect until it is removed.
+type Fault struct {
+	Id      string            `yaml:"-" json:"id"`
+	Methods []string          `yaml:"methods" json:"methods,omitempty"`
+	Path    string            `yaml:"path" json:"path,omitempty"`
+	Delay   time.Duration     `yaml:"delay" json:"-"`
+	Abort   bool              `yaml:"abort" json:"abort,omitempty"` // close the connection without responding
+	Status  int               `yaml:"status" json:"status,omitempty"`
+	Headers map[string]string `yaml:"headers" json:"headers,omitempty"`
+	Entity  string            `yaml:"entity" json:"entity,omitempty"`
+	Count   int               `yaml:"count" json:"count,omitempty"` // the number of requests remaining to affect
+}
+
+// Validate a fault
+func (f Fault) Validate() error {
+	if f.Path != "" {
+		if _, err := path.Match(f.Path, "/"); err != nil {
+			return fmt.Errorf("Invalid path pattern: %w", err)
+		}
+	}
+	if f.Status != 0 && (f.Status < 100 || f.Status > 599) {
+		return fmt.Errorf("Invalid status: %d", f.Status)
+	}
+	if f.Count < 0 {
+		return fmt.Errorf("Invalid count: %d", f.Count)
+	}
+	return nil
+}
+
+// Determine if a fault applies to a request
+func (f Fault) Matches(req *http.Request) bool {
+	if len(f.Methods) > 0 {
+		var found bool
+		for _, e := range f.Methods {
+			if strings.EqualFold(e, req.Method) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Path != "" {
+		if m, _ := path.Match(f.Path, req.URL.Path); !m {
+			return false
+		}
+	}
+	return true
+}
+
+// Stringer
+func (f Fault) String() string {
+	return describe(f)
+}
+
+// Marshal; the delay is represented as a duration string
+func (f Fault) MarshalJSON() ([]byte, error) {
+	type alias Fault
+	var delay string
+	if f.Delay > 0 {
+		delay = f.Delay.String()
+	}
+	return json.Marshal(struct {
+		alias
+		Delay string `json:"delay,omitempty"`
+	}{
+		alias: alias(f),
+		Delay: delay,
+	})
+}
@@ -0,0 +1,26 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/instaunit/instaunit/hunit/service"
+	"github.com/instaunit/instaunit/hunit/service/backend/proxy"
+	"github.com/instaunit/instaunit/hunit/service/backend/rest"
+	"github.com/instaunit/instaunit/hunit/service/backend/websocket"
+)
+
+// Create a service for the backend described by the provided configuration
+func New(conf service.Config) (service.Service, error) {
+	switch conf.Backend {
+	case service.BackendREST:
+		return rest.New(conf)
+	case service.BackendRecord, service.BackendReplay:
+		return proxy.New(conf)
+	case service.BackendOpenAPI:
+		return rest.NewOpenAPI(conf)
+	case service.BackendWebsocket:
+		return websocket.New(conf)
+	default:
+		return nil, fmt.Errorf("Unsupported service backend: %v", conf.Backend)
+	}
+}
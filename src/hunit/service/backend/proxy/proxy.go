@@ -0,0 +1,320 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit/expr"
+	"github.com/instaunit/instaunit/hunit/httputil/mimetype"
+	"github.com/instaunit/instaunit/hunit/service"
+	"github.com/instaunit/instaunit/hunit/service/admin"
+	"github.com/instaunit/instaunit/hunit/service/backend/rest"
+
+	"github.com/bww/go-util/v1/debug"
+	humanize "github.com/dustin/go-humanize"
+)
+
+// Don't wait forever
+const ioTimeout = time.Second * 10
+
+const prefix = "[proxy]"
+
+// Headers which describe a single connection and are not forwarded or recorded
+var hopHeaders = map[string]struct{}{
+	"Connection":          {},
+	"Keep-Alive":          {},
+	"Proxy-Authenticate":  {},
+	"Proxy-Authorization": {},
+	"Proxy-Connection":    {},
+	"Te":                  {},
+	"Trailer":             {},
+	"Transfer-Encoding":   {},
+	"Upgrade":             {},
+}
+
+// Response headers which are not meaningful in a recording; they are either
+// computed when the recording is served or vary for every exchange.
+var volatileHeaders = map[string]struct{}{
+	"Content-Length": {},
+	"Date":           {},
+}
+
+// Record/replay proxy service. In record mode every exchange with the upstream
+// service is written to the resource as a mock endpoint. In replay mode the
+// recordings are served by a REST service.
+func New(conf service.Config) (service.Service, error) {
+	switch conf.Backend {
+	case service.BackendRecord:
+		return newRecorder(conf)
+	case service.BackendReplay:
+		return rest.New(conf)
+	default:
+		return nil, fmt.Errorf("Unsupported proxy backend: %v", conf.Backend)
+	}
+}
+
+// Recording proxy service
+type recordService struct {
+	sync.Mutex
+	conf     service.Config
+	upstream *url.URL
+	client   *http.Client
+	suite    *rest.Suite
+	server   *http.Server
+}
+
+func newRecorder(conf service.Config) (*recordService, error) {
+	upstream, err := url.Parse(conf.Upstream)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid upstream: %w", err)
+	}
+	if upstream.Scheme == "" || upstream.Host == "" {
+		return nil, fmt.Errorf("Invalid upstream; an absolute URL is required: %v", conf.Upstream)
+	}
+
+	// if we have recordings already, we add to them; exchanges that match an
+	// existing recording replace it.
+	suite := &rest.Suite{}
+	f, err := os.Open(conf.Path)
+	if err == nil {
+		defer f.Close()
+		suite, err = rest.LoadSuite(f)
+		if err != nil {
+			return nil, fmt.Errorf("Could not load existing recordings: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return &recordService{
+		conf:     conf,
+		upstream: upstream,
+		client: &http.Client{
+			Timeout: ioTimeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse // redirects are recorded, not followed
+			},
+		},
+		suite: suite,
+	}, nil
+}
+
+// Start the service
+func (s *recordService) Start() error {
+	if s.server != nil {
+		return fmt.Errorf("Service is running")
+	}
+
+	_, _, err := net.SplitHostPort(s.conf.Addr)
+	if err != nil {
+		return fmt.Errorf("Invalid address: %v", err)
+	}
+
+	s.server = &http.Server{
+		Addr:           s.conf.Addr,
+		Handler:        admin.New(nil).Handler(http.HandlerFunc(s.routeRequest)),
+		ReadTimeout:    ioTimeout,
+		WriteTimeout:   ioTimeout * 2,
+		MaxHeaderBytes: 1 << 20,
+	}
+
+	l, err := service.Listen(&s.conf)
+	if err != nil {
+		s.server = nil
+		return fmt.Errorf("Could not listen: %w", err)
+	}
+
+	go func() {
+		err := s.server.Serve(l)
+		if err != nil && err != http.ErrServerClosed {
+			panic(err)
+		}
+	}()
+
+	// wait for our service to start up
+	return service.AwaitStatus(s.conf, ioTimeout)
+}
+
+// The base URL of the service
+func (s *recordService) URL() string {
+	return s.conf.URL()
+}
+
+// Stop the service
+func (s *recordService) Stop() error {
+	if s.server == nil {
+		return fmt.Errorf("Service is not running")
+	}
+	err := s.server.Close()
+	s.server = nil
+	return err
+}
+
+// Handle requests
+func (s *recordService) routeRequest(rsp http.ResponseWriter, req *http.Request) {
+	// match our internal status endpoint; we don't proxy this so that we can
+	// monitor the service.
+	if req.Method == service.StatusMethod && req.URL.Path == service.StatusPath {
+		rsp.Header().Set("Server", "Instaunit/1")
+		rsp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		rsp.WriteHeader(http.StatusOK)
+		return
+	}
+
+	start := time.Now()
+	reqdata, err := io.ReadAll(req.Body)
+	if err != nil {
+		fmt.Printf("%s * * * Could not read request: %v: %v\n", prefix, req.URL, err)
+		rsp.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	res, rspdata, err := s.forward(req, reqdata)
+	if err != nil {
+		fmt.Printf("%s * * * Could not proxy request: %v: %v\n", prefix, req.URL, err)
+		rsp.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	for k, v := range res.Header {
+		if _, ok := hopHeaders[k]; !ok {
+			rsp.Header()[k] = v
+		}
+	}
+	rsp.WriteHeader(res.StatusCode)
+	_, err = rsp.Write(rspdata)
+	if err != nil {
+		fmt.Printf("%s * * * Could not write response: %v: %v\n", prefix, req.URL, err)
+	}
+
+	if debug.VERBOSE {
+		fmt.Printf("%s <- %d/%s (%v) %s %s (%s)\n", prefix, res.StatusCode, http.StatusText(res.StatusCode), time.Since(start), req.Method, req.URL, humanize.Bytes(uint64(len(rspdata))))
+	}
+
+	err = s.record(req, reqdata, res, rspdata)
+	if err != nil {
+		fmt.Printf("%s * * * Could not record exchange: %v: %v\n", prefix, req.URL, err)
+	}
+}
+
+// Forward a request to the upstream service and read its response
+func (s *recordService) forward(req *http.Request, reqdata []byte) (*http.Response, []byte, error) {
+	dst := *s.upstream
+	dst.Path = strings.TrimSuffix(s.upstream.Path, "/") + req.URL.Path
+	dst.RawQuery = req.URL.RawQuery
+
+	up, err := http.NewRequest(req.Method, dst.String(), bytes.NewReader(reqdata))
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range req.Header {
+		if _, ok := hopHeaders[k]; !ok {
+			up.Header[k] = v
+		}
+	}
+	// let the transport negotiate compression so that recorded entities are
+	// legible; it transparently decodes the response for us.
+	up.Header.Del("Accept-Encoding")
+
+	res, err := s.client.Do(up)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	rspdata, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, rspdata, nil
+}
+
+// Record an exchange and persist the updated recordings
+func (s *recordService) record(req *http.Request, reqdata []byte, res *http.Response, rspdata []byte) error {
+	var params map[string]string
+	if q := req.URL.Query(); len(q) > 0 {
+		params = make(map[string]string)
+		for k, v := range q {
+			params[k] = v[0]
+		}
+	}
+
+	// request entities are only matched semantically, which requires JSON
+	var entity string
+	if len(reqdata) > 0 && isJSON(req.Header.Get("Content-Type")) {
+		entity = string(reqdata)
+	}
+
+	var headers map[string]string
+	for k, v := range res.Header {
+		_, hop := hopHeaders[k]
+		_, vol := volatileHeaders[k]
+		if !hop && !vol && len(v) > 0 {
+			if headers == nil {
+				headers = make(map[string]string)
+			}
+			headers[k] = v[0]
+		}
+	}
+
+	endpoint := rest.Endpoint{
+		Request: &rest.Request{
+			Methods: []string{req.Method},
+			Path:    req.URL.Path,
+			Params:  params,
+			Entity:  entity,
+		},
+		Response: &rest.Response{
+			Status:  res.StatusCode,
+			Headers: headers,
+			Entity:  expr.Escape(string(rspdata)),
+		},
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	replaced := false
+	for i, e := range s.suite.Endpoints {
+		if e.Request.Equivalent(endpoint.Request) {
+			s.suite.Endpoints[i] = endpoint
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		s.suite.Endpoints = append(s.suite.Endpoints, endpoint)
+	}
+
+	return s.persist()
+}
+
+// Write recordings to our resource. The caller must hold the lock.
+func (s *recordService) persist() error {
+	b := &bytes.Buffer{}
+	err := rest.WriteSuite(b, s.suite)
+	if err != nil {
+		return err
+	}
+	tmp := s.conf.Path + ".tmp"
+	err = os.WriteFile(tmp, b.Bytes(), 0o644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.conf.Path)
+}
+
+// Determine if two requests are matched by the same recording
+func isJSON(ctype string) bool {
+	t, _, err := mime.ParseMediaType(ctype)
+	return err == nil && t == mimetype.JSON
+}
@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/instaunit/instaunit/hunit/service"
+	"github.com/instaunit/instaunit/hunit/service/backend/rest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordReplay(t *testing.T) {
+	var calls int
+	upstream := httptest.NewServer(http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		calls++
+		switch req.URL.Path {
+		case "/v1/users":
+			data, _ := io.ReadAll(req.Body)
+			rsp.Header().Set("Content-Type", "application/json")
+			rsp.Header().Set("X-Request", req.URL.RawQuery)
+			rsp.WriteHeader(http.StatusCreated)
+			io.WriteString(rsp, `{"created": `+string(data)+`, "price": "${5}"}`)
+		case "/v1/old":
+			http.Redirect(rsp, req, "/v1/new", http.StatusFound)
+		default:
+			http.NotFound(rsp, req)
+		}
+	}))
+	defer upstream.Close()
+
+	p := filepath.Join(t.TempDir(), "recordings.yml")
+	svc, err := New(service.Config{Addr: "localhost:0", Backend: service.BackendRecord, Path: p, Upstream: upstream.URL + "/v1/"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	rec := svc.(*recordService)
+	proxy := httptest.NewServer(http.HandlerFunc(rec.routeRequest))
+	defer proxy.Close()
+
+	send := func(base, method, path, entity string) (*http.Response, string) {
+		req, err := http.NewRequest(method, base+path, strings.NewReader(entity))
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		if entity != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rsp, err := (&http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}).Do(req)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		defer rsp.Body.Close()
+		data, err := io.ReadAll(rsp.Body)
+		assert.Nil(t, err)
+		return rsp, string(data)
+	}
+
+	// exchanges are proxied and recorded; an equivalent exchange replaces the
+	// earlier recording of it
+	rsp, entity := send(proxy.URL, "POST", "/users?notify=true", `{"name": "A"}`)
+	assert.Equal(t, http.StatusCreated, rsp.StatusCode)
+	assert.Equal(t, `{"created": {"name": "A"}, "price": "${5}"}`, entity)
+	send(proxy.URL, "POST", "/users?notify=true", `{"name": "A"}`)
+	rsp, _ = send(proxy.URL, "GET", "/old", "")
+	assert.Equal(t, http.StatusFound, rsp.StatusCode) // redirects are recorded, not followed
+	assert.Equal(t, 3, calls)
+
+	f, err := os.Open(p)
+	if !assert.Nil(t, err) {
+		return
+	}
+	suite, err := rest.LoadSuite(f)
+	f.Close()
+	if !assert.Nil(t, err) || !assert.Len(t, suite.Endpoints, 2) {
+		return
+	}
+	e := suite.Endpoints[0]
+	assert.Equal(t, &rest.Request{Methods: []string{"POST"}, Path: "/users", Params: map[string]string{"notify": "true"}, Entity: `{"name": "A"}`}, e.Request)
+	assert.Equal(t, http.StatusCreated, e.Response.Status)
+	assert.Equal(t, `{"created": {"name": "A"}, "price": "\${5}"}`, e.Response.Entity) // escaped, so it isn't interpolated
+	assert.Equal(t, map[string]string{"Content-Type": "application/json", "X-Request": "notify=true"}, e.Response.Headers)
+	assert.Equal(t, "/v1/new", suite.Endpoints[1].Response.Headers["Location"])
+
+	// existing recordings are loaded by a new recorder
+	svc, err = New(service.Config{Addr: "localhost:0", Backend: service.BackendRecord, Path: p, Upstream: upstream.URL})
+	if assert.Nil(t, err) {
+		assert.Len(t, svc.(*recordService).suite.Endpoints, 2)
+	}
+
+	// recordings are replayed without the upstream
+	f, err = os.Open(p)
+	if !assert.Nil(t, err) {
+		return
+	}
+	svc, err = New(service.Config{Addr: "localhost:0", Backend: service.BackendReplay, Path: p, Resource: f})
+	f.Close()
+	if !assert.Nil(t, err) || !assert.Nil(t, svc.Start()) {
+		return
+	}
+	defer svc.Stop()
+	upstream.Close()
+
+	rsp, entity = send(svc.URL(), "POST", "/users?notify=true", `{"name": "A"}`)
+	assert.Equal(t, http.StatusCreated, rsp.StatusCode)
+	assert.Equal(t, `{"created": {"name": "A"}, "price": "${5}"}`, entity)
+	assert.Equal(t, "notify=true", rsp.Header.Get("X-Request"))
+	rsp, _ = send(svc.URL(), "POST", "/users?notify=true", `{"name": "B"}`)
+	assert.Equal(t, http.StatusNotFound, rsp.StatusCode)
+	assert.Equal(t, 3, calls)
+}
+
+func TestNewRecorder(t *testing.T) {
+	_, err := New(service.Config{Backend: service.BackendRecord, Path: filepath.Join(t.TempDir(), "r.yml"), Upstream: "/relative"})
+	assert.NotNil(t, err)
+	_, err = New(service.Config{Backend: service.BackendREST})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,356 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit/httputil"
+	"github.com/instaunit/instaunit/hunit/httputil/mimetype"
+	"github.com/instaunit/instaunit/hunit/openapi"
+	"github.com/instaunit/instaunit/hunit/schema"
+	"github.com/instaunit/instaunit/hunit/service"
+	"github.com/instaunit/instaunit/hunit/service/admin"
+
+	"github.com/bww/go-router/v2"
+	"github.com/bww/go-util/v1/debug"
+	"github.com/bww/go-util/v1/text"
+
+	humanize "github.com/dustin/go-humanize"
+)
+
+// Create a new service from an OpenAPI document. A route is created for every
+// operation the document declares. Requests are validated against the
+// operation and, if they conform, the operation's declared example response
+// is returned. When no example is declared one is synthesized from the
+// response schema.
+//
+// A specific response can be requested by setting the header 'Prefer' to
+// 'code=<status>' and a specific named example by 'example=<name>'.
+func NewOpenAPI(conf service.Config) (service.Service, error) {
+	doc, err := openapi.Load(conf.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	// routes are derived from the document, so endpoints cannot be
+	// reconfigured; requests are still recorded and may be faulted.
+	return &restService{
+		conf:     conf,
+		router:   routeDocument(doc),
+		document: true,
+		admin:    admin.New(nil),
+	}, nil
+}
+
+// Reload the document the service is created from
+func (s *restService) reloadDocument(data []byte) error {
+	doc, err := openapi.Load(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	r := routeDocument(doc)
+	s.Lock()
+	s.router = r
+	s.Unlock()
+	return nil
+}
+
+// Route every operation in a document
+func routeDocument(doc *openapi.Document) router.Router {
+	// if the document declares a server with a base path, operations are
+	// routed relative to it.
+	var base string
+	if len(doc.Servers) > 0 {
+		u, err := url.Parse(doc.Servers[0].URL)
+		if err == nil {
+			base = strings.TrimSuffix(u.Path, "/")
+		}
+	}
+
+	r := router.New()
+	for _, e := range doc.Routes() {
+		route := e
+		r.Add(base+route.Path, func(req *router.Request, cxt router.Context) (*router.Response, error) {
+			return handleOperation((*http.Request)(req), cxt, route)
+		}).Methods(route.Method)
+	}
+
+	return r
+}
+
+// Handle a request for an operation
+func handleOperation(req *http.Request, cxt router.Context, route openapi.Route) (*router.Response, error) {
+	var res *router.Response
+	var err error
+	var e []byte
+
+	if debug.VERBOSE {
+		start := time.Now()
+		defer func() {
+			if res != nil {
+				fmt.Printf("%s <- %d/%s (%v) %s %s (%s)\n", prefix, res.Status, http.StatusText(res.Status), time.Since(start), req.Method, req.URL, humanize.Bytes(uint64(len(e))))
+				if len(e) > 0 {
+					fmt.Println(text.Indent(string(e), strings.Repeat(" ", len(prefix))+" < "))
+				}
+			}
+		}()
+	}
+
+	var data []byte
+	if req.Body != nil {
+		data, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read request body: %w", err)
+		}
+	}
+
+	var status int
+	var ctype string
+	if errs := validateRequest(req, cxt, route, data); len(errs) > 0 {
+		status, ctype = http.StatusBadRequest, mimetype.JSON
+		e, err = marshalErrors("Request does not conform to the specification", errs)
+		if err != nil {
+			return nil, err
+		}
+		res = router.NewResponse(status)
+	} else {
+		prefer := parsePrefer(req.Header.Get("Prefer"))
+		var rsp *openapi.Response
+		status, rsp = selectResponse(route.Operation, prefer["code"])
+		res = router.NewResponse(status)
+		if rsp != nil {
+			for k, v := range rsp.Headers {
+				if v == nil {
+					continue
+				} else if v.Example != nil {
+					res.SetHeader(k, fmt.Sprint(v.Example))
+				} else if v.Required && v.Schema != nil {
+					res.SetHeader(k, fmt.Sprint(v.Schema.Synthesize()))
+				}
+			}
+			var media *openapi.MediaType
+			ctype, media = rsp.Content.Preferred()
+			if media != nil {
+				e, err = marshalSpecimen(ctype, specimen(media, prefer["example"]))
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if len(e) > 0 {
+		_, err = res.SetBytes(ctype, e)
+		if err != nil {
+			return nil, err
+		}
+		res.SetHeader("Content-Length", strconv.Itoa(len(e)))
+	}
+	return res, nil
+}
+
+// Validate a request against the operation it is routed to
+func validateRequest(req *http.Request, cxt router.Context, route openapi.Route, data []byte) schema.ValidationErrors {
+	var errs schema.ValidationErrors
+
+	query := req.URL.Query()
+	for _, e := range route.Params {
+		var raw []string
+		switch e.In {
+		case openapi.InPath:
+			if v, ok := cxt.Vars[e.Name]; ok {
+				raw = []string{v}
+			}
+		case openapi.InQuery:
+			raw = query[e.Name]
+		case openapi.InHeader:
+			raw = req.Header.Values(e.Name)
+		case openapi.InCookie:
+			if c, err := req.Cookie(e.Name); err == nil {
+				raw = []string{c.Value}
+			}
+		}
+		where := e.In + "." + e.Name
+		if len(raw) == 0 {
+			if e.Required || e.In == openapi.InPath {
+				errs = append(errs, schema.ValidationError{Path: where, Message: "parameter is required"})
+			}
+			continue
+		}
+		if e.Schema != nil {
+			errs = appendErrors(errs, e.Schema.ValidateAt(where, coerceParam(e.Schema, raw)))
+		}
+	}
+
+	body := route.Operation.RequestBody
+	if body == nil {
+		return errs
+	}
+	if len(data) == 0 {
+		if body.Required {
+			errs = append(errs, schema.ValidationError{Path: "body", Message: "request body is required"})
+		}
+		return errs
+	}
+	if len(body.Content) == 0 {
+		return errs
+	}
+
+	ctype := req.Header.Get("Content-Type")
+	_, media := body.Content.Match(ctype)
+	if media == nil {
+		return append(errs, schema.ValidationError{Path: "body", Message: fmt.Sprintf("unsupported content type: %q", ctype)})
+	}
+	if media.Schema != nil && httputil.MatchesContentType("*/*json", ctype) {
+		var v interface{}
+		err := json.Unmarshal(data, &v)
+		if err != nil {
+			return append(errs, schema.ValidationError{Path: "body", Message: fmt.Sprintf("invalid JSON: %v", err)})
+		}
+		errs = appendErrors(errs, media.Schema.ValidateAt("body", v))
+	}
+
+	return errs
+}
+
+// Produce the entity of a response describing validation errors
+func marshalErrors(msg string, errs schema.ValidationErrors) ([]byte, error) {
+	return json.MarshalIndent(struct {
+		Message string                  `json:"message"`
+		Details schema.ValidationErrors `json:"details"`
+	}{
+		Message: msg,
+		Details: errs,
+	}, "", "  ")
+}
+
+func appendErrors(errs schema.ValidationErrors, err error) schema.ValidationErrors {
+	var verrs schema.ValidationErrors
+	if errors.As(err, &verrs) {
+		return append(errs, verrs...)
+	} else if err != nil {
+		return append(errs, schema.ValidationError{Message: err.Error()})
+	}
+	return errs
+}
+
+// Parameters are always strings on the wire; convert them to the type the
+// schema describes, where possible, so that they can be validated. Values that
+// cannot be converted are left as strings and fail validation.
+func coerceParam(s *schema.Schema, raw []string) interface{} {
+	if s.Permits(schema.TypeArray) && len(s.Type) > 0 {
+		var vals []string
+		if len(raw) == 1 {
+			vals = strings.Split(raw[0], ",")
+		} else {
+			vals = raw
+		}
+		items := s.Items
+		if items == nil {
+			items = &schema.Schema{}
+		}
+		v := make([]interface{}, len(vals))
+		for i, e := range vals {
+			v[i] = coerceScalar(items, e)
+		}
+		return v
+	}
+	return coerceScalar(s, raw[0])
+}
+
+func coerceScalar(s *schema.Schema, raw string) interface{} {
+	switch {
+	case s.Type.Has(schema.TypeInteger), s.Type.Has(schema.TypeNumber):
+		if v, err := strconv.ParseFloat(raw, 64); err == nil {
+			return v
+		}
+	case s.Type.Has(schema.TypeBoolean):
+		if v, err := strconv.ParseBool(raw); err == nil {
+			return v
+		}
+	}
+	return raw
+}
+
+// Select the response to produce for an operation. A preferred status code is
+// used if the operation declares it; otherwise the first successful response
+// is used, followed by the default response.
+func selectResponse(op *openapi.Operation, prefer string) (int, *openapi.Response) {
+	if prefer != "" {
+		if v, ok := op.Responses[prefer]; ok {
+			return parseStatus(prefer), v
+		}
+	}
+	keys := make([]string, 0, len(op.Responses))
+	for k := range op.Responses {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if strings.HasPrefix(k, "2") {
+			return parseStatus(k), op.Responses[k]
+		}
+	}
+	if v, ok := op.Responses["default"]; ok {
+		return http.StatusOK, v
+	}
+	if len(keys) > 0 {
+		return parseStatus(keys[0]), op.Responses[keys[0]]
+	}
+	return http.StatusOK, nil
+}
+
+// Parse a response status key, which may be a range like '2XX'
+func parseStatus(s string) int {
+	if v, err := strconv.Atoi(s); err == nil {
+		return v
+	}
+	if len(s) == 3 && strings.HasSuffix(strings.ToUpper(s), "XX") {
+		if v, err := strconv.Atoi(s[:1]); err == nil {
+			return v * 100
+		}
+	}
+	return http.StatusOK
+}
+
+// Produce the named example, if it exists, or the representative specimen
+func specimen(media *openapi.MediaType, name string) interface{} {
+	if name != "" {
+		if e, ok := media.Examples[name]; ok && e != nil {
+			return e.Value
+		}
+	}
+	return media.Specimen()
+}
+
+// Marshal a specimen for the provided content type. Strings are produced
+// literally for types which are not JSON.
+func marshalSpecimen(ctype string, v interface{}) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if s, ok := v.(string); ok && !httputil.MatchesContentType("*/*json", ctype) {
+		return []byte(s), nil
+	}
+	return json.MarshalIndent(v, "", "  ")
+}
+
+// Parse the 'Prefer' header, e.g.: 'code=404, example=missing'
+func parsePrefer(s string) map[string]string {
+	m := make(map[string]string)
+	for _, e := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+		if k, v, ok := strings.Cut(strings.TrimSpace(e), "="); ok {
+			m[strings.ToLower(strings.TrimSpace(k))] = strings.Trim(strings.TrimSpace(v), `"`)
+		}
+	}
+	return m
+}
@@ -0,0 +1,248 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/instaunit/instaunit/hunit/openapi"
+	"github.com/instaunit/instaunit/hunit/schema"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const documentSource = `
+openapi: 3.0.3
+info: {title: Users, version: 1.0.0}
+servers:
+  - url: https://api.example.com/v1/
+paths:
+  /users/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: integer}}
+    get:
+      parameters:
+        - {name: verbose, in: query, required: true, schema: {type: boolean}}
+        - {name: tags, in: query, schema: {type: array, items: {type: integer}}}
+        - {name: Tenant, in: header, required: true, schema: {type: string}}
+        - {name: session, in: cookie, schema: {type: string, minLength: 2}}
+      responses:
+        "200":
+          description: The user
+          headers:
+            X-Version: {example: 3}
+            X-Region: {required: true, schema: {type: string, enum: [us]}}
+          content:
+            application/json:
+              example: {id: 1, name: Joe}
+        "404":
+          description: No such user
+          content:
+            application/json:
+              examples:
+                missing: {value: {message: Missing}}
+                gone: {value: {message: Gone}}
+  /users:
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name: {type: string}
+      responses:
+        "201":
+          description: Created
+          content:
+            text/plain: {example: Created}
+`
+
+// Describe the validation errors in a response
+func problems(t *testing.T, rsp *httptest.ResponseRecorder) []string {
+	var v struct {
+		Message string                  `json:"message"`
+		Details schema.ValidationErrors `json:"details"`
+	}
+	if !assert.Nil(t, json.Unmarshal(rsp.Body.Bytes(), &v), rsp.Body.String()) {
+		return nil
+	}
+	assert.Equal(t, "Request does not conform to the specification", v.Message)
+	var p []string
+	for _, e := range v.Details {
+		p = append(p, e.Path)
+	}
+	return p
+}
+
+func TestOpenAPI(t *testing.T) {
+	s := newTestService(t, NewOpenAPI, documentSource)
+
+	get := func(url, prefer string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		req.Header.Set("Tenant", "t1")
+		if prefer != "" {
+			req.Header.Set("Prefer", prefer)
+		}
+		for _, e := range cookies {
+			req.AddCookie(e)
+		}
+		return serve(s, req)
+	}
+
+	// operations are routed relative to the server's path
+	rsp := get("/users/1?verbose=true", "")
+	assert.Equal(t, http.StatusNotFound, rsp.Code)
+
+	rsp = get("/v1/users/1?verbose=true&tags=1,2", "")
+	if assert.Equal(t, http.StatusOK, rsp.Code, rsp.Body.String()) {
+		assert.Equal(t, "application/json", rsp.Header().Get("Content-Type"))
+		assert.Equal(t, "3", rsp.Header().Get("X-Version"))
+		assert.Equal(t, "us", rsp.Header().Get("X-Region"))
+		assert.JSONEq(t, `{"id": 1, "name": "Joe"}`, rsp.Body.String())
+	}
+	rsp = get("/v1/users/1?verbose=1&tags=1&tags=2", "")
+	assert.Equal(t, http.StatusOK, rsp.Code, rsp.Body.String())
+
+	// parameters are coerced to the types their schemas describe
+	rsp = get("/v1/users/x?verbose=maybe&tags=1,x", "", &http.Cookie{Name: "session", Value: "a"})
+	if assert.Equal(t, http.StatusBadRequest, rsp.Code) {
+		assert.Equal(t, []string{"path.id", "query.verbose", "query.tags[1]", "cookie.session"}, problems(t, rsp))
+	}
+	rsp = serve(s, httptest.NewRequest("GET", "/v1/users/1", nil))
+	if assert.Equal(t, http.StatusBadRequest, rsp.Code) {
+		assert.Equal(t, []string{"query.verbose", "header.Tenant"}, problems(t, rsp))
+	}
+
+	// a response and example can be selected with 'Prefer'
+	rsp = get("/v1/users/1?verbose=true", "code=404")
+	if assert.Equal(t, http.StatusNotFound, rsp.Code) {
+		assert.JSONEq(t, `{"message": "Gone"}`, rsp.Body.String())
+	}
+	rsp = get("/v1/users/1?verbose=true", `code=404; example="missing"`)
+	if assert.Equal(t, http.StatusNotFound, rsp.Code) {
+		assert.JSONEq(t, `{"message": "Missing"}`, rsp.Body.String())
+	}
+	rsp = get("/v1/users/1?verbose=true", "code=500") // not declared
+	assert.Equal(t, http.StatusOK, rsp.Code)
+
+	post := func(ctype, entity string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/users", strings.NewReader(entity))
+		if ctype != "" {
+			req.Header.Set("Content-Type", ctype)
+		}
+		return serve(s, req)
+	}
+
+	rsp = post("application/json", `{"name": "Joe"}`)
+	if assert.Equal(t, http.StatusCreated, rsp.Code, rsp.Body.String()) {
+		assert.Equal(t, "text/plain", rsp.Header().Get("Content-Type"))
+		assert.Equal(t, "Created", rsp.Body.String())
+	}
+	for _, e := range []struct {
+		Type, Entity string
+	}{
+		{"", ""},
+		{"application/json", `{"name": 1}`},
+		{"application/json", `{"name":`},
+		{"text/plain", `Joe`},
+	} {
+		rsp = post(e.Type, e.Entity)
+		if assert.Equal(t, http.StatusBadRequest, rsp.Code, e.Entity) {
+			p := problems(t, rsp)
+			if assert.Len(t, p, 1) {
+				assert.True(t, strings.HasPrefix(p[0], "body"), p[0])
+			}
+		}
+	}
+}
+
+func TestSelectResponse(t *testing.T) {
+	rsp := func(d string) *openapi.Response { return &openapi.Response{Description: d} }
+	tests := []struct {
+		Responses map[string]*openapi.Response
+		Prefer    string
+		Status    int
+		Expect    string
+	}{
+		{map[string]*openapi.Response{"404": rsp("a"), "201": rsp("b"), "200": rsp("c")}, "", 200, "c"},
+		{map[string]*openapi.Response{"404": rsp("a"), "2XX": rsp("b")}, "", 200, "b"},
+		{map[string]*openapi.Response{"404": rsp("a"), "default": rsp("b")}, "", 200, "b"},
+		{map[string]*openapi.Response{"404": rsp("a"), "4XX": rsp("b")}, "", 404, "a"},
+		{map[string]*openapi.Response{"200": rsp("a"), "4XX": rsp("b")}, "4XX", 400, "b"},
+		{map[string]*openapi.Response{}, "", 200, ""},
+	}
+	for _, e := range tests {
+		status, r := selectResponse(&openapi.Operation{Responses: e.Responses}, e.Prefer)
+		assert.Equal(t, e.Status, status)
+		if e.Expect == "" {
+			assert.Nil(t, r)
+		} else if assert.NotNil(t, r) {
+			assert.Equal(t, e.Expect, r.Description)
+		}
+	}
+}
+
+func TestCoerceParam(t *testing.T) {
+	integers := &schema.Schema{Type: schema.Types{schema.TypeArray}, Items: &schema.Schema{Type: schema.Types{schema.TypeInteger}}}
+	tests := []struct {
+		Schema *schema.Schema
+		Raw    []string
+		Expect interface{}
+	}{
+		{&schema.Schema{Type: schema.Types{schema.TypeInteger}}, []string{"10"}, 10.0},
+		{&schema.Schema{Type: schema.Types{schema.TypeNumber}}, []string{"1.5"}, 1.5},
+		{&schema.Schema{Type: schema.Types{schema.TypeBoolean}}, []string{"false"}, false},
+		{&schema.Schema{Type: schema.Types{schema.TypeBoolean}}, []string{"nope"}, "nope"},
+		{&schema.Schema{}, []string{"a", "b"}, "a"},
+		{integers, []string{"1,2"}, []interface{}{1.0, 2.0}},
+		{integers, []string{"1", "x"}, []interface{}{1.0, "x"}},
+		{&schema.Schema{Type: schema.Types{schema.TypeArray}}, []string{"a"}, []interface{}{"a"}},
+	}
+	for _, e := range tests {
+		assert.Equal(t, e.Expect, coerceParam(e.Schema, e.Raw), e.Raw)
+	}
+}
+
+func TestReloadDocument(t *testing.T) {
+	s := newTestService(t, NewOpenAPI, `
+openapi: 3.0.3
+info: {title: A, version: 1.0.0}
+paths:
+  /a:
+    get:
+      responses:
+        "200": {description: Ok, content: {text/plain: {example: A}}}
+`)
+
+	rsp := serve(s, httptest.NewRequest("GET", "/a", nil))
+	assert.Equal(t, http.StatusOK, rsp.Code)
+	assert.Equal(t, "A", rsp.Body.String())
+
+	err := s.Reload([]byte(`
+openapi: 3.0.3
+info: {title: B, version: 1.0.0}
+paths:
+  /b:
+    get:
+      responses:
+        "200": {description: Ok, content: {text/plain: {example: B}}}
+`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	rsp = serve(s, httptest.NewRequest("GET", "/a", nil))
+	assert.Equal(t, http.StatusNotFound, rsp.Code)
+	rsp = serve(s, httptest.NewRequest("GET", "/b", nil))
+	assert.Equal(t, http.StatusOK, rsp.Code)
+	assert.Equal(t, "B", rsp.Body.String())
+
+	// an invalid document leaves the current one in place
+	assert.NotNil(t, s.Reload([]byte(`openapi: [`)))
+	rsp = serve(s, httptest.NewRequest("GET", "/b", nil))
+	assert.Equal(t, http.StatusOK, rsp.Code)
+}
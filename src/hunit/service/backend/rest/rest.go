@@ -2,7 +2,6 @@ package rest
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,13 +12,16 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/instaunit/instaunit/hunit/entity"
 	"github.com/instaunit/instaunit/hunit/expr"
 	"github.com/instaunit/instaunit/hunit/expr/runtime"
-	"github.com/instaunit/instaunit/hunit/net/await"
+	"github.com/instaunit/instaunit/hunit/httputil/mimetype"
+	"github.com/instaunit/instaunit/hunit/schema"
 	"github.com/instaunit/instaunit/hunit/service"
+	"github.com/instaunit/instaunit/hunit/service/admin"
 
 	"github.com/bww/go-router/v2"
 	routerentity "github.com/bww/go-router/v2/entity"
@@ -33,21 +35,22 @@ import (
 // Don't wait forever
 const ioTimeout = time.Second * 10
 
-// Status
-const (
-	statusMethod = "GET"
-	statusPath   = "/_instaunit/status"
-)
+// The time a response script may run before it is halted
+var scriptTimeout = time.Second * 5
 
 const prefix = "[rest]"
 
 // REST service
 type restService struct {
-	conf   service.Config
-	suite  *Suite
-	server *http.Server
-	router router.Router
-	vars   expr.Variables
+	sync.RWMutex
+	conf      service.Config
+	suite     *Suite     // the endpoints the service was created with
+	endpoints []Endpoint // the endpoints currently served
+	document  bool       // the service is defined by an OpenAPI document rather than endpoints
+	server    *http.Server
+	router    router.Router
+	vars      expr.Variables
+	admin     *admin.Admin
 }
 
 // Create a new service
@@ -57,19 +60,30 @@ func New(conf service.Config) (service.Service, error) {
 		return nil, err
 	}
 
-	vars := expr.Variables{
-		"std": runtime.Stdlib,
+	s := &restService{
+		conf:  conf,
+		suite: suite,
+		vars: expr.Variables{
+			"std": runtime.Stdlib,
+		},
 	}
+	s.admin = admin.New(s)
+	s.setEndpoints(suite.Endpoints)
+
+	return s, nil
+}
 
+// Route the provided endpoints
+func (s *restService) setEndpoints(endpoints []Endpoint) {
 	handler := func(e Endpoint) router.Handler {
 		return func(req *router.Request, cxt router.Context) (*router.Response, error) {
-			return handleRequest((*http.Request)(req), cxt, e, maps.Copy(vars))
+			return handleRequest((*http.Request)(req), cxt, e, maps.Copy(s.vars))
 		}
 	}
 
 	r := router.New()
 
-	for _, e := range suite.Endpoints {
+	for _, e := range endpoints {
 		if e.Request != nil {
 			endpoint := e
 			b := r.Add(e.Request.Path, handler(e)).Methods(e.Request.Methods...).Params(convertParams(e.Request.Params))
@@ -85,12 +99,89 @@ func New(conf service.Config) (service.Service, error) {
 		}
 	}
 
-	return &restService{
-		conf:   conf,
-		suite:  suite,
-		router: r,
-		vars:   vars,
-	}, nil
+	s.Lock()
+	s.endpoints = endpoints
+	s.router = r
+	s.Unlock()
+}
+
+// Add endpoints; those equivalent to an existing endpoint replace it
+func (s *restService) AddEndpoints(data []byte) (int, error) {
+	add, err := LoadSuite(io.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		return 0, err
+	}
+
+	s.RLock()
+	endpoints := make([]Endpoint, len(s.endpoints))
+	copy(endpoints, s.endpoints)
+	s.RUnlock()
+
+outer:
+	for _, e := range add.Endpoints {
+		for i, x := range endpoints {
+			if x.Request.Equivalent(e.Request) {
+				endpoints[i] = e
+				continue outer
+			}
+		}
+		endpoints = append(endpoints, e)
+	}
+
+	s.setEndpoints(endpoints)
+	return len(add.Endpoints), nil
+}
+
+// Replace every endpoint
+func (s *restService) SetEndpoints(data []byte) (int, error) {
+	set, err := LoadSuite(io.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		return 0, err
+	}
+	s.setEndpoints(set.Endpoints)
+	return len(set.Endpoints), nil
+}
+
+// Produce the current endpoints
+func (s *restService) Endpoints() ([]byte, error) {
+	s.RLock()
+	suite := &Suite{Endpoints: s.endpoints}
+	s.RUnlock()
+	b := &bytes.Buffer{}
+	err := WriteSuite(b, suite)
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Restore the endpoints the service was created with
+func (s *restService) ResetEndpoints() error {
+	s.RLock()
+	suite := s.suite
+	s.RUnlock()
+	if suite == nil {
+		return nil // routes are not defined by endpoints
+	}
+	s.setEndpoints(suite.Endpoints)
+	return nil
+}
+
+// Reload the service definition. This replaces every endpoint, including
+// those added at runtime.
+func (s *restService) Reload(data []byte) error {
+	if s.document {
+		return s.reloadDocument(data)
+	}
+	suite, err := LoadSuite(io.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	s.suite = suite
+	s.Unlock()
+	s.setEndpoints(suite.Endpoints)
+	return nil
 }
 
 // bodyMatches compares the request entity object with the request body for a match.
@@ -128,39 +219,39 @@ func (s *restService) Start() error {
 		return fmt.Errorf("Service is running")
 	}
 
-	host, port, err := net.SplitHostPort(s.conf.Addr)
+	_, _, err := net.SplitHostPort(s.conf.Addr)
 	if err != nil {
 		return fmt.Errorf("Invalid address: %v", err)
 	}
-	if host == "" {
-		host = "localhost"
-	}
 
 	s.server = &http.Server{
 		Addr:           s.conf.Addr,
-		Handler:        http.HandlerFunc(s.routeRequest),
+		Handler:        s.admin.Handler(http.HandlerFunc(s.routeRequest)),
 		ReadTimeout:    ioTimeout,
 		WriteTimeout:   ioTimeout,
 		MaxHeaderBytes: 1 << 20,
 	}
 
+	l, err := service.Listen(&s.conf)
+	if err != nil {
+		s.server = nil
+		return fmt.Errorf("Could not listen: %w", err)
+	}
+
 	go func() {
-		err := s.server.ListenAndServe()
+		err := s.server.Serve(l)
 		if err != nil && err != http.ErrServerClosed {
 			panic(err)
 		}
 	}()
 
 	// wait for our service to start up
-	status := fmt.Sprintf("http://%s:%s%s", host, port, statusPath)
-	err = await.Await(context.Background(), []string{status}, ioTimeout)
-	if err == await.ErrTimeout {
-		return fmt.Errorf("Timed out waiting for service: %s", status)
-	} else if err != nil {
-		return err
-	}
+	return service.AwaitStatus(s.conf, ioTimeout)
+}
 
-	return nil
+// The base URL of the service
+func (s *restService) URL() string {
+	return s.conf.URL()
 }
 
 // Stop the service
@@ -196,7 +287,7 @@ func (s *restService) routeRequest(rsp http.ResponseWriter, req *http.Request) {
 
 	// match our internal status endpoint; we don't allow this to be shadowed
 	// by defined endpoints so that we can monitor the service.
-	if req.Method == statusMethod && req.URL.Path == statusPath {
+	if req.Method == service.StatusMethod && req.URL.Path == service.StatusPath {
 		rsp.Header().Set("Server", "Instaunit/1")
 		rsp.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		rsp.WriteHeader(http.StatusOK)
@@ -204,9 +295,13 @@ func (s *restService) routeRequest(rsp http.ResponseWriter, req *http.Request) {
 	}
 
 	// handle our route
-	res, err := s.router.Handle((*router.Request)(req))
+	s.RLock()
+	r := s.router
+	s.RUnlock()
+	res, err := r.Handle((*router.Request)(req))
 	if err != nil {
 		fmt.Printf("%s * * * Could not handle request: %v: %v\n", prefix, req.URL, err)
+		http.Error(rsp, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -218,11 +313,19 @@ func (s *restService) routeRequest(rsp http.ResponseWriter, req *http.Request) {
 func handleRequest(req *http.Request, cxt router.Context, endpoint Endpoint, vars expr.Variables) (*router.Response, error) {
 	var err error
 
+	if endpoint.Request != nil && endpoint.Request.Schema != nil {
+		res, err := validateEntity(req, endpoint.Request.Schema)
+		if err != nil || res != nil {
+			return res, err
+		}
+	}
+
 	r := endpoint.Response
 	if r == nil {
 		return router.NewResponse(http.StatusOK), nil
 	}
 
+	status, headers := r.Status, r.Headers
 	var e string
 	if debug.VERBOSE {
 		start := time.Now()
@@ -231,7 +334,7 @@ func handleRequest(req *http.Request, cxt router.Context, endpoint Endpoint, var
 			if len(req.URL.RawQuery) > 0 {
 				query = "?" + req.URL.RawQuery
 			}
-			fmt.Printf("%s <- %d/%s (%v) %s %s%s (%s)\n", prefix, r.Status, http.StatusText(r.Status), time.Since(start), req.Method, req.URL.Path, query, humanize.Bytes(uint64(len(e))))
+			fmt.Printf("%s <- %d/%s (%v) %s %s%s (%s)\n", prefix, status, http.StatusText(status), time.Since(start), req.Method, req.URL.Path, query, humanize.Bytes(uint64(len(e))))
 			if len(e) > 0 {
 				fmt.Println(text.Indent(e, strings.Repeat(" ", len(prefix))+" < "))
 			}
@@ -239,8 +342,9 @@ func handleRequest(req *http.Request, cxt router.Context, endpoint Endpoint, var
 	}
 
 	var reqent interface{}
+	var data []byte
 	if req.Body != nil {
-		data, err := io.ReadAll(req.Body)
+		data, err = io.ReadAll(req.Body)
 		if err != nil {
 			return nil, fmt.Errorf("Could not read request body: %w", err)
 		}
@@ -273,18 +377,37 @@ func handleRequest(req *http.Request, cxt router.Context, endpoint Endpoint, var
 		}
 	}
 
+	cheaders := make(map[string]interface{})
+	for k, v := range req.Header {
+		if len(v) > 0 {
+			cheaders[k] = v[0]
+		}
+	}
+
 	vars["request"] = map[string]interface{}{
-		"vars":   cvars,
-		"params": cparams,
-		"form":   cform,
-		"value":  reqent, // if available; this may be nil
+		"method":  req.Method,
+		"path":    req.URL.Path,
+		"headers": cheaders,
+		"vars":    cvars,
+		"params":  cparams,
+		"form":    cform,
+		"entity":  string(data),
+		"value":   reqent, // if available; this may be nil
 	}
-	e, err = expr.Interpolate(r.Entity, vars)
-	if err != nil {
-		return nil, err
+	if r.Script != nil {
+		res, err := evalScript(r, vars, scriptTimeout)
+		if err != nil {
+			return nil, err
+		}
+		status, headers, e = res.Status, res.Headers, res.Entity
+	} else {
+		e, err = expr.Interpolate(r.Entity, vars)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	x := router.NewResponse(r.Status)
+	x := router.NewResponse(status)
 	if l := len(e); l > 0 {
 		ent, err := routerentity.NewString("binary/octet-stream", e)
 		if err != nil {
@@ -296,13 +419,66 @@ func handleRequest(req *http.Request, cxt router.Context, endpoint Endpoint, var
 		}
 		x.SetHeader("Content-Length", strconv.FormatInt(int64(l), 10))
 	}
-	for k, v := range r.Headers {
+	for k, v := range headers {
 		x.SetHeader(k, v)
 	}
 
 	return x, nil
 }
 
+// Validate a request entity against the schema its endpoint declares. If the
+// entity does not conform, a response describing the problems is produced and
+// the request is flagged in the service's call history.
+func validateEntity(req *http.Request, s *schema.Schema) (*router.Response, error) {
+	var data []byte
+	if req.Body != nil {
+		var err error
+		data, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(data))
+	}
+
+	var errs schema.ValidationErrors
+	if len(data) == 0 {
+		errs = append(errs, schema.ValidationError{Path: "body", Message: "request body is required"})
+	} else {
+		var v interface{}
+		err := json.Unmarshal(data, &v)
+		if err != nil {
+			errs = append(errs, schema.ValidationError{Path: "body", Message: fmt.Sprintf("invalid JSON: %v", err)})
+		} else {
+			errs = appendErrors(errs, s.ValidateAt("body", v))
+		}
+	}
+	if len(errs) == 0 {
+		return nil, nil
+	}
+
+	problems := make([]string, len(errs))
+	for i, e := range errs {
+		problems[i] = e.Error()
+	}
+	admin.Flag(req, problems...)
+	if debug.VERBOSE {
+		fmt.Printf("%s * * * Request does not conform to schema: %s %s\n", prefix, req.Method, req.URL.Path)
+		fmt.Println(text.Indent(strings.Join(problems, "\n"), strings.Repeat(" ", len(prefix))+" ! "))
+	}
+
+	e, err := marshalErrors("Request does not conform to the schema", errs)
+	if err != nil {
+		return nil, err
+	}
+	res := router.NewResponse(http.StatusUnprocessableEntity)
+	_, err = res.SetBytes(mimetype.JSON, e)
+	if err != nil {
+		return nil, err
+	}
+	res.SetHeader("Content-Length", strconv.Itoa(len(e)))
+	return res, nil
+}
+
 // Handle responses
 func handleResponse(rsp http.ResponseWriter, req *http.Request, res *router.Response) {
 	for k, v := range res.Header {
@@ -0,0 +1,139 @@
+package rest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/instaunit/instaunit/hunit/schema"
+	"github.com/instaunit/instaunit/hunit/service"
+	"github.com/instaunit/instaunit/hunit/service/admin"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Create a service from a definition
+func newTestService(t *testing.T, create func(service.Config) (service.Service, error), src string) *restService {
+	svc, err := create(service.Config{Addr: "localhost:0", Resource: io.NopCloser(strings.NewReader(src))})
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return svc.(*restService)
+}
+
+// Make a request to a service without starting it
+func serve(s *restService, req *http.Request) *httptest.ResponseRecorder {
+	rsp := httptest.NewRecorder()
+	s.admin.Handler(http.HandlerFunc(s.routeRequest)).ServeHTTP(rsp, req)
+	return rsp
+}
+
+func TestReload(t *testing.T) {
+	s := newTestService(t, New, `
+- endpoint: {methods: [GET], path: /a}
+  response: {status: 200, entity: A}
+`)
+
+	rsp := serve(s, httptest.NewRequest("GET", "/a", nil))
+	assert.Equal(t, http.StatusOK, rsp.Code)
+	assert.Equal(t, "A", rsp.Body.String())
+
+	err := s.Reload([]byte(`
+- endpoint: {methods: [GET], path: /b}
+  response: {status: 201, entity: B}
+`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	rsp = serve(s, httptest.NewRequest("GET", "/a", nil))
+	assert.Equal(t, http.StatusNotFound, rsp.Code)
+	rsp = serve(s, httptest.NewRequest("GET", "/b", nil))
+	assert.Equal(t, http.StatusCreated, rsp.Code)
+	assert.Equal(t, "B", rsp.Body.String())
+
+	// an invalid definition leaves the current one in place
+	err = s.Reload([]byte(`{not: [valid`))
+	assert.NotNil(t, err)
+	rsp = serve(s, httptest.NewRequest("GET", "/b", nil))
+	assert.Equal(t, http.StatusCreated, rsp.Code)
+
+	// endpoints are restored to those of the reloaded definition
+	_, err = s.AddEndpoints([]byte(`[{endpoint: {methods: [GET], path: /c}, response: {status: 200}}]`))
+	if assert.Nil(t, err) {
+		assert.Nil(t, s.ResetEndpoints())
+		rsp = serve(s, httptest.NewRequest("GET", "/c", nil))
+		assert.Equal(t, http.StatusNotFound, rsp.Code)
+		rsp = serve(s, httptest.NewRequest("GET", "/b", nil))
+		assert.Equal(t, http.StatusCreated, rsp.Code)
+	}
+}
+
+func TestValidateEntity(t *testing.T) {
+	s := newTestService(t, New, `
+- endpoint:
+    methods: [POST]
+    path: /users
+    schema:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        age: {type: integer, minimum: 0}
+  response: {status: 201, entity: Created}
+`)
+
+	post := func(entity string) *httptest.ResponseRecorder {
+		return serve(s, httptest.NewRequest("POST", "/users", strings.NewReader(entity)))
+	}
+
+	rsp := post(`{"name": "Joe", "age": 30}`)
+	assert.Equal(t, http.StatusCreated, rsp.Code)
+	assert.Equal(t, "Created", rsp.Body.String())
+
+	// entities which do not conform are refused, and the problems are
+	// described by the response and recorded in the call history
+	tests := []struct {
+		Entity string
+		Paths  []string
+	}{
+		{`{"age": -1}`, []string{"body.name", "body.age"}},
+		{`{"name": 1}`, []string{"body.name"}},
+		{`{"name":`, []string{"body"}},
+		{``, []string{"body"}},
+	}
+	var expect [][]string
+	for _, e := range tests {
+		rsp = post(e.Entity)
+		if !assert.Equal(t, http.StatusUnprocessableEntity, rsp.Code, e.Entity) {
+			continue
+		}
+		var v struct {
+			Message string                  `json:"message"`
+			Details schema.ValidationErrors `json:"details"`
+		}
+		if !assert.Nil(t, json.Unmarshal(rsp.Body.Bytes(), &v), rsp.Body.String()) {
+			continue
+		}
+		assert.Equal(t, "Request does not conform to the schema", v.Message)
+		var paths, problems []string
+		for _, d := range v.Details {
+			paths = append(paths, d.Path)
+			problems = append(problems, d.Error())
+		}
+		assert.ElementsMatch(t, e.Paths, paths, e.Entity)
+		expect = append(expect, problems)
+	}
+
+	rsp = serve(s, httptest.NewRequest("GET", admin.Path+"/requests", nil))
+	var calls []admin.Call
+	if assert.Nil(t, json.Unmarshal(rsp.Body.Bytes(), &calls)) && assert.Len(t, calls, len(tests)+1) {
+		assert.Nil(t, calls[0].Errors)
+		for i, e := range expect {
+			assert.Equal(t, http.StatusUnprocessableEntity, calls[i+1].Status)
+			assert.Equal(t, e, calls[i+1].Errors)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit/expr"
+	"github.com/instaunit/instaunit/hunit/httputil/mimetype"
+
+	"github.com/robertkrimen/otto"
+)
+
+// The response produced by a script
+type scriptResult struct {
+	Status  int
+	Headers map[string]string
+	Entity  string
+}
+
+// Produce a response by evaluating the response script. The script has access
+// to the same variables as interpolated entities, notably 'request'. It
+// evaluates to an object describing the response:
+//
+//	({status: 201, headers: {"Location": "/users/1"}, body: {id: 1}})
+//
+// Any property may be omitted, in which case the status and headers declared
+// by the response are used. A body which is not a string is marshaled as
+// JSON. If the script evaluates to a string, it is used as the body.
+//
+// A script which does not complete within the timeout is halted and an error
+// is returned, so a script that never completes can't hang a request.
+func evalScript(r *Response, vars expr.Variables, timeout time.Duration) (*scriptResult, error) {
+	s := *r.Script
+	if s.Type == "" {
+		s.Type = "js" // unlike assertions, response scripts are JavaScript unless specified
+	}
+
+	res, err := s.EvalWithin(vars, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("Could not evaluate response script: %w", err)
+	}
+	if v, ok := res.(otto.Value); ok {
+		res, err = v.Export()
+		if err != nil {
+			return nil, fmt.Errorf("Could not evaluate response script: %w", err)
+		}
+	}
+
+	x := &scriptResult{
+		Status:  r.Status,
+		Headers: make(map[string]string),
+	}
+	for k, v := range r.Headers {
+		x.Headers[k] = v
+	}
+
+	var body interface{}
+	switch v := res.(type) {
+	case nil:
+		// nothing to do
+	case string:
+		body = v
+	case map[string]interface{}:
+		for k, e := range v {
+			switch strings.ToLower(k) {
+			case "status":
+				n, ok := toInt(e)
+				if !ok || n < 100 || n > 599 {
+					return nil, fmt.Errorf("Response script produced an invalid status: %v", e)
+				}
+				x.Status = n
+			case "headers":
+				h, ok := e.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("Response script produced invalid headers: %v", e)
+				}
+				for hk, hv := range h {
+					x.Headers[http.CanonicalHeaderKey(hk)] = fmt.Sprint(hv)
+				}
+			case "body", "entity":
+				body = e
+			default:
+				return nil, fmt.Errorf("Response script produced an unsupported property: %s", k)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("Response script must produce an object or a string; got: %T", res)
+	}
+
+	switch v := body.(type) {
+	case nil:
+		// no entity
+	case string:
+		x.Entity = v
+	default:
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("Could not marshal response entity: %w", err)
+		}
+		x.Entity = string(data)
+		if _, ok := x.Headers["Content-Type"]; !ok {
+			x.Headers["Content-Type"] = mimetype.JSON
+		}
+	}
+
+	return x, nil
+}
+
+// JavaScript numbers are exported as a variety of types
+func toInt(v interface{}) (int, bool) {
+	switch c := v.(type) {
+	case int:
+		return c, true
+	case int32:
+		return int(c), true
+	case int64:
+		return int(c), true
+	case float32:
+		return toInt(float64(c))
+	case float64:
+		if c != math.Trunc(c) {
+			return 0, false
+		}
+		return int(c), true
+	default:
+		return 0, false
+	}
+}
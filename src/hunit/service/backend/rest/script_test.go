@@ -0,0 +1,82 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit/expr"
+	"github.com/instaunit/instaunit/hunit/script"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvalScript(t *testing.T) {
+	vars := expr.Variables{
+		"request": map[string]interface{}{
+			"method": "POST",
+			"params": map[string]interface{}{"page": "2"},
+		},
+	}
+	tests := []struct {
+		Response *Response
+		Expect   *scriptResult
+		Error    bool
+	}{
+		{
+			&Response{Status: 200, Script: &script.Script{Source: `request.method`}},
+			&scriptResult{Status: 200, Headers: map[string]string{}, Entity: "POST"},
+			false,
+		},
+		{
+			&Response{Status: 200, Headers: map[string]string{"X-A": "a"}, Script: &script.Script{Source: `({status: 201, headers: {"x-page": parseInt(request.params.page)}, body: {ok: true}})`}},
+			&scriptResult{Status: 201, Headers: map[string]string{"X-A": "a", "X-Page": "2", "Content-Type": "application/json"}, Entity: "{\n  \"ok\": true\n}"},
+			false,
+		},
+		{
+			&Response{Script: &script.Script{Source: `({status: 1000})`}},
+			nil,
+			true,
+		},
+		{
+			&Response{Script: &script.Script{Source: `({stats: 200})`}},
+			nil,
+			true,
+		},
+		{
+			&Response{Script: &script.Script{Source: `throw new Error("Nope")`}},
+			nil,
+			true,
+		},
+	}
+	for _, e := range tests {
+		res, err := evalScript(e.Response, vars, time.Second)
+		if e.Error {
+			assert.NotNil(t, err, e.Response.Script.Source)
+		} else if assert.Nil(t, err, e.Response.Script.Source) {
+			assert.Equal(t, e.Expect, res, e.Response.Script.Source)
+		}
+	}
+}
+
+func TestScriptTimeout(t *testing.T) {
+	_, err := evalScript(&Response{Script: &script.Script{Source: `while (true) {}`}}, expr.Variables{}, time.Millisecond*50)
+	assert.True(t, errors.Is(err, script.ErrTimeout), err)
+
+	defer func(d time.Duration) { scriptTimeout = d }(scriptTimeout)
+	scriptTimeout = time.Millisecond * 50
+	s := newTestService(t, New, `
+- endpoint: {methods: [GET], path: /spin}
+  response:
+    script: {source: "while (true) {}"}
+- endpoint: {methods: [GET], path: /ok}
+  response:
+    script: {source: "({status: 201})"}
+`)
+	rsp := serve(s, httptest.NewRequest("GET", "/spin", nil))
+	assert.Equal(t, http.StatusInternalServerError, rsp.Code)
+	rsp = serve(s, httptest.NewRequest("GET", "/ok", nil))
+	assert.Equal(t, http.StatusCreated, rsp.Code)
+}
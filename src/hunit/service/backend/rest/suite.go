@@ -9,6 +9,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/instaunit/instaunit/hunit/schema"
+	"github.com/instaunit/instaunit/hunit/script"
+
 	yaml "gopkg.in/yaml.v3"
 )
 
@@ -35,28 +38,50 @@ func (errs alternateErrors) Error() string {
 
 // A request
 type Request struct {
-	sync.Mutex
-	Methods []string          `yaml:"methods"`
-	Path    string            `yaml:"path"`
-	Params  map[string]string `yaml:"params"`
-	Headers map[string]string `yaml:"headers"`
-	Cookies map[string]string `yaml:"cookies"`
-	Entity  string            `yaml:"entity"`
+	sync.Mutex `yaml:"-"`
+	Methods    []string          `yaml:"methods,omitempty"`
+	Path       string            `yaml:"path"`
+	Params     map[string]string `yaml:"params,omitempty"`
+	Headers    map[string]string `yaml:"headers,omitempty"`
+	Cookies    map[string]string `yaml:"cookies,omitempty"`
+	Entity     string            `yaml:"entity,omitempty"`
+	Schema     *schema.Schema    `yaml:"schema,omitempty"` // the entity must conform to this schema
+}
+
+// Determine if two requests describe the same endpoint; that is, if they
+// would match the same requests.
+func (r *Request) Equivalent(o *Request) bool {
+	if r == nil || o == nil {
+		return r == o
+	}
+	if strings.Join(r.Methods, ",") != strings.Join(o.Methods, ",") || r.Path != o.Path || r.Entity != o.Entity {
+		return false
+	}
+	if len(r.Params) != len(o.Params) {
+		return false
+	}
+	for k, v := range r.Params {
+		if x, ok := o.Params[k]; !ok || x != v {
+			return false
+		}
+	}
+	return true
 }
 
 // A response
 type Response struct {
-	Status  int               `yaml:"status"`
-	Headers map[string]string `yaml:"headers"`
-	Cookies map[string]string `yaml:"cookies"`
-	Entity  string            `yaml:"entity"`
+	Status  int               `yaml:"status,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Cookies map[string]string `yaml:"cookies,omitempty"`
+	Entity  string            `yaml:"entity,omitempty"`
+	Script  *script.Script    `yaml:"script,omitempty"` // computes the response; see evalScript
 }
 
 // An endpoint
 type Endpoint struct {
-	Wait     time.Duration `yaml:"wait"`
+	Wait     time.Duration `yaml:"wait,omitempty"`
 	Request  *Request      `yaml:"endpoint"`
-	Response *Response     `yaml:"response"`
+	Response *Response     `yaml:"response,omitempty"`
 }
 
 // A test suite
@@ -96,6 +121,17 @@ func LoadSuite(src io.ReadCloser) (*Suite, error) {
 	}
 }
 
+// Write a test suite
+func WriteSuite(dst io.Writer, suite *Suite) error {
+	enc := yaml.NewEncoder(dst)
+	enc.SetIndent(2)
+	err := enc.Encode(suite)
+	if err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
 func unmarshal(data []byte, dest interface{}) error {
 	dec := yaml.NewDecoder(bytes.NewReader(data))
 	dec.KnownFields(true)
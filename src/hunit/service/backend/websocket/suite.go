@@ -0,0 +1,134 @@
+package websocket
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit/entity"
+	"github.com/instaunit/instaunit/hunit/httputil/mimetype"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Message comparison modes
+const (
+	compareLiteral  = "literal"
+	compareSemantic = "semantic"
+)
+
+// The request used to match an endpoint
+type Request struct {
+	Path    string            `yaml:"path"`
+	Params  map[string]string `yaml:"params"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+// A message sent to the client
+type Message struct {
+	Wait time.Duration `yaml:"wait"`
+	Send string        `yaml:"send"`
+}
+
+// A message exchange; when a message matching the expected input is received,
+// the reply is sent. If no input is expected, any message matches.
+type Exchange struct {
+	Receive *string       `yaml:"receive"`
+	Compare string        `yaml:"compare"`
+	Wait    time.Duration `yaml:"wait"`
+	Send    *string       `yaml:"send"`
+	Close   bool          `yaml:"close"`
+}
+
+// Determine if a received message matches this exchange
+func (e Exchange) Matches(msg []byte) (bool, error) {
+	if e.Receive == nil {
+		return true, nil
+	}
+	switch strings.ToLower(e.Compare) {
+	case compareLiteral, "":
+		return strings.TrimSpace(*e.Receive) == strings.TrimSpace(string(msg)), nil
+	case compareSemantic:
+		x, err := entity.Unmarshal(mimetype.JSON, []byte(*e.Receive))
+		if err != nil {
+			return false, err
+		}
+		a, err := entity.Unmarshal(mimetype.JSON, msg)
+		if err != nil {
+			return false, nil // the message isn't JSON, so it can't match
+		}
+		return entity.SemanticEqual(x, a), nil
+	default:
+		return false, fmt.Errorf("Unsupported comparison: %v", e.Compare)
+	}
+}
+
+// A message that is pushed to the client periodically
+type Push struct {
+	Wait  time.Duration `yaml:"wait"`
+	Every time.Duration `yaml:"every"`
+	Count int           `yaml:"count"` // zero pushes indefinitely
+	Send  string        `yaml:"send"`
+}
+
+// When to close the connection
+type Close struct {
+	After  int           `yaml:"after"` // close after this many messages are received
+	Wait   time.Duration `yaml:"wait"`  // close after this interval has elapsed
+	Code   int           `yaml:"code"`
+	Reason string        `yaml:"reason"`
+}
+
+// An endpoint
+type Endpoint struct {
+	Request  *Request   `yaml:"endpoint"`
+	Connect  []Message  `yaml:"connect"`
+	Messages []Exchange `yaml:"messages"`
+	Push     []Push     `yaml:"push"`
+	Close    *Close     `yaml:"close"`
+}
+
+// A websocket service suite
+type Suite struct {
+	Endpoints []Endpoint `yaml:"websocket"`
+}
+
+// Load a suite. As with REST services, endpoints may be declared either under
+// the 'websocket' key or as a bare list.
+func LoadSuite(src io.Reader) (*Suite, error) {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+
+	suite := &Suite{}
+	err = unmarshal(data, suite)
+	if err != nil {
+		var endpoints []Endpoint
+		if unmarshal(data, &endpoints) != nil {
+			return nil, err
+		}
+		suite.Endpoints = endpoints
+	}
+
+	for i, e := range suite.Endpoints {
+		if e.Request == nil || e.Request.Path == "" {
+			return nil, fmt.Errorf("Endpoint #%d does not define a path", i+1)
+		}
+		for j, x := range e.Push {
+			if x.Every <= 0 && x.Count != 1 {
+				return nil, fmt.Errorf("Endpoint #%d, push #%d must define an interval ('every') unless it is sent once", i+1, j+1)
+			}
+		}
+	}
+
+	return suite, nil
+}
+
+func unmarshal(data []byte, dest interface{}) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	return dec.Decode(dest)
+}
@@ -0,0 +1,435 @@
+package websocket
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit/entity"
+	"github.com/instaunit/instaunit/hunit/expr"
+	"github.com/instaunit/instaunit/hunit/httputil/mimetype"
+	"github.com/instaunit/instaunit/hunit/service"
+	"github.com/instaunit/instaunit/hunit/service/admin"
+
+	"github.com/bww/go-router/v2"
+	"github.com/bww/go-util/v1/debug"
+	"github.com/bww/go-util/v1/text"
+	"github.com/gorilla/websocket"
+)
+
+// Don't wait forever
+const ioTimeout = time.Second * 10
+
+const prefix = "[websocket]"
+
+// Websocket service
+type websocketService struct {
+	sync.Mutex // guards the definition and connections
+	conf       service.Config
+	suite      *Suite
+	server     *http.Server
+	router     router.Router
+	endpoints  map[*router.Route]*Endpoint
+	upgrader   websocket.Upgrader
+	conns      map[*conn]struct{}
+}
+
+// Create a new service
+func New(conf service.Config) (service.Service, error) {
+	suite, err := LoadSuite(conf.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &websocketService{
+		conf: conf,
+		upgrader: websocket.Upgrader{
+			HandshakeTimeout: ioTimeout,
+			CheckOrigin:      func(*http.Request) bool { return true }, // anyone can connect to a mock
+		},
+		conns: make(map[*conn]struct{}),
+	}
+	s.setSuite(suite)
+
+	return s, nil
+}
+
+// Route the endpoints in a suite
+func (s *websocketService) setSuite(suite *Suite) {
+	r := router.New()
+	endpoints := make(map[*router.Route]*Endpoint)
+	for i, e := range suite.Endpoints {
+		route := r.Add(e.Request.Path, nil).Params(convertParams(e.Request.Params))
+		endpoints[route] = &suite.Endpoints[i]
+	}
+
+	s.Lock()
+	s.suite = suite
+	s.router = r
+	s.endpoints = endpoints
+	s.Unlock()
+}
+
+// Reload the service definition. Connections which are already established
+// continue with the endpoint they were opened with.
+func (s *websocketService) Reload(data []byte) error {
+	suite, err := LoadSuite(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	s.setSuite(suite)
+	return nil
+}
+
+// Start the service
+func (s *websocketService) Start() error {
+	if s.server != nil {
+		return fmt.Errorf("Service is running")
+	}
+
+	_, _, err := net.SplitHostPort(s.conf.Addr)
+	if err != nil {
+		return fmt.Errorf("Invalid address: %v", err)
+	}
+
+	s.server = &http.Server{
+		Addr:           s.conf.Addr,
+		Handler:        admin.New(nil).Handler(http.HandlerFunc(s.routeRequest)),
+		ReadTimeout:    ioTimeout,
+		MaxHeaderBytes: 1 << 20,
+	}
+
+	l, err := service.Listen(&s.conf)
+	if err != nil {
+		s.server = nil
+		return fmt.Errorf("Could not listen: %w", err)
+	}
+
+	go func() {
+		err := s.server.Serve(l)
+		if err != nil && err != http.ErrServerClosed {
+			panic(err)
+		}
+	}()
+
+	// wait for our service to start up
+	return service.AwaitStatus(s.conf, ioTimeout)
+}
+
+// The base URL of the service
+func (s *websocketService) URL() string {
+	return s.conf.URL()
+}
+
+// Stop the service. Hijacked connections are not managed by the server, so
+// we close every open connection ourselves.
+func (s *websocketService) Stop() error {
+	if s.server == nil {
+		return fmt.Errorf("Service is not running")
+	}
+	err := s.server.Close()
+	s.server = nil
+
+	s.Lock()
+	conns := s.conns
+	s.conns = make(map[*conn]struct{})
+	s.Unlock()
+	for c := range conns {
+		c.close(websocket.CloseGoingAway, "Service stopped")
+	}
+
+	return err
+}
+
+// Handle requests
+func (s *websocketService) routeRequest(rsp http.ResponseWriter, req *http.Request) {
+	// match our internal status endpoint; we don't allow this to be shadowed
+	// by defined endpoints so that we can monitor the service.
+	if req.Method == service.StatusMethod && req.URL.Path == service.StatusPath {
+		rsp.Header().Set("Server", "Instaunit/1")
+		rsp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		rsp.WriteHeader(http.StatusOK)
+		return
+	}
+
+	s.Lock()
+	r, endpoints := s.router, s.endpoints
+	s.Unlock()
+
+	route, match, err := r.Find((*router.Request)(req))
+	if err != nil {
+		fmt.Printf("%s * * * Could not route request: %v: %v\n", prefix, req.URL, err)
+		http.Error(rsp, "Could not route request", http.StatusInternalServerError)
+		return
+	}
+	var endpoint *Endpoint
+	if route != nil {
+		endpoint = endpoints[route]
+	}
+	if endpoint == nil || !headersMatch(endpoint.Request.Headers, req.Header) {
+		if debug.VERBOSE {
+			fmt.Printf("%s * * * No such endpoint: %v\n", prefix, req.URL.Path)
+		}
+		http.NotFound(rsp, req)
+		return
+	}
+
+	ws, err := s.upgrader.Upgrade(rsp, req, nil)
+	if err != nil {
+		fmt.Printf("%s * * * Could not upgrade connection: %v: %v\n", prefix, req.URL, err)
+		return // the upgrader has already responded
+	}
+	if debug.VERBOSE {
+		fmt.Printf("%s -> Connected: %s\n", prefix, req.URL.Path)
+	}
+
+	c := &conn{
+		ws:       ws,
+		url:      req.URL.String(),
+		endpoint: endpoint,
+		vars:     requestVars(req, match.Vars),
+		done:     make(chan struct{}),
+	}
+
+	s.Lock()
+	s.conns[c] = struct{}{}
+	s.Unlock()
+
+	go func() {
+		c.run()
+		s.Lock()
+		delete(s.conns, c)
+		s.Unlock()
+	}()
+}
+
+// A client connection
+type conn struct {
+	sync.Mutex // guards writes and closing
+	ws         *websocket.Conn
+	url        string
+	endpoint   *Endpoint
+	vars       expr.Variables
+	done       chan struct{}
+	closed     bool
+}
+
+// Run a connection until it is closed, by us or by the client
+func (c *conn) run() {
+	defer c.close(websocket.CloseNormalClosure, "")
+
+	for _, e := range c.endpoint.Connect {
+		if !c.pause(e.Wait) {
+			return
+		}
+		if err := c.send(e.Send, nil); err != nil {
+			c.log(err)
+			return
+		}
+	}
+
+	for _, e := range c.endpoint.Push {
+		go c.push(e)
+	}
+
+	if x := c.endpoint.Close; x != nil && x.Wait > 0 {
+		go func() {
+			if c.pause(x.Wait) {
+				c.close(x.Code, x.Reason)
+			}
+		}()
+	}
+
+	var count int
+	for {
+		t, msg, err := c.ws.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) && !c.isClosed() {
+				c.log(err)
+			}
+			return
+		}
+		if t != websocket.TextMessage && t != websocket.BinaryMessage {
+			continue
+		}
+		count++
+		if debug.VERBOSE {
+			fmt.Printf("%s -> %s (#%d)\n", prefix, c.url, count)
+			fmt.Println(text.Indent(string(msg), strings.Repeat(" ", len(prefix))+" > "))
+		}
+
+		var value interface{}
+		if v, err := entity.Unmarshal(mimetype.JSON, msg); err == nil {
+			value = v
+		}
+		mvars := expr.Variables{
+			"text":  string(msg),
+			"value": value, // if the message is JSON; this may be nil
+			"count": count,
+		}
+
+		for _, e := range c.endpoint.Messages {
+			m, err := e.Matches(msg)
+			if err != nil {
+				c.log(err)
+				return
+			}
+			if !m {
+				continue
+			}
+			if !c.pause(e.Wait) {
+				return
+			}
+			if e.Send != nil {
+				if err := c.send(*e.Send, expr.Variables{"message": mvars}); err != nil {
+					c.log(err)
+					return
+				}
+			}
+			if e.Close {
+				return
+			}
+			break // only the first matching exchange is used
+		}
+
+		if x := c.endpoint.Close; x != nil && x.After > 0 && count >= x.After {
+			c.close(x.Code, x.Reason)
+			return
+		}
+	}
+}
+
+// Push a message periodically until the connection is closed
+func (c *conn) push(p Push) {
+	if !c.pause(p.Wait) {
+		return
+	}
+	for n := 1; p.Count < 1 || n <= p.Count; n++ {
+		if n > 1 && !c.pause(p.Every) {
+			return
+		}
+		err := c.send(p.Send, expr.Variables{"push": expr.Variables{"count": n}})
+		if err != nil {
+			if !c.isClosed() {
+				c.log(err)
+			}
+			return
+		}
+	}
+}
+
+// Wait for an interval; false is returned if the connection is closed first
+func (c *conn) pause(d time.Duration) bool {
+	if d <= 0 {
+		return !c.isClosed()
+	}
+	select {
+	case <-c.done:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// Interpolate and send a message
+func (c *conn) send(msg string, vars expr.Variables) error {
+	v := expr.Variables{"request": c.vars}
+	for k, x := range vars {
+		v[k] = x
+	}
+	d, err := expr.Interpolate(msg, v)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return fmt.Errorf("Connection is closed")
+	}
+	if debug.VERBOSE {
+		fmt.Printf("%s <- %s\n", prefix, c.url)
+		fmt.Println(text.Indent(d, strings.Repeat(" ", len(prefix))+" < "))
+	}
+	c.ws.SetWriteDeadline(time.Now().Add(ioTimeout))
+	return c.ws.WriteMessage(websocket.TextMessage, []byte(d))
+}
+
+// Close the connection, if it is not already closed
+func (c *conn) close(code int, reason string) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.done)
+	if code == 0 {
+		code = websocket.CloseNormalClosure
+	}
+	c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	c.ws.Close()
+	if debug.VERBOSE {
+		fmt.Printf("%s -- Closed: %s (%d)\n", prefix, c.url, code)
+	}
+}
+
+func (c *conn) isClosed() bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.closed
+}
+
+func (c *conn) log(err error) {
+	fmt.Printf("%s * * * %v: %v\n", prefix, c.url, err)
+}
+
+// Variables describing the request that opened a connection
+func requestVars(req *http.Request, vars map[string]string) expr.Variables {
+	cvars := make(map[string]interface{})
+	for k, v := range vars {
+		cvars[k] = v
+	}
+	cparams := make(map[string]interface{})
+	for k, v := range req.URL.Query() {
+		if len(v) > 0 {
+			cparams[k] = v[0]
+		}
+	}
+	cheaders := make(map[string]interface{})
+	for k, v := range req.Header {
+		if len(v) > 0 {
+			cheaders[k] = v[0]
+		}
+	}
+	return expr.Variables{
+		"vars":    cvars,
+		"params":  cparams,
+		"headers": cheaders,
+	}
+}
+
+func headersMatch(expect map[string]string, actual http.Header) bool {
+	for k, v := range expect {
+		if actual.Get(k) != v {
+			return false
+		}
+	}
+	return true
+}
+
+func convertParams(p map[string]string) url.Values {
+	var r url.Values
+	if len(p) > 0 {
+		r = make(url.Values)
+		for k, v := range p {
+			r.Set(k, v)
+		}
+	}
+	return r
+}
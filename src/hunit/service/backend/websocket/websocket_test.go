@@ -0,0 +1,199 @@
+package websocket
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit/service"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// Create a service from a definition and serve it
+func newTestService(t *testing.T, src string) (*websocketService, *httptest.Server) {
+	svc, err := New(service.Config{Resource: io.NopCloser(strings.NewReader(src))})
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	s := svc.(*websocketService)
+	srv := httptest.NewServer(http.HandlerFunc(s.routeRequest))
+	t.Cleanup(func() {
+		srv.Close()
+		s.Lock()
+		conns := s.conns
+		s.conns = make(map[*conn]struct{})
+		s.Unlock()
+		for c := range conns {
+			c.close(websocket.CloseGoingAway, "")
+		}
+	})
+	return s, srv
+}
+
+// Connect to a service
+func dial(t *testing.T, srv *httptest.Server, path string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	ws, rsp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+path, header)
+	if err == nil {
+		ws.SetReadDeadline(time.Now().Add(time.Second * 5))
+		t.Cleanup(func() { ws.Close() })
+	}
+	return ws, rsp, err
+}
+
+// Read a text message
+func read(t *testing.T, ws *websocket.Conn) string {
+	_, msg, err := ws.ReadMessage()
+	assert.Nil(t, err)
+	return string(msg)
+}
+
+func TestReload(t *testing.T) {
+	s, srv := newTestService(t, `
+- endpoint: {path: /a}
+  connect: [{send: A}]
+`)
+
+	ws, _, err := dial(t, srv, "/a", nil)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "A", read(t, ws))
+	}
+
+	err = s.Reload([]byte(`
+- endpoint: {path: /b}
+  connect: [{send: B}]
+`))
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, rsp, err := dial(t, srv, "/a", nil)
+	if assert.NotNil(t, err) && assert.NotNil(t, rsp) {
+		assert.Equal(t, http.StatusNotFound, rsp.StatusCode)
+	}
+	ws, _, err = dial(t, srv, "/b", nil)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "B", read(t, ws))
+	}
+
+	// an invalid definition leaves the current one in place
+	assert.NotNil(t, s.Reload([]byte(`- endpoint: {params: {a: b}}`)))
+	ws, _, err = dial(t, srv, "/b", nil)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "B", read(t, ws))
+	}
+}
+
+const serviceSource = `
+websocket:
+  - endpoint:
+      path: /feeds/{feed}
+      params: {format: json}
+      headers: {Tenant: t1}
+    connect:
+      - send: '{"type": "subscribed", "feed": "${request.vars.feed}", "tenant": "${request.headers.Tenant}"}'
+    messages:
+      - receive: '{"type": "ping"}'
+        compare: semantic
+        send: '{"type": "pong", "seq": ${message.count}}'
+      - receive: bye
+        send: goodbye
+        close: true
+      - send: 'echo: ${message.text}'
+  - endpoint:
+      path: /updates
+    push:
+      - every: 10ms
+        count: 3
+        send: 'update ${push.count}'
+  - endpoint:
+      path: /limited
+    close:
+      after: 2
+      code: 4000
+      reason: Enough
+`
+
+func TestService(t *testing.T) {
+	_, srv := newTestService(t, serviceSource)
+
+	// the endpoint requires its parameters and headers
+	_, rsp, err := dial(t, srv, "/feeds/a", http.Header{"Tenant": {"t1"}})
+	if assert.NotNil(t, err) && assert.NotNil(t, rsp) {
+		assert.Equal(t, http.StatusNotFound, rsp.StatusCode)
+	}
+	_, rsp, err = dial(t, srv, "/feeds/a?format=json", nil)
+	if assert.NotNil(t, err) && assert.NotNil(t, rsp) {
+		assert.Equal(t, http.StatusNotFound, rsp.StatusCode)
+	}
+
+	// scripted exchanges
+	ws, _, err := dial(t, srv, "/feeds/a?format=json", http.Header{"Tenant": {"t1"}})
+	if assert.Nil(t, err) {
+		assert.Equal(t, `{"type": "subscribed", "feed": "a", "tenant": "t1"}`, read(t, ws))
+		assert.Nil(t, ws.WriteMessage(websocket.TextMessage, []byte(`{ "type":"ping" }`)))
+		assert.Equal(t, `{"type": "pong", "seq": 1}`, read(t, ws))
+		assert.Nil(t, ws.WriteMessage(websocket.TextMessage, []byte(`Hello`)))
+		assert.Equal(t, `echo: Hello`, read(t, ws))
+		assert.Nil(t, ws.WriteMessage(websocket.TextMessage, []byte(`{"type": "ping"}`)))
+		assert.Equal(t, `{"type": "pong", "seq": 3}`, read(t, ws))
+		assert.Nil(t, ws.WriteMessage(websocket.TextMessage, []byte(`bye`)))
+		assert.Equal(t, `goodbye`, read(t, ws))
+		_, _, err = ws.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), "Unexpected error: %v", err)
+	}
+
+	// pushed messages
+	ws, _, err = dial(t, srv, "/updates", nil)
+	if assert.Nil(t, err) {
+		for i := 1; i <= 3; i++ {
+			assert.Equal(t, fmt.Sprintf("update %d", i), read(t, ws))
+		}
+	}
+
+	// closed after a number of messages
+	ws, _, err = dial(t, srv, "/limited", nil)
+	if assert.Nil(t, err) {
+		for i := 0; i < 2; i++ {
+			assert.Nil(t, ws.WriteMessage(websocket.TextMessage, []byte(`Hello`)))
+		}
+		_, _, err = ws.ReadMessage()
+		if assert.IsType(t, &websocket.CloseError{}, err) {
+			assert.Equal(t, 4000, err.(*websocket.CloseError).Code)
+			assert.Equal(t, "Enough", err.(*websocket.CloseError).Text)
+		}
+	}
+}
+
+func TestStop(t *testing.T) {
+	svc, err := New(service.Config{Addr: "localhost:0", Resource: io.NopCloser(strings.NewReader(serviceSource))})
+	if !assert.Nil(t, err) {
+		return
+	}
+	if !assert.Nil(t, svc.Start()) {
+		return
+	}
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(svc.URL(), "http")+"/updates", nil)
+	if !assert.Nil(t, err) {
+		svc.Stop()
+		return
+	}
+	defer ws.Close()
+	ws.SetReadDeadline(time.Now().Add(time.Second * 5))
+	assert.Equal(t, "update 1", read(t, ws))
+
+	// open connections are closed when the service is stopped
+	assert.Nil(t, svc.Stop())
+	for {
+		_, _, err = ws.ReadMessage()
+		if err != nil {
+			break
+		}
+	}
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "Unexpected error: %v", err)
+	assert.NotNil(t, svc.Stop())
+}
@@ -1,49 +1,262 @@
 package service
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit/net/await"
+)
+
+// Status; every service exposes this endpoint so that we can tell when it's
+// available. Backends must not allow it to be shadowed by their own routes.
+const (
+	StatusMethod = "GET"
+	StatusPath   = "/_instaunit/status"
 )
 
 // A service
 type Service interface {
 	Start() error
 	Stop() error
+	URL() string // the base URL of the service, once it is started
+}
+
+// Service backend type
+type Backend uint32
+
+const (
+	BackendREST Backend = iota
+	BackendRecord
+	BackendReplay
+	BackendOpenAPI
+	BackendWebsocket
+	BackendInvalid
+)
+
+var backendNames = []string{
+	"rest",
+	"record",
+	"replay",
+	"openapi",
+	"websocket",
+	"<invalid>",
+}
+
+// Parse a backend
+func ParseBackend(s string) (Backend, error) {
+	switch s {
+	case "rest":
+		return BackendREST, nil
+	case "record":
+		return BackendRecord, nil
+	case "replay":
+		return BackendReplay, nil
+	case "openapi":
+		return BackendOpenAPI, nil
+	case "websocket":
+		return BackendWebsocket, nil
+	default:
+		return BackendInvalid, fmt.Errorf("Unsupported backend: %v", s)
+	}
+}
+
+// Stringer
+func (b Backend) String() string {
+	if b < 0 || b >= BackendInvalid {
+		return "<invalid>"
+	} else {
+		return backendNames[int(b)]
+	}
 }
 
 // Service config
 type Config struct {
+	Name     string // the name by which the service is referred to
 	Addr     string
+	Backend  Backend
 	Path     string
+	Upstream string // the upstream service proxied to, for backends that support it
+	TLS      *TLS   // if non-nil, the service is served over TLS
 	Resource io.ReadCloser
 }
 
-// Parse configuration
+// The base URL of the service
+func (c Config) URL() string {
+	scheme := "http"
+	if c.TLS != nil {
+		scheme = "https"
+	}
+	host, port, err := net.SplitHostPort(c.Addr)
+	if err != nil {
+		return fmt.Sprintf("%s://%s", scheme, c.Addr)
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, port))
+}
+
+// Describe the service configuration
+func (c Config) String() string {
+	switch {
+	case c.Upstream != "":
+		return fmt.Sprintf("%v: %v -> %v", c.Backend, c.Upstream, c.Path)
+	case c.Backend != BackendREST:
+		return fmt.Sprintf("%v: %v", c.Backend, c.Path)
+	default:
+		return c.Path
+	}
+}
+
+// Parse configuration. Services are specified as
+// '[host]:<port>=[backend:]<resource>'; the backend is REST if it is omitted.
+// The record backend additionally requires an upstream, which is provided as
+// 'record:<resource>@<upstream URL>'. If the address is prefixed with
+// 'https://' the service is served over TLS; the caller is expected to
+// provide the certificate or authority to use.
 func ParseConfig(s string) (Config, error) {
 	var conf Config
 
-	p := strings.Split(s, "=")
-	if len(p) != 2 {
+	addr, rc, ok := strings.Cut(s, "=")
+	if !ok {
 		return conf, fmt.Errorf("Invalid service: %v", s)
 	}
 
-	if len(p[0]) < 1 {
+	addr, conf.TLS = ParseAddr(addr)
+
+	if len(addr) < 1 {
 		return conf, fmt.Errorf("Invalid service address: %v", s)
 	}
-	if len(p[1]) < 1 {
+
+	backend := BackendREST
+	if x, p, ok := strings.Cut(rc, ":"); ok {
+		if b, err := ParseBackend(x); err == nil {
+			backend, rc = b, p
+		}
+	}
+
+	var upstream string
+	if backend == BackendRecord {
+		var ok bool
+		rc, upstream, ok = strings.Cut(rc, "@")
+		if !ok || len(upstream) < 1 {
+			return conf, fmt.Errorf("Invalid service upstream: %v", s)
+		}
+	}
+
+	if len(rc) < 1 {
 		return conf, fmt.Errorf("Invalid service resource: %v", s)
 	}
 
-	f, err := os.Open(p[1])
+	conf.Name = NameFromPath(rc)
+	conf.Addr = addr
+	conf.Backend = backend
+	conf.Path = rc
+	conf.Upstream = upstream
+
+	// recordings are written by the service, so the resource may not exist yet;
+	// the backend manages it itself.
+	if backend != BackendRecord {
+		f, err := os.Open(rc)
+		if err != nil {
+			return conf, err
+		}
+		conf.Resource = f
+	}
+
+	return conf, nil
+}
+
+// Listen on the address the service is configured for. If the port is 0, a
+// port is assigned and the configuration is updated to reflect it. If the
+// service is served over TLS, the listener negotiates TLS and HTTP/2.
+func Listen(c *Config) (net.Listener, error) {
+	l, err := net.Listen("tcp", c.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if host, port, err := net.SplitHostPort(c.Addr); err == nil && port == "0" {
+		if a, ok := l.Addr().(*net.TCPAddr); ok {
+			c.Addr = net.JoinHostPort(host, strconv.Itoa(a.Port))
+		}
+	}
+	if c.TLS == nil {
+		return l, nil
+	}
+	conf, err := c.TLS.Config(c.Addr)
 	if err != nil {
-		return conf, err
+		l.Close()
+		return nil, err
 	}
+	return tls.NewListener(l, conf), nil
+}
 
-	conf.Addr = p[0]
-	conf.Path = p[1]
-	conf.Resource = f
+// Produce the default name of a service from the path of the resource which
+// defines it; this is the file name without its extension.
+func NameFromPath(p string) string {
+	b := filepath.Base(p)
+	return strings.TrimSuffix(b, filepath.Ext(b))
+}
 
-	return conf, nil
+// Parse a service address. If the address is prefixed with 'https://' the
+// service is served over TLS and the configuration to use is returned.
+func ParseAddr(s string) (string, *TLS) {
+	if a, ok := strings.CutPrefix(s, "https://"); ok {
+		return a, &TLS{}
+	} else {
+		return strings.TrimPrefix(s, "http://"), nil
+	}
+}
+
+// Wait for the service to report that it is available via its status
+// endpoint.
+func AwaitStatus(c Config, timeout time.Duration) error {
+	status := c.URL() + StatusPath
+	var err error
+	if c.TLS != nil {
+		err = awaitTLS(status, timeout)
+	} else {
+		err = await.Await(context.Background(), []string{status}, timeout)
+	}
+	if err == await.ErrTimeout {
+		return fmt.Errorf("Timed out waiting for service: %s", status)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Services served over TLS usually present a certificate we don't trust;
+// since we're only checking our own service, we don't verify it.
+func awaitTLS(status string, timeout time.Duration) error {
+	client := &http.Client{
+		Timeout: time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	defer client.CloseIdleConnections()
+	deadline := time.Now().Add(timeout)
+	for {
+		rsp, err := client.Get(status)
+		if err == nil {
+			rsp.Body.Close()
+			if rsp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return await.ErrTimeout
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
 }
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"time"
+)
+
+// Generated authority files
+const (
+	AuthorityCert = "ca.pem"
+	AuthorityKey  = "ca-key.pem"
+)
+
+// TLS configuration. If a certificate and key are provided they are used
+// as-is; otherwise a certificate is issued by a self-signed authority which is
+// written to the authority directory. The authority is reused if it already
+// exists so that a system under test only needs to trust it once.
+type TLS struct {
+	Cert      string
+	Key       string
+	Authority string
+}
+
+// Produce the server configuration for a service listening on the provided
+// address. HTTP/2 is negotiated with clients that support it.
+func (t TLS) Config(addr string) (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+	if t.Cert != "" || t.Key != "" {
+		cert, err = tls.LoadX509KeyPair(t.Cert, t.Key)
+		if err != nil {
+			return nil, fmt.Errorf("Could not load certificate: %w", err)
+		}
+	} else {
+		if t.Authority == "" {
+			return nil, fmt.Errorf("No certificate or authority provided")
+		}
+		ca, key, err := loadAuthority(t.Authority)
+		if err != nil {
+			return nil, err
+		}
+		cert, err = issueCert(ca, key, addr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// Load the authority from the provided directory, creating it if necessary
+func loadAuthority(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	cpath, kpath := path.Join(dir, AuthorityCert), path.Join(dir, AuthorityKey)
+
+	pair, err := tls.LoadX509KeyPair(cpath, kpath)
+	if err == nil {
+		key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, nil, fmt.Errorf("Unsupported authority key: %s", kpath)
+		}
+		ca, err := x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			return nil, nil, fmt.Errorf("Could not parse authority: %w", err)
+		}
+		return ca, key, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, fmt.Errorf("Could not load authority: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serialNumber(),
+		Subject:               pkix.Name{Organization: []string{"Instaunit"}, CommonName: "Instaunit Mock Service Authority"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not create authority: %w", err)
+	}
+	kder, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not create authority directory: %w", err)
+	}
+	err = os.WriteFile(kpath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kder}), 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not write authority key: %w", err)
+	}
+	err = os.WriteFile(cpath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not write authority: %w", err)
+	}
+
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ca, key, nil
+}
+
+// Issue a certificate for a service. The certificate is valid for the local
+// host and for the host the service listens on, if it is specified.
+func issueCert(ca *x509.Certificate, cakey *ecdsa.PrivateKey, addr string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	names := []string{"localhost"}
+	ips := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" {
+		if ip := net.ParseIP(host); ip != nil {
+			ips = append(ips, ip)
+		} else if host != "localhost" {
+			names = append(names, host)
+		}
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serialNumber(),
+		Subject:      pkix.Name{Organization: []string{"Instaunit"}, CommonName: names[len(names)-1]},
+		DNSNames:     names,
+		IPAddresses:  ips,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, cakey)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("Could not issue certificate: %w", err)
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der, ca.Raw},
+		PrivateKey:  key,
+	}, nil
+}
+
+func serialNumber() *big.Int {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenTLS(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "authority")
+	conf := &Config{Addr: "localhost:0", TLS: &TLS{Authority: dir}}
+
+	l, err := Listen(conf)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.False(t, strings.HasSuffix(conf.Addr, ":0"), conf.Addr) // the assigned port
+	assert.True(t, strings.HasPrefix(conf.URL(), "https://localhost:"), conf.URL())
+
+	server := &http.Server{Handler: http.HandlerFunc(func(rsp http.ResponseWriter, req *http.Request) {
+		io.WriteString(rsp, req.Proto)
+	})}
+	go server.Serve(l)
+	defer server.Close()
+
+	ca, err := os.ReadFile(filepath.Join(dir, AuthorityCert))
+	if !assert.Nil(t, err) {
+		return
+	}
+	roots := x509.NewCertPool()
+	if !assert.True(t, roots.AppendCertsFromPEM(ca)) {
+		return
+	}
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+		ForceAttemptHTTP2: true,
+	}}
+	defer client.CloseIdleConnections()
+
+	// the certificate is issued by the authority and HTTP/2 is negotiated
+	rsp, err := client.Get(conf.URL() + "/")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer rsp.Body.Close()
+	data, err := io.ReadAll(rsp.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, rsp.ProtoMajor)
+	assert.Equal(t, "HTTP/2.0", string(data))
+
+	// the authority is reused by other services
+	_, err = (TLS{Authority: dir}).Config("127.0.0.1:0")
+	if assert.Nil(t, err) {
+		reused, err := os.ReadFile(filepath.Join(dir, AuthorityCert))
+		assert.Nil(t, err)
+		assert.Equal(t, ca, reused)
+	}
+}
+
+func TestIssueCert(t *testing.T) {
+	ca, key, err := loadAuthority(t.TempDir())
+	if !assert.Nil(t, err) {
+		return
+	}
+	tests := []struct {
+		Addr  string
+		Names []string
+		IPs   int
+	}{
+		{":8443", []string{"localhost"}, 2},
+		{"localhost:8443", []string{"localhost"}, 2},
+		{"api.local:8443", []string{"localhost", "api.local"}, 2},
+		{"10.0.0.1:8443", []string{"localhost"}, 3},
+	}
+	for _, e := range tests {
+		cert, err := issueCert(ca, key, e.Addr)
+		if !assert.Nil(t, err, e.Addr) {
+			continue
+		}
+		x, err := x509.ParseCertificate(cert.Certificate[0])
+		if !assert.Nil(t, err, e.Addr) {
+			continue
+		}
+		assert.Equal(t, e.Names, x.DNSNames, e.Addr)
+		assert.Len(t, x.IPAddresses, e.IPs, e.Addr)
+		assert.Nil(t, x.CheckSignatureFrom(ca), e.Addr)
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// Services whose definition can be replaced while they are running implement
+// this interface. The definition is provided in the same format as the
+// resource the service was created from. If it cannot be loaded an error is
+// returned and the service continues with its current definition.
+type Reloader interface {
+	Reload(data []byte) error
+}
+
+// Watch a file for changes until the context is canceled. When the file
+// changes, the handler is invoked with its contents.
+//
+// The file is polled, and a change is only reported once the file has stopped
+// changing for an interval, so that we don't read a file that is in the
+// process of being written.
+func Watch(cxt context.Context, p string, iv time.Duration, handler func(data []byte, err error)) error {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		loaded, prev := stateOf(fi), stateOf(fi)
+		t := time.NewTicker(iv)
+		defer t.Stop()
+		for {
+			select {
+			case <-cxt.Done():
+				return
+			case <-t.C:
+			}
+			fi, err := os.Stat(p)
+			if err != nil {
+				continue // the file may be being replaced; try again later
+			}
+			curr := stateOf(fi)
+			if curr != loaded && curr == prev {
+				loaded = curr
+				handler(os.ReadFile(p))
+			}
+			prev = curr
+		}
+	}()
+
+	return nil
+}
+
+type fileState struct {
+	mod  time.Time
+	size int64
+}
+
+func stateOf(fi os.FileInfo) fileState {
+	return fileState{fi.ModTime(), fi.Size()}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "endpoints.yml")
+	if !assert.Nil(t, os.WriteFile(p, []byte("a"), 0644)) {
+		return
+	}
+
+	cxt, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan string, 10)
+	err := Watch(cxt, p, time.Millisecond*10, func(data []byte, err error) {
+		assert.Nil(t, err)
+		changes <- string(data)
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	select {
+	case v := <-changes:
+		t.Fatalf("Unexpected change: %q", v)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	if !assert.Nil(t, os.WriteFile(p, []byte("bb"), 0644)) {
+		return
+	}
+	select {
+	case v := <-changes:
+		assert.Equal(t, "bb", v)
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for change")
+	}
+
+	err = Watch(cxt, filepath.Join(filepath.Dir(p), "missing.yml"), time.Millisecond*10, func([]byte, error) {})
+	assert.NotNil(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/instaunit/instaunit/hunit/har"
 	"github.com/instaunit/instaunit/hunit/runtime"
 	"github.com/instaunit/instaunit/hunit/testcase"
 
@@ -29,11 +30,12 @@ type StreamMonitor struct {
 	finish   chan struct{}
 	valid    bool
 	result   *Result
+	capture  *har.Socket // records messages, if non-nil
 }
 
 // Create a stream monitor for the provided stream
 func NewStreamMonitor(url string, context runtime.Context, conn *websocket.Conn, messages []testcase.MessageExchange) *StreamMonitor {
-	return &StreamMonitor{sync.Mutex{}, url, context, conn, messages, nil, false, nil}
+	return &StreamMonitor{sync.Mutex{}, url, context, conn, messages, nil, false, nil, nil}
 }
 
 // Run the stream monitor
@@ -76,6 +78,7 @@ outer:
 				fmt.Println("---->", m.url)
 				fmt.Println(text.Indent(d, "      > "))
 			}
+			m.capture.Send(d)
 			for len(d) > 0 {
 				n, err := w.Write([]byte(d))
 				if err != nil {
@@ -101,6 +104,7 @@ outer:
 				result.Error(err)
 				break outer
 			}
+			m.capture.Receive(string(d))
 			if debug.VERBOSE {
 				fmt.Println()
 				fmt.Println("---->", m.url)
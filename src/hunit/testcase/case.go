@@ -96,6 +96,7 @@ type Route struct {
 type Frame struct {
 	Vars map[string]interface{}
 	Case Case
+	Row  int // the row of the matrix the frame is produced for, from 1; zero if the case is not part of a matrix
 }
 
 // Implemented by types that can produce test frames
@@ -124,11 +125,12 @@ func (m *Matrix) Annotate(node *yaml.Node, src Source) error {
 // Produce a frame for every case in the matrix
 func (m Matrix) Frames() []Frame {
 	var r []Frame
-	for _, v := range m.Vars {
+	for i, v := range m.Vars {
 		for _, c := range m.Cases {
 			r = append(r, Frame{
 				Vars: v,
 				Case: *c,
+				Row:  i + 1,
 			})
 		}
 	}
@@ -42,6 +42,16 @@ type Dependencies struct {
 	Timeout   time.Duration `yaml:"timeout"`
 }
 
+// A mock service. The service is defined by the endpoints in a file or by
+// endpoints declared inline, in the format used by its backend.
+type Service struct {
+	Addr      string    `yaml:"addr"`
+	Backend   string    `yaml:"backend"`
+	File      string    `yaml:"file"` // relative to the suite
+	Upstream  string    `yaml:"upstream"`
+	Endpoints yaml.Node `yaml:"endpoints"`
+}
+
 // A contents section
 type Section struct {
 	Key   string `yaml:"key"`
@@ -71,6 +81,7 @@ type Suite struct {
 	Transform TransformCollection       `yaml:"transform"`
 	Exec      *exec.Command             `yaml:"process"`
 	Deps      *Dependencies             `yaml:"depends"`
+	Services  map[string]*Service       `yaml:"services"`
 	Globals   map[string]interface{}    `yaml:"vars"`
 }
 
@@ -134,6 +145,7 @@ func LoadSuiteFromData(conf *Config, p, b string, data []byte) (*Suite, error) {
 	var globals map[string]interface{}
 	var cases []*caseOrMatrix
 	var authns map[string]Authentication
+	var services map[string]*Service
 	for _, e := range suite.Imports {
 		sub, err := LoadSuiteFromFile(conf, filepath.Join(b, e))
 		if err != nil {
@@ -155,6 +167,13 @@ func LoadSuiteFromData(conf *Config, p, b string, data []byte) (*Suite, error) {
 			}
 			maps.Merge(authns, sub.Authns)
 		}
+		// services
+		if len(sub.Services) > 0 {
+			if services == nil {
+				services = make(map[string]*Service)
+			}
+			maps.Merge(services, sub.Services)
+		}
 	}
 
 	// ...globals
@@ -171,6 +190,17 @@ func LoadSuiteFromData(conf *Config, p, b string, data []byte) (*Suite, error) {
 		suite.Authns = maps.Merged(authns, suite.Authns)
 	}
 
+	// ...current services, whose files are relative to this suite; imported
+	// services have already been resolved relative to theirs
+	for _, e := range suite.Services {
+		if e != nil && e.File != "" && !filepath.IsAbs(e.File) {
+			e.File = filepath.Join(b, e.File)
+		}
+	}
+	if services != nil {
+		suite.Services = maps.Merged(services, suite.Services)
+	}
+
 	*conf = suite.Config
 	return suite, nil
 }
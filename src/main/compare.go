@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/instaunit/instaunit/hunit/history"
+	"github.com/instaunit/instaunit/hunit/report/emit/json"
+
+	"github.com/fatih/color"
+	flag "github.com/spf13/pflag"
+)
+
+// Compare the results of two runs
+func compareCommand(args []string) int {
+	cmdline := flag.NewFlagSet("compare", flag.ExitOnError)
+	var (
+		file     string
+		ratio    float64
+		minDelta time.Duration
+	)
+	cmdline.StringVar(&file, "file", coalesce(os.Getenv("HUNIT_HISTORY_FILE"), historyFile), "The history file from which runs are loaded. Overrides: $HUNIT_HISTORY_FILE.")
+	cmdline.Float64Var(&ratio, "threshold", 0.5, "The proportional change in the duration of a case that is considered significant.")
+	cmdline.DurationVar(&minDelta, "min-delta", time.Millisecond*10, "The absolute change in the duration of a case below which changes are not considered significant.")
+	cmdline.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s compare [options] <base> <head>\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Compare the results of a run to those of a base run. Runs are either JSON reports or, specified as '@<run>', runs recorded in the history file; '@latest' and '@previous' refer to the most recent runs. Exits with an error if any case is newly failing.")
+		cmdline.PrintDefaults()
+	}
+	cmdline.Parse(args)
+	if cmdline.NArg() != 2 {
+		cmdline.Usage()
+		return 1
+	}
+
+	var hist []history.Record
+	load := func(s string) ([]history.Record, error) {
+		if !strings.HasPrefix(s, "@") {
+			f, err := os.Open(s)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			r, err := json.Load(f)
+			if err != nil {
+				return nil, fmt.Errorf("Could not load report: %s: %w", s, err)
+			}
+			return history.FromReport(r), nil
+		}
+		if hist == nil {
+			var err error
+			hist, err = history.Read(file)
+			if err != nil {
+				return nil, fmt.Errorf("Could not read history: %w", err)
+			}
+		}
+		run, runs := s[1:], history.Runs(hist)
+		switch run {
+		case "latest":
+			if len(runs) < 1 {
+				return nil, fmt.Errorf("No runs have been recorded: %s", file)
+			}
+			run = runs[len(runs)-1]
+		case "previous":
+			if len(runs) < 2 {
+				return nil, fmt.Errorf("Fewer than two runs have been recorded: %s", file)
+			}
+			run = runs[len(runs)-2]
+		}
+		recs := history.Run(hist, run)
+		if len(recs) == 0 {
+			return nil, fmt.Errorf("No such run: %s", run)
+		}
+		return recs, nil
+	}
+
+	base, err := load(cmdline.Arg(0))
+	if err != nil {
+		color.New(colorErr...).Printf("* * * %v\n", err)
+		return 1
+	}
+	head, err := load(cmdline.Arg(1))
+	if err != nil {
+		color.New(colorErr...).Printf("* * * %v\n", err)
+		return 1
+	}
+
+	cmp := history.Compare(base, head, history.Thresholds{Ratio: ratio, Min: minDelta})
+	fmt.Printf("----> Comparing %s to %s\n", cmdline.Arg(1), cmdline.Arg(0))
+	printChanges("Newly failing", colorErr, cmp.Failing, func(c history.Change) string { return "" })
+	printChanges("Newly passing", nil, cmp.Passing, func(c history.Change) string { return "" })
+	printChanges("Added", nil, cmp.Added, func(c history.Change) string { return c.After })
+	printChanges("Removed", nil, cmp.Removed, func(c history.Change) string { return c.Before })
+	printChanges("Slower", colorErr, cmp.Slower, formatDelta)
+	printChanges("Faster", nil, cmp.Faster, formatDelta)
+
+	n := len(cmp.Failing) + len(cmp.Passing) + len(cmp.Added) + len(cmp.Removed) + len(cmp.Slower) + len(cmp.Faster)
+	if n == 0 {
+		fmt.Println("----> No changes")
+	}
+	if len(cmp.Failing) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printChanges(title string, attrs []color.Attribute, changes []history.Change, detail func(history.Change) string) {
+	if len(changes) == 0 {
+		return
+	}
+	color.New(attrs...).Printf("\n====> %s (%d)\n", title, len(changes))
+	for _, e := range changes {
+		if d := detail(e); d != "" {
+			fmt.Printf("      %s: %s (%s)\n", e.Case, truncate(e.Name, 80), d)
+		} else {
+			fmt.Printf("      %s: %s\n", e.Case, truncate(e.Name, 80))
+		}
+	}
+}
+
+func formatDelta(c history.Change) string {
+	return fmt.Sprintf("%s → %s, %+.0f%%", formatSeconds(c.Base), formatSeconds(c.Head), c.Delta()*100)
+}
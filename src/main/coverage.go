@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/instaunit/instaunit/hunit/coverage"
+	"github.com/instaunit/instaunit/hunit/openapi"
+
+	"github.com/fatih/color"
+)
+
+// Create a coverage tracker for the operations declared by an OpenAPI
+// document. Only requests to the host of the base URL are considered.
+func newCoverage(spec, baseURL string) (*coverage.Coverage, error) {
+	doc, err := openapi.LoadFile(spec)
+	if err != nil {
+		return nil, fmt.Errorf("Could not load coverage spec: %w", err)
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid base URL: %w", err)
+	}
+	return coverage.New(doc, u.Host), nil
+}
+
+// Summarize coverage and, if an output path is provided, write a report
+func reportCoverage(cov *coverage.Coverage, dest string) error {
+	rep := cov.Report()
+
+	fmt.Printf("----> Coverage: %d of %d operations tested\n", rep.Covered(), len(rep.Operations))
+	var untested, statuses []string
+	for _, e := range rep.Operations {
+		if !e.Covered() {
+			untested = append(untested, fmt.Sprintf("%s %s", e.Method, e.Path))
+		} else if len(e.Untested) > 0 {
+			statuses = append(statuses, fmt.Sprintf("%s %s: %s", e.Method, e.Path, strings.Join(e.Untested, ", ")))
+		}
+	}
+	if len(untested) > 0 {
+		color.New(colorErr...).Printf("\n====> Untested operations (%d)\n", len(untested))
+		for _, e := range untested {
+			fmt.Println("      " + e)
+		}
+	}
+	if len(statuses) > 0 {
+		color.New(colorErr...).Printf("\n====> Untested responses (%d)\n", len(statuses))
+		for _, e := range statuses {
+			fmt.Println("      " + e)
+		}
+	}
+	if len(rep.Undeclared) > 0 {
+		color.New(colorErr...).Printf("\n====> Requests to undeclared operations (%d)\n", len(rep.Undeclared))
+		for _, e := range rep.Undeclared {
+			fmt.Printf("      %s %s (%d)\n", e.Method, e.Path, e.Requests)
+		}
+	}
+	fmt.Println()
+
+	if dest == "" {
+		return nil
+	}
+	err := os.MkdirAll(path.Dir(dest), 0o755)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return rep.Write(f)
+}
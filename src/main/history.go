@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/instaunit/instaunit/hunit/history"
+
+	"github.com/fatih/color"
+	flag "github.com/spf13/pflag"
+)
+
+// The default history file
+const historyFile = "./.instaunit/history.jsonl"
+
+// Summarize the history of test cases
+func historyCommand(args []string) int {
+	cmdline := flag.NewFlagSet("history", flag.ExitOnError)
+	var (
+		file      string
+		onlyFlaky bool
+	)
+	cmdline.StringVar(&file, "file", coalesce(os.Getenv("HUNIT_HISTORY_FILE"), historyFile), "The history file to summarize. Overrides: $HUNIT_HISTORY_FILE.")
+	cmdline.BoolVar(&onlyFlaky, "flaky", false, "Only display cases that are flaky; that is, whose outcome has changed between runs of the same version of their suite.")
+	cmdline.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s history [options] [suite ...]\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Summarize the results recorded by running tests with --history. Provide suites to limit the summary to their cases.")
+		cmdline.PrintDefaults()
+	}
+	cmdline.Parse(args)
+
+	recs, err := history.Read(file)
+	if err != nil {
+		color.New(colorErr...).Printf("* * * Could not read history: %v\n", err)
+		return 1
+	}
+
+	var filtered []history.Record
+	if suites := cmdline.Args(); len(suites) > 0 {
+		for _, e := range recs {
+			for _, s := range suites {
+				if e.Suite == s {
+					filtered = append(filtered, e)
+					break
+				}
+			}
+		}
+	} else {
+		filtered = recs
+	}
+
+	runs := make(map[string]struct{})
+	for _, e := range filtered {
+		runs[e.Run] = struct{}{}
+	}
+	stats := history.Analyze(filtered)
+	fmt.Printf("----> %d cases in %d runs: %s\n", len(stats), len(runs), file)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CASE\tRUNS\tPASS RATE\tSKIPPED\tFLAKY\tMEAN\tLAST\tTREND\tNAME")
+	var flaky int
+	for _, e := range stats {
+		if e.Flaky() {
+			flaky++
+		} else if onlyFlaky {
+			continue
+		}
+		var f, trend string
+		if e.Flaky() {
+			f = fmt.Sprintf("yes (%d)", e.Flips)
+		}
+		if e.Trend != 0 {
+			trend = fmt.Sprintf("%+.0f%%", e.Trend*100)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%.0f%%\t%d\t%s\t%s\t%s\t%s\t%s\n", e.Case, e.Runs, e.PassRate()*100, e.Skipped, f, formatSeconds(e.Mean), formatSeconds(e.Last), trend, truncate(e.Name, 60))
+	}
+	w.Flush()
+
+	if flaky > 0 {
+		color.New(colorErr...).Printf("\n----> %d flaky cases\n", flaky)
+	}
+	return 0
+}
+
+func formatSeconds(v float64) string {
+	if v < 1 {
+		return fmt.Sprintf("%.1fms", v*1000)
+	}
+	return fmt.Sprintf("%.2fs", v)
+}
+
+func truncate(s string, n int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
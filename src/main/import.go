@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/instaunit/instaunit/hunit/importer"
+
+	"github.com/fatih/color"
+	flag "github.com/spf13/pflag"
+)
+
+// Generate a starter suite from an API description or recording
+func importCommand(args []string) int {
+	cmdline := flag.NewFlagSet("import", flag.ExitOnError)
+	var (
+		formatSpec string
+		baseURL    string
+		output     string
+	)
+	cmdline.StringVar(&formatSpec, "format", "", "The format of the source: 'openapi', 'har', or 'postman'. If omitted, the format is detected from the source.")
+	cmdline.StringVar(&baseURL, "base-url", os.Getenv("HUNIT_BASE_URL"), "Requests to URLs under the base URL are made relative to it, so the suite can be run against any base URL. Overrides: $HUNIT_BASE_URL.")
+	cmdline.StringVarP(&output, "output", "o", "", "The path to write the suite to. If omitted, the suite is written to standard output.")
+	cmdline.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s import [options] <source>\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Generate a starter test suite from an OpenAPI 3 document, with one test per operation; an HTTP Archive (HAR), with one test per recorded exchange that expects the response that was recorded; or a Postman collection, with one test per request.")
+		cmdline.PrintDefaults()
+	}
+	cmdline.Parse(args)
+	if cmdline.NArg() != 1 {
+		cmdline.Usage()
+		return 1
+	}
+
+	src := cmdline.Arg(0)
+	data, err := os.ReadFile(src)
+	if err != nil {
+		color.New(colorErr...).Printf("* * * Could not read source: %v\n", err)
+		return 1
+	}
+
+	var format importer.Format
+	if formatSpec != "" {
+		format, err = importer.ParseFormat(formatSpec)
+	} else {
+		format, err = importer.Detect(data)
+	}
+	if err != nil {
+		color.New(colorErr...).Printf("* * * %v: %s\n", err, src)
+		return 1
+	}
+
+	suite, err := importer.Import(format, data, importer.Config{BaseURL: baseURL})
+	if err != nil {
+		color.New(colorErr...).Printf("* * * Could not import %s: %v\n", format, err)
+		return 1
+	}
+
+	b := &bytes.Buffer{}
+	err = importer.Write(b, suite)
+	if err != nil {
+		color.New(colorErr...).Printf("* * * Could not write suite: %v\n", err)
+		return 1
+	}
+	if output == "" {
+		os.Stdout.Write(b.Bytes())
+		return 0
+	}
+	err = os.WriteFile(output, b.Bytes(), 0644)
+	if err != nil {
+		color.New(colorErr...).Printf("* * * Could not write suite: %v\n", err)
+		return 1
+	}
+	fmt.Printf("----> Imported %d %s from %s to %s\n", len(suite.Cases), plural(len(suite.Cases), "test", "tests"), src, output)
+	return 0
+}
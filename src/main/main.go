@@ -13,18 +13,22 @@ import (
 
 	"github.com/instaunit/instaunit/hunit"
 	"github.com/instaunit/instaunit/hunit/cache"
+	"github.com/instaunit/instaunit/hunit/coverage"
 	"github.com/instaunit/instaunit/hunit/doc"
+	"github.com/instaunit/instaunit/hunit/doc/sample"
 	"github.com/instaunit/instaunit/hunit/exec"
+	"github.com/instaunit/instaunit/hunit/har"
+	"github.com/instaunit/instaunit/hunit/history"
 	"github.com/instaunit/instaunit/hunit/net/await"
-	"github.com/instaunit/instaunit/hunit/report"
 	"github.com/instaunit/instaunit/hunit/runtime"
 	"github.com/instaunit/instaunit/hunit/service"
-	"github.com/instaunit/instaunit/hunit/service/backend/rest"
 	"github.com/instaunit/instaunit/hunit/syncio"
 	"github.com/instaunit/instaunit/hunit/testcase"
 	"github.com/instaunit/instaunit/hunit/text"
 
 	doc_emit "github.com/instaunit/instaunit/hunit/doc/emit"
+	doc_openapi "github.com/instaunit/instaunit/hunit/doc/emit/openapi"
+	doc_postman "github.com/instaunit/instaunit/hunit/doc/emit/postman"
 	report_emit "github.com/instaunit/instaunit/hunit/report/emit"
 
 	"github.com/bww/go-util/v1/debug"
@@ -54,8 +58,21 @@ func main() {
 	os.Exit(app())
 }
 
+// Commands other than running tests, named by the first argument
+var commands = map[string]func(args []string) int{
+	"history": historyCommand,
+	"compare": compareCommand,
+	"import":  importCommand,
+}
+
 // You know what it does
 func app() int {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			return cmd(os.Args[2:])
+		}
+	}
+
 	var tests, skipped, failures, errno int
 	var headerSpecs, serviceSpecs, awaitURLs []string
 
@@ -70,13 +87,25 @@ func app() int {
 		genDoc          bool
 		docpath         string
 		doctypeSpec     string
+		docAPIFormat    string
+		docAPIBase      string
+		docSamples      []string
 		docInclHTTP     bool
 		docFormatEntity bool
-		genReport       bool
+		reportSpecs     reportSpecs
 		reportPath      string
 		reportType      string
 		cacheResults    bool
+		saveHistory     bool
+		historyPath     string
+		coverageSpec    string
+		coveragePath    string
+		harPath         string
 		ioGracePeriod   time.Duration
+		serviceCert     string
+		serviceKey      string
+		serviceCA       string
+		serviceWatch    bool
 		execCmd         string
 		execLog         string
 		maxRedirs       int
@@ -95,13 +124,21 @@ func app() int {
 	cmdline.BoolVar(&dumpResponse, "dump:response", strToBool(os.Getenv("HUNIT_DUMP_RESPONSES")), "Dump responses to standard output as they are processed. Overrides: $HUNIT_DUMP_RESPONSES.")
 	cmdline.BoolVar(&genDoc, "gendoc", strToBool(os.Getenv("HUNIT_GENDOC")), "Generate documentation. Overrides: $HUNIT_GENDOC.")
 	cmdline.StringVar(&docpath, "doc:output", coalesce(os.Getenv("HUNIT_DOC_OUTPUT"), "./docs"), "The directory in which generated documentation should be written. Overrides: $HUNIT_DOC_OUTPUT.")
-	cmdline.StringVar(&doctypeSpec, "doc:type", coalesce(os.Getenv("HUNIT_DOC_TYPE"), "markdown"), "The format to generate documentation in. Overrides: $HUNIT_DOC_TYPE.")
+	cmdline.StringVar(&doctypeSpec, "doc:type", coalesce(os.Getenv("HUNIT_DOC_TYPE"), "markdown"), "The format to generate documentation in: 'markdown', 'instadoc', 'openapi', 'html', or 'postman'. Overrides: $HUNIT_DOC_TYPE.")
+	cmdline.StringVar(&docAPIFormat, "doc:openapi:format", coalesce(os.Getenv("HUNIT_DOC_OPENAPI_FORMAT"), "json"), "The format to write OpenAPI documentation in: 'json' or 'yaml'. Overrides: $HUNIT_DOC_OPENAPI_FORMAT.")
+	cmdline.StringVar(&docAPIBase, "doc:openapi:base", os.Getenv("HUNIT_DOC_OPENAPI_BASE"), "A hand-written OpenAPI document to merge generated operations into. The base document provides metadata like info, servers, and security schemes, and takes precedence over generated content. Overrides: $HUNIT_DOC_OPENAPI_BASE.")
+	cmdline.StringSliceVar(&docSamples, "doc:samples", strToList(coalesce(os.Getenv("HUNIT_DOC_SAMPLES"), "none")), "The languages to produce request code samples in: 'curl', 'httpie', 'go', 'python', and 'javascript'; by default, no samples are produced. Samples are included in markdown, instadoc, and html documentation. The values of credential headers and of headers set with --header are redacted from samples. Overrides: $HUNIT_DOC_SAMPLES.")
 	cmdline.BoolVar(&docInclHTTP, "doc:include-http", strToBool(os.Getenv("HUNIT_DOC_INCLUDE_HTTP")), "Include HTTP in request and response examples (as opposed to just routes and entities). Overrides: $HUNIT_DOC_INCLUDE_HTTP.")
 	cmdline.BoolVar(&docFormatEntity, "doc:format-entities", strToBool(os.Getenv("HUNIT_DOC_FORMAT_ENTITIES")), "Pretty-print supported request and response entities in documentation output. Overrides: $HUNIT_DOC_FORMAT_ENTITIES.")
-	cmdline.BoolVar(&genReport, "report", strToBool(os.Getenv("HUNIT_REPORT")), "Generate a report. Overrides: $HUNIT_REPORT.")
+	cmdline.VarPF(&reportSpecs, "report", "", "Generate a report, specified as '<type>[:<path>]'. Reports without a path are written to the report output directory; provide '-' as the path to write a report to standard output. A bare --report generates a report of the default type. Provide --report repeatedly to generate many reports. Overrides: $HUNIT_REPORT.").NoOptDefVal = defaultReport
 	cmdline.StringVar(&reportPath, "report:output", coalesce(os.Getenv("HUNIT_REPORT_OUTPUT"), "./reports"), "The directory in which generated reports should be written. Overrides: $HUNIT_REPORT_OUTPUT.")
-	cmdline.StringVar(&reportType, "report:type", coalesce(os.Getenv("HUNIT_REPORT_TYPE"), "junit"), "The format to generate reports in. Overrides: $HUNIT_REPORT_TYPE.")
+	cmdline.StringVar(&reportType, "report:type", coalesce(os.Getenv("HUNIT_REPORT_TYPE"), "junit"), "The default format to generate reports in: 'junit', 'json', 'html', 'tap', or 'github'. Overrides: $HUNIT_REPORT_TYPE.")
 	cmdline.BoolVar(&cacheResults, "cache", strToBool(os.Getenv("HUNIT_CACHE_RESULTS")), "Cache results. When enabled, test suites run against a managed service will cache results if neither the service binary nor the test suite has changed. Overrides: $HUNIT_CACHE_RESULTS.")
+	cmdline.BoolVar(&saveHistory, "history", strToBool(os.Getenv("HUNIT_HISTORY")), "Record the outcome of every test case in a history file, which can be summarized with 'instaunit history'. Overrides: $HUNIT_HISTORY.")
+	cmdline.StringVar(&historyPath, "history:file", coalesce(os.Getenv("HUNIT_HISTORY_FILE"), historyFile), "The history file to record results in. Overrides: $HUNIT_HISTORY_FILE.")
+	cmdline.StringVar(&coverageSpec, "coverage-spec", os.Getenv("HUNIT_COVERAGE_SPEC"), "Measure the coverage of the operations declared by an OpenAPI 3 document by requests to the base URL and report untested operations, untested responses, and requests to undeclared operations. Overrides: $HUNIT_COVERAGE_SPEC.")
+	cmdline.StringVar(&coveragePath, "coverage:output", os.Getenv("HUNIT_COVERAGE_OUTPUT"), "The path to write a JSON coverage report to. If omitted, coverage is only summarized. Overrides: $HUNIT_COVERAGE_OUTPUT.")
+	cmdline.StringVar(&harPath, "har", os.Getenv("HUNIT_HAR"), "Record every HTTP exchange performed by tests, including redirects that are followed and messages exchanged over websockets, in an HTTP Archive (HAR) file at the provided path. Overrides: $HUNIT_HAR.")
 	cmdline.DurationVar(&ioGracePeriod, "net:grace-period", strToDuration(os.Getenv("HUNIT_NET_IO_GRACE_PERIOD")), "The grace period to wait for long-running I/O to complete before shutting down websocket/persistent connections. Overrides: $HUNIT_NET_IO_GRACE_PERIOD.")
 	cmdline.StringVarP(&execCmd, "exec", "x", os.Getenv("HUNIT_EXEC_COMMAND"), "The command to execute before running tests, usually the program that is being tested. This process will be interrupted after tests have completed. Overrides: $HUNIT_EXEC_COMMAND.")
 	cmdline.StringVar(&execLog, "exec:log", os.Getenv("HUNIT_EXEC_LOG"), "The path to log command output to. If omitted, output is redirected to standard output. Overrides: $HUNIT_EXEC_LOG.")
@@ -113,9 +150,16 @@ func app() int {
 	cmdline.BoolVar(&version, "version", false, "Display the version and exit.")
 
 	cmdline.StringSliceVar(&headerSpecs, "header", nil, "Define a header to be set for every request, specified as 'Header-Name: <value>'. Provide -header repeatedly to set many headers.")
-	cmdline.StringSliceVar(&serviceSpecs, "service", nil, "Define a mock service, specified as '[host]:<port>=endpoints.yml'. The service is available while tests are running.")
+	cmdline.StringSliceVar(&serviceSpecs, "service", nil, "Define a mock service, specified as '[host]:<port>=[backend:]endpoints.yml'. Backends are 'rest' (the default), 'record' which proxies to an upstream and records exchanges, specified as 'record:recordings.yml@<upstream URL>', 'replay' which serves recordings, 'openapi' which mocks the operations described by an OpenAPI 3 document, and 'websocket' which serves scripted websocket endpoints. Prefix the address with 'https://' to serve the service over TLS and HTTP/2. The service is available while tests are running.")
+	cmdline.StringVar(&serviceCert, "service:cert", os.Getenv("HUNIT_SERVICE_CERT"), "The certificate to present from mock services that are served over TLS. Overrides: $HUNIT_SERVICE_CERT.")
+	cmdline.StringVar(&serviceKey, "service:key", os.Getenv("HUNIT_SERVICE_KEY"), "The private key for the certificate provided by --service:cert. Overrides: $HUNIT_SERVICE_KEY.")
+	cmdline.StringVar(&serviceCA, "service:ca", coalesce(os.Getenv("HUNIT_SERVICE_CA"), "./ca"), "When no certificate is provided, the directory in which a self-signed authority is created (or reused) to issue certificates for mock services that are served over TLS. Configure the system under test to trust 'ca.pem' in this directory. Overrides: $HUNIT_SERVICE_CA.")
+	cmdline.BoolVar(&serviceWatch, "service:watch", strToBool(os.Getenv("HUNIT_SERVICE_WATCH")), "Watch the files that define mock services and reload services when they change. Overrides: $HUNIT_SERVICE_WATCH.")
 	cmdline.StringSliceVar(&awaitURLs, "await", nil, "Wait for the resource described by a URL to become available before running tests. The URL will be polled until it becomes available. Provide -await repeatedly to wait for multiple resources.")
-	cmdline.Parse(os.Args[1:])
+	cmdline.Parse(reportArgs(os.Args[1:]))
+	if v := os.Getenv("HUNIT_REPORT"); v != "" && !cmdline.Changed("report") {
+		reportSpecs = envReportSpecs(v)
+	}
 
 	if version {
 		fmt.Println(formatVersion())
@@ -189,61 +233,60 @@ func app() int {
 		docname = make(map[string]int)
 	}
 
-	var reports []report.Generator
-	if genReport {
-		err := os.MkdirAll(reportPath, 0o755)
-		if err != nil {
-			color.New(colorErr...).Printf("* * * Could not create documentation base: %v\n", err)
-			return 1
-		}
-		rtype, err := report_emit.ParseDoctype(reportType)
-		if err != nil {
-			color.New(colorErr...).Printf("* * * Invalid report type: %v\n", err)
-			return 1
-		}
-		out, err := os.OpenFile(path.Join(reportPath, rtype.String()+rtype.Ext()), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
-		if err != nil {
-			color.New(colorErr...).Printf("* * * Could not open report output: %v\n", err)
-			return 1
-		}
-		gen, err := report.New(rtype, out, fmt.Sprint(time.Now().Unix()))
-		if err != nil {
-			color.New(colorErr...).Printf("* * * Could create report generator: %v\n", err)
-			return 1
-		}
-		err = gen.Init()
+	reports, err := newReports(reportSpecs, reportType, reportPath, fmt.Sprint(time.Now().Unix()))
+	if err != nil {
+		color.New(colorErr...).Printf("* * * %v\n", err)
+		return 1
+	}
+
+	var rec *har.Recorder
+	if harPath != "" {
+		rec = har.NewRecorder("instaunit", formatVersion())
+	}
+
+	var cov *coverage.Coverage
+	if coverageSpec != "" {
+		cov, err = newCoverage(coverageSpec, baseURL)
 		if err != nil {
-			color.New(colorErr...).Printf("* * * Could initialize report generator: %v\n", err)
+			color.New(colorErr...).Printf("* * * %v\n", err)
 			return 1
 		}
-		reports = []report.Generator{gen} // just one for now
 	}
 
+	if (serviceCert == "") != (serviceKey == "") {
+		color.New(colorErr...).Printf("* * * Both a certificate and key must be provided for TLS mock services\n")
+		return 1
+	}
+	serviceTLS := service.TLS{
+		Cert:      serviceCert,
+		Key:       serviceKey,
+		Authority: serviceCA,
+	}
+
+	cxt, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	services := 0
+	globalServices := &mockServices{}
+	defer globalServices.Stop()
 	for _, e := range serviceSpecs {
 		conf, err := service.ParseConfig(e)
 		if err != nil {
 			color.New(colorErr...).Printf("* * * Could not create mock service: %v\n", err)
 			return 1
 		}
-		svc, err := rest.New(conf) // only REST is supported for now...
+		svc, err := globalServices.Start(conf, serviceTLS)
 		if err != nil {
-			color.New(colorErr...).Printf("* * * Could not create mock service: %v\n", err)
-			return 1
-		}
-		err = svc.Start()
-		if err != nil {
-			color.New(colorErr...).Printf("* * * Could not start mock service: %v\n", err)
+			color.New(colorErr...).Printf("* * * %v\n", err)
 			return 1
 		}
-		if debug.VERBOSE {
-			fmt.Println()
+		if serviceWatch {
+			err = watchService(cxt, svc, conf)
+			if err != nil {
+				color.New(colorErr...).Printf("* * * Could not watch mock service: %v\n", err)
+				return 1
+			}
 		}
-		defer func(s service.Service, c service.Config) {
-			c.Resource.Close()
-			s.Stop()
-		}(svc, conf)
-		fmt.Printf("----> Service %v (%v)\n", conf.Addr, conf.Path)
 		services++
 	}
 
@@ -269,6 +312,7 @@ func app() int {
 	}
 
 	// setup caching
+	var hist []history.Record
 	var rcache, wcache *cache.Cache
 	var cachePath string
 	if cacheResults && execCmd != "" {
@@ -315,7 +359,32 @@ func app() int {
 
 	var gendocs []doc.Generator
 	if genDoc {
-		gen, err := doc.New(doctype, docpath)
+		apiFormat, err := doc_openapi.ParseFormat(docAPIFormat)
+		if err != nil {
+			color.New(colorErr...).Printf("* * * Invalid OpenAPI format: %v\n", err)
+			return 1
+		}
+		samples := sample.Config{}
+		for _, e := range docSamples {
+			if e == "none" {
+				samples.Langs = nil
+				break
+			}
+			l, err := sample.ParseLang(e)
+			if err != nil {
+				color.New(colorErr...).Printf("* * * Invalid code sample language: %v\n", err)
+				return 1
+			}
+			samples.Langs = append(samples.Langs, l)
+		}
+		for k := range globalHeaders {
+			samples.Secrets = append(samples.Secrets, k)
+		}
+		gen, err := doc.New(doctype, docpath, doc.Config{
+			OpenAPI: doc_openapi.Config{Format: apiFormat, Base: docAPIBase},
+			Postman: doc_postman.Config{BaseURL: baseURL},
+			Samples: samples,
+		})
 		if err != nil {
 			color.New(colorErr...).Println("* * * Could create documentation generator:", err)
 			return 1
@@ -324,6 +393,7 @@ func app() int {
 	}
 
 	var proc *exec.Process
+	var suiteServices *mockServices
 	success := true
 	start := time.Now()
 
@@ -338,6 +408,10 @@ suites:
 			}
 			proc = nil
 		}
+		if suiteServices != nil {
+			suiteServices.Stop()
+			suiteServices = nil
+		}
 
 		var (
 			suite      *testcase.Suite
@@ -379,13 +453,15 @@ suites:
 		}
 
 		var sum *cache.Resource
-		if (rcache != nil || wcache != nil) && e != stdinPath {
+		if (rcache != nil || wcache != nil || saveHistory) && e != stdinPath {
 			sum, err = cache.Checksum(e)
 			if err != nil {
 				color.New(colorErr...).Println("\n* * * Could not load suite checksum:", err)
 				errno++
 				break
 			}
+		}
+		if sum != nil && (rcache != nil || wcache != nil) {
 			color.New(colorSuite...).Printf(" (cache: %s)\n", sum.Checksum)
 		} else {
 			fmt.Println()
@@ -411,6 +487,15 @@ suites:
 			}
 		}
 
+		if len(suite.Services) > 0 {
+			suiteServices, err = startSuiteServices(suite, serviceTLS)
+			if err != nil {
+				color.New(colorErr...).Printf("* * * %v\n", err)
+				errno++
+				continue suites
+			}
+		}
+
 		if len(suite.Setup) > 0 {
 			if execCommands(options, suite.Setup) != nil {
 				continue suites
@@ -459,16 +544,21 @@ suites:
 				}
 			},
 		}
+		if rec != nil {
+			client.Transport = rec.Transport(nil)
+		}
 
 		startSuite := time.Now()
 		results, err := hunit.RunSuite(suite, runtime.Context{
-			BaseURL: baseURL,
-			Options: options,
-			Headers: globalHeaders,
-			Debug:   debug.DEBUG,
-			Gendoc:  gendocs,
-			Config:  cdup,
-			Client:  client,
+			BaseURL:   baseURL,
+			Options:   options,
+			Headers:   globalHeaders,
+			Debug:     debug.DEBUG,
+			Gendoc:    gendocs,
+			Config:    cdup,
+			Client:    client,
+			HAR:       rec,
+			Variables: serviceVars(globalServices, suiteServices),
 		})
 		if err != nil {
 			color.New(colorErr...).Printf("* * * Could not run test suite: %v\n", err)
@@ -483,7 +573,7 @@ suites:
 		}
 
 		for _, e := range reports {
-			err := e.Suite(cdup, suite, &report_emit.Results{results, suiteDuration})
+			err := e.Suite(cdup, suite, &report_emit.Results{Path: file, Results: results, Runtime: suiteDuration})
 			if err != nil {
 				color.New(colorErr...).Printf("* * * Could not emit report: %v\n", err)
 			}
@@ -500,6 +590,16 @@ suites:
 		if wcache != nil && sum != nil {
 			wcache.AddSuite(sum, results)
 		}
+		if saveHistory {
+			var checksum string
+			if sum != nil {
+				checksum = sum.Checksum
+			}
+			hist = append(hist, history.Records(fmt.Sprint(start.UnixNano()), file, checksum, results)...)
+		}
+		if cov != nil {
+			cov.Add(results)
+		}
 
 		if len(suite.Teardown) > 0 {
 			if execCommands(options, suite.Teardown) != nil {
@@ -541,6 +641,10 @@ suites:
 		}
 		proc = nil
 	}
+	if suiteServices != nil {
+		suiteServices.Stop()
+		suiteServices = nil
+	}
 
 	duration := time.Since(start)
 	fmt.Println()
@@ -551,6 +655,26 @@ suites:
 			color.New(colorErr...).Printf("* * * Could not write cache: %v\n\n", err)
 		}
 	}
+	if len(hist) > 0 {
+		err := history.Append(historyPath, hist)
+		if err != nil {
+			color.New(colorErr...).Printf("* * * Could not write history: %v\n\n", err)
+		}
+	}
+
+	if rec != nil {
+		err := rec.Write(harPath)
+		if err != nil {
+			color.New(colorErr...).Printf("* * * Could not write HAR: %v\n\n", err)
+		}
+	}
+
+	if cov != nil {
+		err := reportCoverage(cov, coveragePath)
+		if err != nil {
+			color.New(colorErr...).Printf("* * * Could not write coverage report: %v\n\n", err)
+		}
+	}
 
 	if errno > 0 {
 		color.New(color.BgHiRed, color.Bold, color.FgBlack).Printf(" ERRORS! ")
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/instaunit/instaunit/hunit/report"
+	report_emit "github.com/instaunit/instaunit/hunit/report/emit"
+)
+
+// The path which directs a report to standard output
+const stdoutPath = "-"
+
+// The value of a bare --report flag
+const defaultReport = "true"
+
+// Reports to generate. Each is specified as '<type>[:<path>]'. A report
+// without a path is written to the report output directory; a bare --report
+// generates a report of the default type.
+type reportSpecs []string
+
+func (r *reportSpecs) String() string {
+	return strings.Join(*r, ",")
+}
+
+// Values which do not describe a report are interpreted as booleans, as
+// '--report' was before it accepted a type: true generates a report of the
+// default type and anything else disables reports.
+func (r *reportSpecs) Set(v string) error {
+	switch {
+	case isReportSpec(v):
+		*r = append(*r, v)
+	case strToBool(v):
+		*r = append(*r, defaultReport)
+	default:
+		*r = nil
+	}
+	return nil
+}
+
+func (r *reportSpecs) Type() string {
+	return "type[:path]"
+}
+
+// Produce reports from the value of $HUNIT_REPORT, which is a list of reports
+// separated by commas
+func envReportSpecs(v string) reportSpecs {
+	var r reportSpecs
+	for _, e := range strings.Split(v, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			r.Set(e)
+		}
+	}
+	return r
+}
+
+// Our report flag accepts an optional value; the flag package only supports
+// this in the form '--report=<value>'. We also want to support the more
+// conventional '--report <value>', so arguments in that form are rewritten
+// when the value names a report type.
+func reportArgs(args []string) []string {
+	res := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		e := args[i]
+		if e == "--" {
+			return append(res, args[i:]...)
+		}
+		if (e == "--report" || e == "-report") && i+1 < len(args) && isReportSpec(args[i+1]) {
+			res = append(res, "--report="+args[i+1])
+			i++
+			continue
+		}
+		res = append(res, e)
+	}
+	return res
+}
+
+// Determine if a value describes a report
+func isReportSpec(s string) bool {
+	t, _, _ := strings.Cut(s, ":")
+	_, err := report_emit.ParseDoctype(t)
+	return err == nil
+}
+
+// Create report generators. Reports without a path are written to the output
+// directory, named after their type. If any report cannot be created, the
+// outputs which were already opened are closed.
+func newReports(specs []string, deftype, dir, id string) (_ []report.Generator, err error) {
+	var gens []report.Generator
+	var outs []io.WriteCloser
+	defer func() {
+		if err != nil {
+			for _, e := range outs {
+				e.Close()
+			}
+		}
+	}()
+
+	paths := make(map[string]struct{})
+	for _, e := range specs {
+		if e == defaultReport {
+			e = deftype
+		}
+		t, p, _ := strings.Cut(e, ":")
+		rtype, err := report_emit.ParseDoctype(t)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid report type: %w", err)
+		}
+		if p == "" {
+			p = path.Join(dir, rtype.String()+rtype.Ext())
+		}
+		if _, ok := paths[p]; ok {
+			continue // the same report, specified more than once
+		}
+		paths[p] = struct{}{}
+
+		var out io.WriteCloser
+		if p == stdoutPath {
+			out = nopCloser{os.Stdout}
+		} else {
+			err = os.MkdirAll(path.Dir(p), 0o755)
+			if err != nil {
+				return nil, fmt.Errorf("Could not create report directory: %w", err)
+			}
+			out, err = os.OpenFile(p, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+			if err != nil {
+				return nil, fmt.Errorf("Could not open report output: %w", err)
+			}
+		}
+		outs = append(outs, out)
+
+		gen, err := report.New(rtype, out, id)
+		if err != nil {
+			return nil, fmt.Errorf("Could not create report generator: %w", err)
+		}
+		err = gen.Init()
+		if err != nil {
+			return nil, fmt.Errorf("Could not initialize report generator: %w", err)
+		}
+		gens = append(gens, gen)
+	}
+	return gens, nil
+}
+
+// Standard output is not closed when a report is finalized
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
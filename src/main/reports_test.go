@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportArgs(t *testing.T) {
+	tests := []struct {
+		Args   []string
+		Expect []string
+	}{
+		{
+			[]string{"--report", "json", "suite.yml"},
+			[]string{"--report=json", "suite.yml"},
+		},
+		{
+			[]string{"--report", "html:out/report.html", "-report", "tap:-", "suite.yml"},
+			[]string{"--report=html:out/report.html", "--report=tap:-", "suite.yml"},
+		},
+		{
+			[]string{"--report", "suite.yml"}, // not a report type; a bare flag followed by a suite
+			[]string{"--report", "suite.yml"},
+		},
+		{
+			[]string{"--report"},
+			[]string{"--report"},
+		},
+		{
+			[]string{"--", "--report", "json"},
+			[]string{"--", "--report", "json"},
+		},
+	}
+	for _, e := range tests {
+		assert.Equal(t, e.Expect, reportArgs(e.Args), e.Args)
+	}
+}
+
+func TestReportSpecs(t *testing.T) {
+	tests := []struct {
+		Values []string
+		Expect reportSpecs
+	}{
+		{[]string{"json"}, reportSpecs{"json"}},
+		{[]string{"json:out.json", "junit"}, reportSpecs{"json:out.json", "junit"}},
+		{[]string{"true"}, reportSpecs{defaultReport}},
+		{[]string{"yes", "tap"}, reportSpecs{defaultReport, "tap"}},
+		{[]string{"json", "false"}, nil},
+		{[]string{"0"}, nil},
+		{[]string{"1"}, nil},
+		{[]string{"no"}, nil},
+		{[]string{"n"}, nil},
+		{[]string{"f"}, nil},
+	}
+	for _, e := range tests {
+		var r reportSpecs
+		for _, v := range e.Values {
+			assert.Nil(t, r.Set(v))
+		}
+		assert.Equal(t, e.Expect, r, e.Values)
+	}
+}
+
+func TestEnvReportSpecs(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.json")
+	tests := []struct {
+		Value  string
+		Expect reportSpecs
+	}{
+		{"true", reportSpecs{defaultReport}},
+		{"junit, json:" + out, reportSpecs{"junit", "json:" + out}},
+		{"0", nil},
+		{"1", nil},
+		{"no", nil},
+		{"f", nil},
+	}
+	for _, e := range tests {
+		r := envReportSpecs(e.Value)
+		assert.Equal(t, e.Expect, r, e.Value)
+		gens, err := newReports(r, "junit", dir, "1")
+		if assert.Nil(t, err, e.Value) && assert.Len(t, gens, len(e.Expect), e.Value) {
+			for _, g := range gens {
+				assert.Nil(t, g.Finalize(), e.Value)
+			}
+		}
+	}
+	for _, e := range []string{"junit.xml", "out.json"} {
+		_, err := os.Stat(filepath.Join(dir, e))
+		assert.Nil(t, err, e)
+	}
+}
+
+func TestNewReports(t *testing.T) {
+	dir := t.TempDir()
+	gens, err := newReports([]string{"json", "json:" + filepath.Join(dir, "json.json"), "tap:" + filepath.Join(dir, "r.tap")}, "junit", dir, "1")
+	if assert.Nil(t, err) && assert.Len(t, gens, 2) { // the same report is only generated once
+		for _, g := range gens {
+			assert.Nil(t, g.Finalize())
+		}
+	}
+
+	_, err = newReports([]string{"json:" + filepath.Join(dir, "a.json"), "nope"}, "junit", dir, "1")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/instaunit/instaunit/hunit/expr"
+	"github.com/instaunit/instaunit/hunit/service"
+	"github.com/instaunit/instaunit/hunit/service/backend"
+	"github.com/instaunit/instaunit/hunit/testcase"
+
+	"github.com/bww/go-util/v1/debug"
+	"github.com/fatih/color"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// How often service definitions are checked for changes
+const watchInterval = time.Millisecond * 250
+
+// The prefix of environment variables which describe services
+const serviceEnvPrefix = "HUNIT_SERVICE_"
+
+// A set of running mock services
+type mockServices struct {
+	svcs []service.Service
+	vars expr.Variables
+	env  map[string]*string // the values of the environment variables we've set, before we set them
+}
+
+// Start a mock service. The resource the service is created from is consumed
+// and closed.
+//
+// The service is described by variables which are available to test cases as
+// '${services.<name>.url}' and to commands as '$HUNIT_SERVICE_<NAME>_URL'.
+// This allows services to be bound to port 0, in which case a port is
+// assigned. If another service already has the same name, the service is
+// named after the directory that contains its resource as well.
+func (m *mockServices) Start(conf service.Config, tls service.TLS) (service.Service, error) {
+	conf.Name = m.uniqueName(conf)
+	if conf.TLS != nil {
+		*conf.TLS = tls
+	}
+
+	svc, err := backend.New(conf)
+	if conf.Resource != nil {
+		conf.Resource.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Could not create mock service: %w", err)
+	}
+	err = svc.Start()
+	if err != nil {
+		return nil, fmt.Errorf("Could not start mock service: %w", err)
+	}
+
+	if debug.VERBOSE {
+		fmt.Println()
+	}
+	fmt.Printf("----> Service %v: %v (%v)\n", conf.Name, svc.URL(), conf)
+	if conf.TLS != nil && conf.TLS.Cert == "" {
+		fmt.Printf("      Trust authority: %v\n", path.Join(tls.Authority, service.AuthorityCert))
+	}
+
+	m.svcs = append(m.svcs, svc)
+	m.export(conf.Name, svc.URL())
+	return svc, nil
+}
+
+// Produce a name for a service which is not used by any other service. A name
+// which clashes is qualified by the directory that contains the service's
+// resource, e.g., 'a_endpoints', and then suffixed with a number if it still
+// clashes.
+func (m *mockServices) uniqueName(conf service.Config) string {
+	name := conf.Name
+	if _, ok := m.vars[name]; !ok {
+		return name
+	}
+	if d := filepath.Base(filepath.Dir(conf.Path)); d != "." && d != ".." && d != string(filepath.Separator) {
+		name = d + "_" + conf.Name
+	}
+	base := name
+	for i := 2; ; i++ {
+		if _, ok := m.vars[name]; !ok {
+			return name
+		}
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+}
+
+// Describe a service in variables and the environment
+func (m *mockServices) export(name, base string) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return // we produced this URL, this shouldn't happen
+	}
+	if m.vars == nil {
+		m.vars = make(expr.Variables)
+	}
+	m.vars[name] = map[string]interface{}{
+		"url":  base,
+		"addr": u.Host,
+		"host": u.Hostname(),
+		"port": u.Port(),
+	}
+
+	if m.env == nil {
+		m.env = make(map[string]*string)
+	}
+	key := serviceEnvPrefix + envName(name) + "_"
+	for k, v := range map[string]string{"URL": base, "ADDR": u.Host} {
+		k = key + k
+		if _, ok := m.env[k]; !ok {
+			if prev, ok := os.LookupEnv(k); ok {
+				m.env[k] = &prev
+			} else {
+				m.env[k] = nil
+			}
+		}
+		os.Setenv(k, v)
+	}
+}
+
+// Stop every service and restore the environment
+func (m *mockServices) Stop() {
+	for _, e := range m.svcs {
+		err := e.Stop()
+		if err != nil {
+			color.New(colorErr...).Printf("* * * Could not stop mock service: %v\n", err)
+		}
+	}
+	for k, v := range m.env {
+		if v != nil {
+			os.Setenv(k, *v)
+		} else {
+			os.Unsetenv(k)
+		}
+	}
+	m.svcs, m.vars, m.env = nil, nil, nil
+}
+
+// Start the mock services declared by a suite. If any service cannot be
+// started, those which have been are stopped.
+func startSuiteServices(suite *testcase.Suite, tls service.TLS) (*mockServices, error) {
+	names := make([]string, 0, len(suite.Services))
+	for k := range suite.Services {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	svcs := &mockServices{}
+	for _, e := range names {
+		conf, err := suiteServiceConfig(e, suite.Services[e])
+		if err != nil {
+			svcs.Stop()
+			return nil, err
+		}
+		_, err = svcs.Start(conf, tls)
+		if err != nil {
+			svcs.Stop()
+			return nil, fmt.Errorf("%s: %w", e, err)
+		}
+	}
+
+	return svcs, nil
+}
+
+// Produce variables describing services; services declared later shadow those
+// declared earlier with the same name.
+func serviceVars(m ...*mockServices) expr.Variables {
+	vars := make(expr.Variables)
+	for _, e := range m {
+		if e != nil {
+			for k, v := range e.vars {
+				vars[k] = v
+			}
+		}
+	}
+	return expr.Variables{"services": vars}
+}
+
+// Produce the form of a name used in environment variables
+func envName(n string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return unicode.ToUpper(r)
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			return r
+		default:
+			return '_'
+		}
+	}, n)
+}
+
+// Produce configuration for a service declared by a suite
+func suiteServiceConfig(name string, svc *testcase.Service) (service.Config, error) {
+	var conf service.Config
+	if svc == nil {
+		return conf, fmt.Errorf("Service %s is not defined", name)
+	}
+	if svc.Addr == "" {
+		return conf, fmt.Errorf("Service %s does not define an address", name)
+	}
+
+	conf.Backend = service.BackendREST
+	if svc.Backend != "" {
+		var err error
+		conf.Backend, err = service.ParseBackend(svc.Backend)
+		if err != nil {
+			return conf, fmt.Errorf("Service %s: %w", name, err)
+		}
+	}
+	if conf.Backend == service.BackendRecord && (svc.File == "" || svc.Upstream == "") {
+		return conf, fmt.Errorf("Service %s: recordings require a file and an upstream", name)
+	}
+
+	conf.Name = name
+	conf.Addr, conf.TLS = service.ParseAddr(svc.Addr)
+	conf.Upstream = svc.Upstream
+
+	switch {
+	case svc.File != "" && svc.Endpoints.Kind != 0:
+		return conf, fmt.Errorf("Service %s defines both a file and inline endpoints", name)
+	case svc.File != "":
+		conf.Path = svc.File
+		if conf.Backend != service.BackendRecord {
+			f, err := os.Open(svc.File)
+			if err != nil {
+				return conf, fmt.Errorf("Service %s: %w", name, err)
+			}
+			conf.Resource = f
+		}
+	case svc.Endpoints.Kind != 0:
+		data, err := yaml.Marshal(&svc.Endpoints)
+		if err != nil {
+			return conf, fmt.Errorf("Service %s: %w", name, err)
+		}
+		conf.Path = "inline"
+		conf.Resource = io.NopCloser(bytes.NewReader(data))
+	default:
+		return conf, fmt.Errorf("Service %s defines neither a file nor endpoints", name)
+	}
+
+	return conf, nil
+}
+
+// Reload a service whenever the file that defines it changes. If the new
+// definition cannot be loaded, the service continues with its current one.
+// Services whose backend cannot be reloaded are not watched.
+func watchService(cxt context.Context, svc service.Service, conf service.Config) error {
+	r, ok := svc.(service.Reloader)
+	if !ok {
+		color.New(colorErr...).Printf("* * * Not watching service %v: the %v backend does not support reloading\n", conf.Name, conf.Backend)
+		return nil
+	}
+	return service.Watch(cxt, conf.Path, watchInterval, func(data []byte, err error) {
+		if err == nil {
+			err = r.Reload(data)
+		}
+		if err != nil {
+			color.New(colorErr...).Printf("* * * Could not reload mock service: %v: %v\n", conf.Path, err)
+		} else {
+			fmt.Printf("----> Reloaded service %v: %v (%v)\n", conf.Name, svc.URL(), conf)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/instaunit/instaunit/hunit/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const endpointsSource = `
+- endpoint:
+    methods: [GET]
+    path: /ping
+  response:
+    status: 200
+    entity: pong
+`
+
+func TestServiceNames(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, e := range []string{"a", "b", "x/a", "y/a"} {
+		p := filepath.Join(dir, e, "endpoints.yml")
+		if !assert.Nil(t, os.MkdirAll(filepath.Dir(p), 0755)) {
+			return
+		}
+		if !assert.Nil(t, os.WriteFile(p, []byte(endpointsSource), 0644)) {
+			return
+		}
+		paths = append(paths, p)
+	}
+
+	svcs := &mockServices{}
+	defer svcs.Stop()
+	for _, e := range paths {
+		conf, err := service.ParseConfig("localhost:0=" + e)
+		if !assert.Nil(t, err) {
+			return
+		}
+		_, err = svcs.Start(conf, service.TLS{})
+		if !assert.Nil(t, err) {
+			return
+		}
+	}
+
+	for _, e := range []string{"endpoints", "b_endpoints", "a_endpoints", "a_endpoints_2"} {
+		assert.Contains(t, svcs.vars, e)
+	}
+	assert.Len(t, svcs.vars, 4)
+	assert.NotEmpty(t, os.Getenv("HUNIT_SERVICE_A_ENDPOINTS_2_URL"))
+}
+
+type staticService struct{}
+
+func (staticService) Start() error { return nil }
+func (staticService) Stop() error  { return nil }
+func (staticService) URL() string  { return "http://localhost" }
+
+func TestWatchService(t *testing.T) {
+	cxt, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// services which cannot be reloaded are skipped rather than failing
+	err := watchService(cxt, staticService{}, service.Config{Name: "recording", Backend: service.BackendRecord, Path: "missing.yml"})
+	assert.Nil(t, err)
+}
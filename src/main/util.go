@@ -82,6 +82,17 @@ func strToDuration(s string, d ...time.Duration) time.Duration {
 	return v
 }
 
+// String to a list of comma-separated, trimmed values
+func strToList(s string) []string {
+	var l []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			l = append(l, e)
+		}
+	}
+	return l
+}
+
 // Dump environment pairs
 func dumpEnv(w io.Writer, env map[string]string) {
 	wk := 0